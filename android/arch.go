@@ -28,12 +28,13 @@ import (
 var (
 	archTypeList []ArchType
 
-	Arm    = newArch("arm", "lib32")
-	Arm64  = newArch("arm64", "lib64")
-	Mips   = newArch("mips", "lib32")
-	Mips64 = newArch("mips64", "lib64")
-	X86    = newArch("x86", "lib32")
-	X86_64 = newArch("x86_64", "lib64")
+	Arm     = newArch("arm", "lib32")
+	Arm64   = newArch("arm64", "lib64")
+	Mips    = newArch("mips", "lib32")
+	Mips64  = newArch("mips64", "lib64")
+	Riscv64 = newArch("riscv64", "lib64")
+	X86     = newArch("x86", "lib32")
+	X86_64  = newArch("x86_64", "lib64")
 
 	Common = ArchType{
 		Name: "common",
@@ -41,12 +42,13 @@ var (
 )
 
 var archTypeMap = map[string]ArchType{
-	"arm":    Arm,
-	"arm64":  Arm64,
-	"mips":   Mips,
-	"mips64": Mips64,
-	"x86":    X86,
-	"x86_64": X86_64,
+	"arm":     Arm,
+	"arm64":   Arm64,
+	"mips":    Mips,
+	"mips64":  Mips64,
+	"riscv64": Riscv64,
+	"x86":     X86,
+	"x86_64":  X86_64,
 }
 
 /*
@@ -67,6 +69,9 @@ module {
         mips64: {
             // Host or device variants with mips64 architecture
         },
+        riscv64: {
+            // Host or device variants with riscv64 architecture
+        },
         x86: {
             // Host or device variants with x86 architecture
         },
@@ -604,11 +609,11 @@ var (
 	Fuchsia     = NewOsType("fuchsia", Device, false)
 
 	osArchTypeMap = map[OsType][]ArchType{
-		Linux:       []ArchType{X86, X86_64},
+		Linux:       []ArchType{Arm64, X86, X86_64},
 		LinuxBionic: []ArchType{X86_64},
 		Darwin:      []ArchType{X86_64},
 		Windows:     []ArchType{X86, X86_64},
-		Android:     []ArchType{Arm, Arm64, Mips, Mips64, X86, X86_64},
+		Android:     []ArchType{Arm, Arm64, Mips, Mips64, Riscv64, X86, X86_64},
 		Fuchsia:     []ArchType{Arm64, X86_64},
 	}
 )
@@ -1543,6 +1548,7 @@ func getMegaDeviceConfig() []archConfig {
 		// mips64r2 is mismatching 64r2 and 64r6 libraries during linking to libgcc
 		//{"mips64", "mips64r2", "", []string{"mips64"}},
 		{"mips64", "mips64r6", "", []string{"mips64"}},
+		{"riscv64", "", "", []string{"riscv64"}},
 		{"x86", "", "", []string{"x86"}},
 		{"x86", "atom", "", []string{"x86"}},
 		{"x86", "haswell", "", []string{"x86"}},
@@ -230,3 +230,60 @@ func TestFilterArchStruct(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeArch(t *testing.T) {
+	tests := []struct {
+		name        string
+		arch        string
+		archVariant string
+		multilib    string
+	}{
+		{
+			name:        "arm64",
+			arch:        "arm64",
+			archVariant: "armv8-a",
+			multilib:    "lib64",
+		},
+		{
+			name:        "riscv64",
+			arch:        "riscv64",
+			archVariant: "",
+			multilib:    "lib64",
+		},
+		{
+			name:        "riscv64 generic",
+			arch:        "riscv64",
+			archVariant: "generic",
+			multilib:    "lib64",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpuVariant := ""
+			arch, err := decodeArch(Android, test.arch, &test.archVariant, &cpuVariant, nil)
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if arch.ArchType.Name != test.arch {
+				t.Errorf("expected arch type %q, got %q", test.arch, arch.ArchType.Name)
+			}
+			if arch.ArchType.Multilib != test.multilib {
+				t.Errorf("expected multilib %q, got %q", test.multilib, arch.ArchType.Multilib)
+			}
+			if test.archVariant == "generic" && arch.ArchVariant != "" {
+				t.Errorf("expected generic arch variant to be cleared, got %q", arch.ArchVariant)
+			}
+		})
+	}
+
+	found := false
+	for _, archType := range osArchTypeMap[Android] {
+		if archType == Riscv64 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected riscv64 to be a supported android arch")
+	}
+}
@@ -389,10 +389,14 @@ func (c *config) HostSystemTool(name string) string {
 	return name
 }
 
-// PrebuiltOS returns the name of the host OS used in prebuilts directories
+// PrebuiltOS returns the name of the host OS used in prebuilts directories.  It follows the machine
+// running the build, as the prebuilt tools and the host tools in out/host have to run on it.
 func (c *config) PrebuiltOS() string {
 	switch runtime.GOOS {
 	case "linux":
+		if runtime.GOARCH == "arm64" {
+			return "linux-arm64"
+		}
 		return "linux-x86"
 	case "darwin":
 		return "darwin-x86"
@@ -845,6 +849,14 @@ func (c *deviceConfig) VndkUseCoreVariant() bool {
 	return Bool(c.config.productVariables.VndkUseCoreVariant)
 }
 
+func (c *deviceConfig) VndkSnapshotBuildArtifacts() bool {
+	return Bool(c.config.productVariables.VndkSnapshotBuildArtifacts)
+}
+
+func (c *deviceConfig) CheckElfDepsExternalLibraries() []string {
+	return c.config.productVariables.CheckElfDepsExternalLibraries
+}
+
 func (c *deviceConfig) SystemSdkVersions() []string {
 	return c.config.productVariables.DeviceSystemSdkVersions
 }
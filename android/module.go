@@ -89,6 +89,7 @@ type BaseModuleContext interface {
 	ModuleName() string
 	ModuleDir() string
 	ModuleType() string
+	BlueprintsFile() string
 	Config() Config
 
 	ContainsProperty(name string) bool
@@ -1407,11 +1408,25 @@ func findStringInSlice(str string, slice []string) int {
 	return -1
 }
 
+// SrcIsModule returns the name of the module referenced by a ":module" or ":module{tag}" string, or
+// an empty string if s does not reference a module.
 func SrcIsModule(s string) string {
+	module, _ := SrcIsModuleWithTag(s)
+	return module
+}
+
+// SrcIsModuleWithTag returns the name of the module and the output tag referenced by a ":module{tag}"
+// string.  The tag is empty for a ":module" string, and both are empty if s does not reference a
+// module.
+func SrcIsModuleWithTag(s string) (module, tag string) {
 	if len(s) > 1 && s[0] == ':' {
-		return s[1:]
+		module = s[1:]
+		if tagStart := strings.IndexByte(module, '{'); tagStart > 0 && strings.HasSuffix(module, "}") {
+			return module[:tagStart], module[tagStart+1 : len(module)-1]
+		}
+		return module, ""
 	}
-	return ""
+	return "", ""
 }
 
 type sourceDependencyTag struct {
@@ -1458,6 +1473,12 @@ type SourceFileProducer interface {
 	Srcs() Paths
 }
 
+// OutputFileProducer is implemented by modules that provide named groups of output files, which
+// can be referenced with the ":module{tag}" syntax in properties tagged with `android:"path"`.
+type OutputFileProducer interface {
+	OutputFiles(tag string) (Paths, error)
+}
+
 type HostToolProvider interface {
 	HostToolPath() OptionalPath
 }
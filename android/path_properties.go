@@ -45,6 +45,8 @@ func pathDepsMutator(ctx BottomUpMutatorContext) {
 				deps = append(deps, m)
 			}
 		}
+		// ":module{tag}" references to different tags of the same module need a single dependency.
+		deps = FirstUniqueStrings(deps)
 
 		ctx.AddDependency(ctx.Module(), SourceDepTag, deps...)
 	}
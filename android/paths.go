@@ -262,16 +262,16 @@ func PathsAndMissingDepsForModuleSrcExcludes(ctx ModuleContext, paths, excludes
 	var missingExcludeDeps []string
 
 	for _, e := range excludes {
-		if m := SrcIsModule(e); m != "" {
+		if m, t := SrcIsModuleWithTag(e); m != "" {
 			module := ctx.GetDirectDepWithTag(m, SourceDepTag)
 			if module == nil {
 				missingExcludeDeps = append(missingExcludeDeps, m)
 				continue
 			}
-			if srcProducer, ok := module.(SourceFileProducer); ok {
-				expandedExcludes = append(expandedExcludes, srcProducer.Srcs().Strings()...)
+			if paths, err := pathsFromModuleDep(module, m, t); err == nil {
+				expandedExcludes = append(expandedExcludes, paths.Strings()...)
 			} else {
-				ctx.ModuleErrorf("srcs dependency %q is not a source file producing module", m)
+				ctx.ModuleErrorf("srcs dependency %s", err.Error())
 			}
 		} else {
 			expandedExcludes = append(expandedExcludes, filepath.Join(prefix, e))
@@ -306,26 +306,46 @@ func (e missingDependencyError) Error() string {
 	return "missing dependencies: " + strings.Join(e.missingDeps, ", ")
 }
 
+// pathsFromModuleDep returns the files referenced by ":name" or ":name{tag}", using Srcs() of a
+// SourceFileProducer module when no tag is given and OutputFiles(tag) of an OutputFileProducer module
+// otherwise.  The returned Paths may be modified by the caller.
+func pathsFromModuleDep(module blueprint.Module, name, tag string) (Paths, error) {
+	if tag != "" {
+		if outProducer, ok := module.(OutputFileProducer); ok {
+			paths, err := outProducer.OutputFiles(tag)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %s", name, err.Error())
+			}
+			return append(Paths(nil), paths...), nil
+		}
+		return nil, fmt.Errorf("%q is not an output file producing module, cannot reference %q", name,
+			":"+name+"{"+tag+"}")
+	}
+	if srcProducer, ok := module.(SourceFileProducer); ok {
+		return srcProducer.Srcs(), nil
+	}
+	return nil, fmt.Errorf("%q is not a source file producing module", name)
+}
+
 func expandOneSrcPath(ctx ModuleContext, s string, expandedExcludes []string) (Paths, error) {
-	if m := SrcIsModule(s); m != "" {
+	if m, t := SrcIsModuleWithTag(s); m != "" {
 		module := ctx.GetDirectDepWithTag(m, SourceDepTag)
 		if module == nil {
 			return nil, missingDependencyError{[]string{m}}
 		}
-		if srcProducer, ok := module.(SourceFileProducer); ok {
-			moduleSrcs := srcProducer.Srcs()
-			for _, e := range expandedExcludes {
-				for j := 0; j < len(moduleSrcs); j++ {
-					if moduleSrcs[j].String() == e {
-						moduleSrcs = append(moduleSrcs[:j], moduleSrcs[j+1:]...)
-						j--
-					}
+		moduleSrcs, err := pathsFromModuleDep(module, m, t)
+		if err != nil {
+			return nil, fmt.Errorf("path dependency %s", err.Error())
+		}
+		for _, e := range expandedExcludes {
+			for j := 0; j < len(moduleSrcs); j++ {
+				if moduleSrcs[j].String() == e {
+					moduleSrcs = append(moduleSrcs[:j], moduleSrcs[j+1:]...)
+					j--
 				}
 			}
-			return moduleSrcs, nil
-		} else {
-			return nil, fmt.Errorf("path dependency %q is not a source file producing module", m)
 		}
+		return moduleSrcs, nil
 	} else if pathtools.IsGlob(s) {
 		paths := ctx.GlobFiles(pathForModuleSrc(ctx, s).String(), expandedExcludes)
 		return PathsWithModuleSrcSubDir(ctx, paths, ""), nil
@@ -1142,7 +1162,13 @@ func PathForModuleInstall(ctx ModuleInstallPathContext, pathComponents ...string
 	} else {
 		switch ctx.Os() {
 		case Linux:
-			outPaths = []string{"host", "linux-x86"}
+			// Follow the configured host arch rather than the machine running the build, so
+			// that an arm64 host can be cross-targeted from x86_64.
+			if ctx.Arch().ArchType == Arm64 {
+				outPaths = []string{"host", "linux-arm64"}
+			} else {
+				outPaths = []string{"host", "linux-x86"}
+			}
 		case LinuxBionic:
 			// TODO: should this be a separate top level, or shared with linux-x86?
 			outPaths = []string{"host", "linux_bionic-x86"}
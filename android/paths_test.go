@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -508,6 +509,40 @@ func TestPathForModuleInstall(t *testing.T) {
 	}
 }
 
+func TestPathsCrossTargetingArm64Host(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("cross-targeting an arm64 host is tested from an x86_64 linux machine")
+	}
+
+	config := TestArchConfig("out", nil)
+	config.Targets[BuildOs] = []Target{
+		{BuildOs, Arch{ArchType: Arm64}},
+	}
+
+	// The prebuilts and the host tools have to run on the machine running the build.
+	if g, w := config.PrebuiltOS(), "linux-x86"; g != w {
+		t.Errorf("unexpected prebuilt OS:\n got: %q\nwant: %q", g, w)
+	}
+	tool := config.HostToolPath(PathContextForTesting(config, nil), "my_tool")
+	if g, w := tool.String(), "out/host/linux-x86/bin/my_tool"; g != w {
+		t.Errorf("unexpected tool path:\n got: %q\nwant: %q", g, w)
+	}
+	if g, w := config.BlueprintToolLocation(), "out/host/linux-x86/bin"; g != w {
+		t.Errorf("unexpected blueprint tool location:\n got: %q\nwant: %q", g, w)
+	}
+
+	// Host modules are installed for the configured host arch.
+	ctx := &moduleInstallPathContextImpl{
+		androidBaseContextImpl: androidBaseContextImpl{
+			target: config.Targets[BuildOs][0],
+			config: config,
+		},
+	}
+	if g, w := PathForModuleInstall(ctx, "bin", "my_tool").basePath.path, "host/linux-arm64/bin/my_tool"; g != w {
+		t.Errorf("unexpected install path:\n got: %q\nwant: %q", g, w)
+	}
+}
+
 func TestDirectorySortedPaths(t *testing.T) {
 	config := TestConfig("out", nil)
 
@@ -760,6 +795,44 @@ func (p *pathForModuleSrcTestModule) GenerateAndroidBuildActions(ctx ModuleConte
 	}
 }
 
+type pathForModuleSrcOutputFileProviderModule struct {
+	ModuleBase
+	props struct {
+		Outs   []string
+		Tagged []string
+	}
+
+	outs   Paths
+	tagged Paths
+}
+
+func pathForModuleSrcOutputFileProviderModuleFactory() Module {
+	module := &pathForModuleSrcOutputFileProviderModule{}
+	module.AddProperties(&module.props)
+	InitAndroidModule(module)
+	return module
+}
+
+func (p *pathForModuleSrcOutputFileProviderModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	p.outs = PathsForModuleSrc(ctx, p.props.Outs)
+	p.tagged = PathsForModuleSrc(ctx, p.props.Tagged)
+}
+
+func (p *pathForModuleSrcOutputFileProviderModule) Srcs() Paths {
+	return append(Paths{}, p.outs...)
+}
+
+func (p *pathForModuleSrcOutputFileProviderModule) OutputFiles(tag string) (Paths, error) {
+	switch tag {
+	case "":
+		return p.Srcs(), nil
+	case "tagged":
+		return p.tagged, nil
+	default:
+		return nil, fmt.Errorf("unsupported tag %q", tag)
+	}
+}
+
 type pathForModuleSrcTestCase struct {
 	name string
 	bp   string
@@ -777,6 +850,8 @@ func testPathForModuleSrc(t *testing.T, buildDir string, tests []pathForModuleSr
 
 			ctx.RegisterModuleType("test", ModuleFactoryAdaptor(pathForModuleSrcTestModuleFactory))
 			ctx.RegisterModuleType("filegroup", ModuleFactoryAdaptor(FileGroupFactory))
+			ctx.RegisterModuleType("output_file_provider",
+				ModuleFactoryAdaptor(pathForModuleSrcOutputFileProviderModuleFactory))
 
 			fgBp := `
 				filegroup {
@@ -785,10 +860,21 @@ func testPathForModuleSrc(t *testing.T, buildDir string, tests []pathForModuleSr
 				}
 			`
 
+			ofpBp := `
+				output_file_provider {
+					name: "b",
+					outs: ["src/b", "src/c"],
+					tagged: ["src/c"],
+				}
+			`
+
 			mockFS := map[string][]byte{
 				"fg/Android.bp":     []byte(fgBp),
+				"ofp/Android.bp":    []byte(ofpBp),
 				"foo/Android.bp":    []byte(test.bp),
 				"fg/src/a":          nil,
+				"ofp/src/b":         nil,
+				"ofp/src/c":         nil,
 				"foo/src/b":         nil,
 				"foo/src/c":         nil,
 				"foo/src/d":         nil,
@@ -799,7 +885,7 @@ func testPathForModuleSrc(t *testing.T, buildDir string, tests []pathForModuleSr
 			ctx.MockFileSystem(mockFS)
 
 			ctx.Register()
-			_, errs := ctx.ParseFileList(".", []string{"fg/Android.bp", "foo/Android.bp"})
+			_, errs := ctx.ParseFileList(".", []string{"fg/Android.bp", "ofp/Android.bp", "foo/Android.bp"})
 			FailIfErrored(t, errs)
 			_, errs = ctx.PrepareBuildActions(config)
 			FailIfErrored(t, errs)
@@ -870,6 +956,37 @@ func TestPathsForModuleSrc(t *testing.T) {
 			srcs: []string{"fg/src/a"},
 			rels: []string{"src/a"},
 		},
+		{
+			name: "output file provider",
+			bp: `
+			test {
+				name: "foo",
+				srcs: [":b"],
+			}`,
+			srcs: []string{"ofp/src/b", "ofp/src/c"},
+			rels: []string{"src/b", "src/c"},
+		},
+		{
+			name: "output file provider tag",
+			bp: `
+			test {
+				name: "foo",
+				srcs: [":b{tagged}"],
+			}`,
+			srcs: []string{"ofp/src/c"},
+			rels: []string{"src/c"},
+		},
+		{
+			name: "output file provider tag excludes",
+			bp: `
+			test {
+				name: "foo",
+				srcs: [":b"],
+				exclude_srcs: [":b{tagged}"],
+			}`,
+			srcs: []string{"ofp/src/b"},
+			rels: []string{"src/b"},
+		},
 		{
 			name: "special characters glob",
 			bp: `
@@ -923,6 +1040,16 @@ func TestPathForModuleSrc(t *testing.T) {
 			src: "fg/src/a",
 			rel: "src/a",
 		},
+		{
+			name: "output file provider tag",
+			bp: `
+			test {
+				name: "foo",
+				src: ":b{tagged}",
+			}`,
+			src: "ofp/src/c",
+			rel: "src/c",
+		},
 		{
 			name: "special characters glob",
 			bp: `
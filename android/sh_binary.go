@@ -73,6 +73,8 @@ type ShTest struct {
 	ShBinary
 
 	testProperties TestProperties
+
+	testConfig Path
 }
 
 func (s *ShBinary) DepsMutator(ctx BottomUpMutatorContext) {
@@ -122,6 +124,24 @@ func (s *ShBinary) GenerateAndroidBuildActions(ctx ModuleContext) {
 	})
 }
 
+func (s *ShTest) GenerateAndroidBuildActions(ctx ModuleContext) {
+	s.ShBinary.GenerateAndroidBuildActions(ctx)
+
+	// Make uses the AndroidTest.xml in the module directory when test_config is not set.
+	if p := ctx.ExpandOptionalSource(s.testProperties.Test_config, "test_config"); p.Valid() {
+		s.testConfig = p.Path()
+	} else if p := ExistentPathForSource(ctx, ctx.ModuleDir(), "AndroidTest.xml"); p.Valid() {
+		s.testConfig = p.Path()
+	}
+}
+
+func (s *ShTest) TestModuleInfo() *TestModuleInfo {
+	return &TestModuleInfo{
+		TestSuites: s.testProperties.Test_suites,
+		TestConfig: s.testConfig,
+	}
+}
+
 func (s *ShBinary) AndroidMk() AndroidMkData {
 	return AndroidMkData{
 		Class:      "EXECUTABLES",
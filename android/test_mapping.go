@@ -0,0 +1,165 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/blueprint"
+)
+
+// This singleton validates the TEST_MAPPING files found by soong_ui, and writes an index of the
+// tests that cover each directory with a TEST_MAPPING file to out/soong/test_mapping/index.json.
+// The test suites and test configs of the Soong test modules are written to
+// out/soong/test_mapping/test_modules.json, and the test_mapping tool checks that every test
+// referenced by a TEST_MAPPING file is either a Soong test module with a test config or a module
+// defined in an Android.mk file.  It is built with the test-mapping-check goal.
+
+func init() {
+	RegisterSingletonType("test_mapping", testMappingSingletonFactory)
+
+	pctx.HostBinToolVariable("testMappingCmd", "test_mapping")
+}
+
+var (
+	testMappingCheck = pctx.AndroidStaticRule("testMappingCheck",
+		blueprint.RuleParams{
+			Command: "$testMappingCmd -modules $in -test_mappings $testMappingList " +
+				"-android_mks $androidMkList -o $out -d $out.d",
+			CommandDeps: []string{"$testMappingCmd"},
+			Deps:        blueprint.DepsGCC,
+			Depfile:     "$out.d",
+		},
+		"testMappingList", "androidMkList")
+)
+
+// TestModuleInfo describes a test module that can be referenced from TEST_MAPPING files.
+type TestModuleInfo struct {
+	// The test suites the test is part of.
+	TestSuites []string
+
+	// The tradefed config of the test, or nil if it has none.
+	TestConfig Path
+}
+
+// TestModuleInfoProvider is implemented by modules that can be tests.  TestModuleInfo returns nil
+// if the module is not a test.
+type TestModuleInfoProvider interface {
+	TestModuleInfo() *TestModuleInfo
+}
+
+// testMappingModule is an entry of test_modules.json.
+type testMappingModule struct {
+	Dir        string   `json:"dir"`
+	TestConfig string   `json:"test_config"`
+	TestSuites []string `json:"test_suites"`
+}
+
+func testMappingSingletonFactory() Singleton {
+	return &testMappingSingleton{}
+}
+
+type testMappingSingleton struct {
+	index Path
+}
+
+func (s *testMappingSingleton) GenerateBuildActions(ctx SingletonContext) {
+	modules := make(map[string]testMappingModule)
+	ctx.VisitAllModules(func(module Module) {
+		provider, ok := module.(TestModuleInfoProvider)
+		if !ok || !module.Enabled() {
+			return
+		}
+		info := provider.TestModuleInfo()
+		if info == nil {
+			return
+		}
+
+		// Merge the variants of the test.
+		name := ctx.ModuleName(module)
+		m, exists := modules[name]
+		if !exists {
+			m.Dir = ctx.ModuleDir(module)
+		}
+		if m.TestConfig == "" && info.TestConfig != nil {
+			m.TestConfig = info.TestConfig.String()
+		}
+		m.TestSuites = FirstUniqueStrings(append(m.TestSuites, info.TestSuites...))
+		modules[name] = m
+	})
+
+	for name, m := range modules {
+		sort.Strings(m.TestSuites)
+		modules[name] = m
+	}
+
+	modulesFile := PathForOutput(ctx, "test_mapping", "test_modules.json")
+	data, err := json.MarshalIndent(modules, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to write %s: %s", modulesFile, err)
+		return
+	}
+	if err := writeFileIfChanged(modulesFile.String(), data); err != nil {
+		ctx.Errorf("failed to write %s: %s", modulesFile, err)
+		return
+	}
+
+	// soong_ui writes the lists of the TEST_MAPPING and Android.mk files in the source tree to
+	// $OUT_DIR/.module_paths, next to the Soong output directory.
+	modulePathsDir := filepath.Join(filepath.Dir(ctx.Config().BuildDir()), ".module_paths")
+
+	index := PathForOutput(ctx, "test_mapping", "index.json")
+	ctx.Build(pctx, BuildParams{
+		Rule:        testMappingCheck,
+		Description: "check TEST_MAPPING files",
+		Input:       modulesFile,
+		Output:      index,
+		Args: map[string]string{
+			"testMappingList": filepath.Join(modulePathsDir, "TEST_MAPPING.list"),
+			"androidMkList":   filepath.Join(modulePathsDir, "Android.mk.list"),
+		},
+	})
+
+	ctx.Build(pctx, BuildParams{
+		Rule:        Phony,
+		Output:      PathForPhony(ctx, "test-mapping-check"),
+		Input:       index,
+		Description: "check TEST_MAPPING files",
+	})
+	s.index = index
+}
+
+func (s *testMappingSingleton) MakeVars(ctx MakeVarsContext) {
+	if s.index != nil {
+		ctx.Strict("SOONG_TEST_MAPPING_INDEX", s.index.String())
+	}
+}
+
+// writeFileIfChanged writes data to file unless it already contains data, so that the rules that
+// use the file don't rerun when the file is regenerated with the same contents.
+func writeFileIfChanged(file string, data []byte) error {
+	if old, err := ioutil.ReadFile(file); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0666)
+}
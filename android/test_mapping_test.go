@@ -0,0 +1,107 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTestMappingSingleton(t *testing.T) {
+	config, buildDir := setUp(t)
+	defer tearDown(buildDir)
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("sh_binary", ModuleFactoryAdaptor(ShBinaryFactory))
+	ctx.RegisterModuleType("sh_test", ModuleFactoryAdaptor(ShTestFactory))
+	ctx.RegisterSingletonType("test_mapping", SingletonFactoryAdaptor(testMappingSingletonFactory))
+	ctx.Register()
+
+	ctx.MockFileSystem(map[string][]byte{
+		"Android.bp": []byte(`
+			sh_test {
+				name: "foo_test",
+				src: "foo.sh",
+				test_config: "foo.xml",
+				test_suites: ["device-tests", "general-tests"],
+			}
+
+			sh_test {
+				name: "no_config_test",
+				src: "foo.sh",
+			}
+
+			sh_binary {
+				name: "baz",
+				src: "foo.sh",
+			}
+		`),
+		"bar/Android.bp": []byte(`
+			sh_test {
+				name: "bar_test",
+				src: "bar.sh",
+				test_suites: ["general-tests"],
+			}
+		`),
+		"foo.sh":              nil,
+		"foo.xml":             nil,
+		"bar/bar.sh":          nil,
+		"bar/AndroidTest.xml": nil,
+	})
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp", "bar/Android.bp"})
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	data, err := ioutil.ReadFile(filepath.Join(buildDir, "test_mapping", "test_modules.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modules map[string]testMappingModule
+	if err := json.Unmarshal(data, &modules); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]testMappingModule{
+		"foo_test": {
+			Dir:        ".",
+			TestConfig: "foo.xml",
+			TestSuites: []string{"device-tests", "general-tests"},
+		},
+		"bar_test": {
+			Dir:        "bar",
+			TestConfig: "bar/AndroidTest.xml",
+			TestSuites: []string{"general-tests"},
+		},
+		"no_config_test": {
+			Dir: ".",
+		},
+	}
+	if !reflect.DeepEqual(modules, expected) {
+		t.Errorf("incorrect test modules:\nwant: %#v\n got: %#v", expected, modules)
+	}
+
+	check := ctx.SingletonForTests("test_mapping").Rule("testMappingCheck")
+	if g, w := check.Output.String(), filepath.Join(buildDir, "test_mapping", "index.json"); g != w {
+		t.Errorf("want output %q, got %q", w, g)
+	}
+	if g, w := check.Args["testMappingList"], filepath.Join(filepath.Dir(buildDir), ".module_paths", "TEST_MAPPING.list"); g != w {
+		t.Errorf("want testMappingList %q, got %q", w, g)
+	}
+}
@@ -197,8 +197,11 @@ func callerName(skip int) (pkgPath, funcName string, ok bool) {
 }
 
 func GetNumericSdkVersion(v string) string {
-	if strings.Contains(v, "system_") {
-		return strings.Replace(v, "system_", "", 1)
+	// system_server_ must be checked before system_.
+	for _, prefix := range []string{"system_server_", "system_", "module_"} {
+		if strings.Contains(v, prefix) {
+			return strings.Replace(v, prefix, "", 1)
+		}
 	}
 	return v
 }
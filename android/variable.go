@@ -296,7 +296,10 @@ type productVariables struct {
 
 	PgoAdditionalProfileDirs []string `json:",omitempty"`
 
-	VndkUseCoreVariant *bool `json:",omitempty"`
+	VndkUseCoreVariant         *bool `json:",omitempty"`
+	VndkSnapshotBuildArtifacts *bool `json:",omitempty"`
+
+	CheckElfDepsExternalLibraries []string `json:",omitempty"`
 
 	BoardVendorSepolicyDirs      []string `json:",omitempty"`
 	BoardOdmSepolicyDirs         []string `json:",omitempty"`
@@ -368,7 +371,12 @@ func (v *productVariables) SetDefaultConfig() {
 		Safestack:         boolPtr(false),
 	}
 
-	if runtime.GOOS == "linux" {
+	if runtime.GOOS == "linux" && runtime.GOARCH == "arm64" {
+		v.HostArch = stringPtr("arm64")
+		v.HostSecondaryArch = nil
+	}
+
+	if runtime.GOOS == "linux" && runtime.GOARCH == "amd64" {
 		v.CrossHost = stringPtr("windows")
 		v.CrossHostArch = stringPtr("x86")
 		v.CrossHostSecondaryArch = stringPtr("x86_64")
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"sort"
 	"strings"
@@ -36,8 +37,8 @@ var (
 	pctx = android.NewPackageContext("android/apex")
 
 	// Create a canned fs config file where all files and directories are
-	// by default set to (uid/gid/mode) = (1000/1000/0644)
-	// TODO(b/113082813) make this configurable using config.fs syntax
+	// by default set to (uid/gid/mode) = (1000/1000/0644). The defaults can
+	// be overridden with a config.fs style file, see applyFsConfig.
 	generateFsConfig = pctx.StaticRule("generateFsConfig", blueprint.RuleParams{
 		Command: `echo '/ 1000 1000 0755' > ${out} && ` +
 			`echo '/apex_manifest.json 1000 1000 0644' >> ${out} && ` +
@@ -46,10 +47,43 @@ var (
 		Description: "fs_config ${out}",
 	}, "ro_paths", "exec_paths")
 
-	// TODO(b/113233103): make sure that file_contexts is sane, i.e., validate
-	// against the binary policy using sefcontext_compiler -p <policy>.
+	// Apply the ownership, mode and capabilities of the files listed in a config.fs style file
+	// to the default canned fs config.  The users and groups are looked up in the AIDs defined by
+	// android_filesystem_config.h.
+	applyFsConfig = pctx.AndroidStaticRule("applyFsConfig", blueprint.RuleParams{
+		Command:     `${canned_fs_config} -fs_config ${fs_config} -aid_header ${aid_header} -o ${out} ${in}`,
+		CommandDeps: []string{"${canned_fs_config}", "${aid_header}"},
+		Description: "fs_config ${out}",
+	}, "fs_config")
+
+	// Make sure that file_contexts is sane by compiling it against the binary policy.
+	validateFileContexts = pctx.AndroidStaticRule("validateFileContexts", blueprint.RuleParams{
+		Command:     `${sefcontext_compile} -o ${out} -p ${policy} ${in}`,
+		CommandDeps: []string{"${sefcontext_compile}"},
+		Description: "validate file_contexts ${in}",
+	}, "policy")
+
+	// Write the content manifest of an APEX from a list of "<module> <version> <path> <file>"
+	// lines, replacing the built file with its size.
+	apexContentManifestRule = pctx.StaticRule("apexContentManifestRule", blueprint.RuleParams{
+		Command: `(echo '# module version path size' && ` +
+			`while read module version path file; do ` +
+			`echo "$${module} $${version} $${path} $$(wc -c < $${file} | tr -d ' ')"; ` +
+			`done < ${in}) > ${out}`,
+		Description: "content manifest ${out}",
+	})
+
+	// Fail if an APEX contains modules that are not in its allowed_deps file, and show the
+	// difference between the allowed and the actual dependencies.
+	apexAllowedDepsRule = pctx.StaticRule("apexAllowedDepsRule", blueprint.RuleParams{
+		Command: `(grep -v -e '^#' -e '^$$' ${allowed_deps} || true) | LC_ALL=C sort -u > ${out}.allowed && ` +
+			`if [ -n "$$(LC_ALL=C comm -13 ${out}.allowed ${in})" ]; then ` +
+			`echo "${apex} contains modules that are not in ${allowed_deps}:" && ` +
+			`diff -u ${out}.allowed ${in}; exit 1; fi && ` +
+			`touch ${out}`,
+		Description: "check allowed deps ${apex}",
+	}, "allowed_deps", "apex")
 
-	// TODO(b/114327326): automate the generation of file_contexts
 	apexRule = pctx.StaticRule("apexRule", blueprint.RuleParams{
 		Command: `rm -rf ${image_dir} && mkdir -p ${image_dir} && ` +
 			`(${copy_commands}) && ` +
@@ -76,6 +110,17 @@ var (
 		Description: "ZipAPEX ${image_dir} => ${out}",
 	}, "tool_path", "image_dir", "copy_commands", "manifest")
 
+	// Report the size of the files in an APEX and the overhead of the image and the zip container
+	// around them.
+	apexSizeReportRule = pctx.StaticRule("apexSizeReportRule", blueprint.RuleParams{
+		Command: `payload=$$(awk '!/^#/ {s += $$4} END {printf "%d", s}' ${content_manifest}) && ` +
+			`apex=$$(wc -c < ${apex} | tr -d ' ') && ` +
+			`(echo "payload $${payload}" && ` +
+			`echo "image_overhead $$(($${apex} - $${payload}))" && ` +
+			`echo "apex $${apex}") > ${out}`,
+		Description: "size report ${out}",
+	}, "content_manifest", "apex")
+
 	apexProtoConvertRule = pctx.AndroidStaticRule("apexProtoConvertRule",
 		blueprint.RuleParams{
 			Command:     `${aapt2} convert --output-format proto $in -o $out`,
@@ -129,6 +174,7 @@ func init() {
 	}
 	hostBinToolVariableWithPrebuilt("aapt2", "prebuilts/sdk/tools", "aapt2")
 	pctx.HostBinToolVariable("avbtool", "avbtool")
+	pctx.HostBinToolVariable("canned_fs_config", "canned_fs_config")
 	pctx.HostBinToolVariable("e2fsdroid", "e2fsdroid")
 	pctx.HostBinToolVariable("merge_zips", "merge_zips")
 	pctx.HostBinToolVariable("mke2fs", "mke2fs")
@@ -138,6 +184,8 @@ func init() {
 	pctx.HostBinToolVariable("zip2zip", "zip2zip")
 	pctx.HostBinToolVariable("zipalign", "zipalign")
 
+	pctx.SourcePathVariable("aid_header", "system/core/libcutils/include/private/android_filesystem_config.h")
+
 	android.RegisterModuleType("apex", apexBundleFactory)
 	android.RegisterModuleType("apex_test", testApexBundleFactory)
 	android.RegisterModuleType("apex_defaults", defaultsFactory)
@@ -229,6 +277,22 @@ type apexBundleProperties struct {
 	// Default: <name_of_this_module>
 	File_contexts *string
 
+	// Generates the file contexts file from the files in this APEX bundle instead of using
+	// /system/sepolicy/apex/<file_contexts>-file_contexts.
+	Generated_file_contexts apexGeneratedFileContexts
+
+	// Binary sepolicy that the file contexts file is validated against with sefcontext_compile -p.
+	File_contexts_policy *string `android:"path"`
+
+	// File listing the modules that are allowed to be in this APEX bundle, one per line. The
+	// build fails when a module that is not listed is pulled in, directly or transitively.
+	Allowed_deps *string `android:"path"`
+
+	// config.fs style file that sets the ownership, mode and capabilities of files in this APEX
+	// bundle. Files and directories not listed keep the defaults of 1000/1000 0644, or 0/2000 0755
+	// for executables and directories.
+	Fs_config *string `android:"path"`
+
 	// List of native shared libs that are embedded inside this APEX bundle
 	Native_shared_libs []string
 
@@ -268,6 +332,26 @@ type apexBundleProperties struct {
 	SanitizerNames []string `blueprint:"mutated"`
 }
 
+type apexGeneratedFileContexts struct {
+	// Whether to generate the file contexts file. Default: false.
+	Enabled *bool
+
+	// Label of the files that have no label for their class. Default: u:object_r:system_file:s0
+	Default_label *string
+
+	// Label of native executables and scripts in bin.
+	Bin_label *string
+
+	// Label of native shared libraries in lib and lib64.
+	Lib_label *string
+
+	// Label of prebuilt files in etc.
+	Etc_label *string
+
+	// Label of java libraries in javalib.
+	Javalib_label *string
+}
+
 type apexTargetBundleProperties struct {
 	Target struct {
 		// Multilib properties only for android.
@@ -386,6 +470,7 @@ type apexBundle struct {
 
 	bundleModuleFile android.WritablePath
 	outputFiles      map[apexPackaging]android.WritablePath
+	sizeReport       android.WritablePath
 	installDir       android.OutputPath
 
 	public_key_file  android.Path
@@ -400,6 +485,12 @@ type apexBundle struct {
 	// list of module names that this APEX is depending on
 	externalDeps []string
 
+	// list of the modules in this apex, their version, path in the apex and size
+	contentManifest android.WritablePath
+
+	// stamp of the check that the modules in this apex are in allowed_deps
+	allowedDepsStamp android.WritablePath
+
 	flattened bool
 
 	testApex bool
@@ -810,6 +901,8 @@ func (a *apexBundle) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	a.installDir = android.PathForModuleInstall(ctx, "apex")
 	a.filesInfo = filesInfo
 
+	a.buildContentManifest(ctx)
+
 	if a.apexTypes.zip() {
 		a.buildUnflattenedApex(ctx, zipApex)
 	}
@@ -823,6 +916,80 @@ func (a *apexBundle) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 }
 
+// sdkVersioner is implemented by the modules that are built against a specific SDK version.
+type sdkVersioner interface {
+	SdkVersion() string
+}
+
+// buildContentManifest writes the list of the modules in this APEX with their version, path in
+// the APEX and size, and checks the modules against the allowed_deps file if there is one.
+func (a *apexBundle) buildContentManifest(ctx android.ModuleContext) {
+	var lines, deps []string
+	var builtFiles android.Paths
+	for _, f := range a.filesInfo {
+		name := strings.TrimPrefix(f.moduleName, ctx.ModuleName()+".")
+		version := "platform"
+		if m, ok := f.module.(sdkVersioner); ok && m.SdkVersion() != "" {
+			version = m.SdkVersion()
+		}
+		pathInApex := filepath.Join(f.installDir, f.builtFile.Base())
+		lines = append(lines, strings.Join([]string{name, version, pathInApex, f.builtFile.String()}, " "))
+		deps = append(deps, name)
+		builtFiles = append(builtFiles, f.builtFile)
+	}
+	sort.Strings(lines)
+	deps = android.FirstUniqueStrings(deps)
+	sort.Strings(deps)
+
+	contentsList := android.PathForModuleOut(ctx, "content_manifest.list")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Output:      contentsList,
+		Description: "content list",
+		Args: map[string]string{
+			"content": strings.Join(lines, "\\n"),
+		},
+	})
+
+	a.contentManifest = android.PathForModuleOut(ctx, ctx.ModuleName()+"-content_manifest.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        apexContentManifestRule,
+		Input:       contentsList,
+		Implicits:   builtFiles,
+		Output:      a.contentManifest,
+		Description: "content manifest",
+	})
+	ctx.CheckbuildFile(a.contentManifest)
+
+	if a.properties.Allowed_deps == nil {
+		return
+	}
+	allowedDeps := android.PathForModuleSrc(ctx, *a.properties.Allowed_deps)
+	depsList := android.PathForModuleOut(ctx, "deps.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Output:      depsList,
+		Description: "deps list",
+		Args: map[string]string{
+			"content": strings.Join(deps, "\\n"),
+		},
+	})
+
+	a.allowedDepsStamp = android.PathForModuleOut(ctx, "allowed_deps.stamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        apexAllowedDepsRule,
+		Input:       depsList,
+		Implicit:    allowedDeps,
+		Output:      a.allowedDepsStamp,
+		Description: "check allowed deps",
+		Args: map[string]string{
+			"allowed_deps": allowedDeps.String(),
+			"apex":         ctx.ModuleName(),
+		},
+	})
+	ctx.CheckbuildFile(a.allowedDepsStamp)
+}
+
 func (a *apexBundle) buildNoticeFile(ctx android.ModuleContext, apexFileName string) android.OptionalPath {
 	noticeFiles := []android.Path{}
 	for _, f := range a.filesInfo {
@@ -889,7 +1056,11 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext, apexType ap
 		}
 	}
 	implicitInputs := append(android.Paths(nil), filesToCopy...)
-	implicitInputs = append(implicitInputs, manifest)
+	implicitInputs = append(implicitInputs, manifest, a.contentManifest)
+	if a.allowedDepsStamp != nil {
+		// Don't build the APEX when it has modules that are not allowed.
+		implicitInputs = append(implicitInputs, a.allowedDepsStamp)
+	}
 
 	outHostBinDir := android.PathForOutput(ctx, "host", ctx.Config().PrebuiltOS(), "bin").String()
 	prebuiltSdkToolsBinDir := filepath.Join("prebuilts", "sdk", "tools", runtime.GOOS, "bin")
@@ -921,29 +1092,68 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext, apexType ap
 		sort.Strings(readOnlyPaths)
 		sort.Strings(executablePaths)
 		cannedFsConfig := android.PathForModuleOut(ctx, "canned_fs_config")
+		defaultFsConfig := cannedFsConfig
+		if a.properties.Fs_config != nil {
+			defaultFsConfig = android.PathForModuleOut(ctx, "canned_fs_config.default")
+		}
 		ctx.Build(pctx, android.BuildParams{
 			Rule:        generateFsConfig,
-			Output:      cannedFsConfig,
+			Output:      defaultFsConfig,
 			Description: "generate fs config",
 			Args: map[string]string{
 				"ro_paths":   strings.Join(readOnlyPaths, " "),
 				"exec_paths": strings.Join(executablePaths, " "),
 			},
 		})
+		if a.properties.Fs_config != nil {
+			fsConfig := android.PathForModuleSrc(ctx, *a.properties.Fs_config)
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        applyFsConfig,
+				Input:       defaultFsConfig,
+				Implicit:    fsConfig,
+				Output:      cannedFsConfig,
+				Description: "apply fs config",
+				Args: map[string]string{
+					"fs_config": fsConfig.String(),
+				},
+			})
+		}
 
-		fcName := proptools.StringDefault(a.properties.File_contexts, ctx.ModuleName())
-		fileContextsPath := "system/sepolicy/apex/" + fcName + "-file_contexts"
-		fileContextsOptionalPath := android.ExistentPathForSource(ctx, fileContextsPath)
-		if !fileContextsOptionalPath.Valid() {
-			ctx.ModuleErrorf("Cannot find file_contexts file: %q", fileContextsPath)
-			return
+		var fileContexts android.Path
+		if proptools.Bool(a.properties.Generated_file_contexts.Enabled) {
+			fileContexts = a.buildFileContexts(ctx)
+		} else {
+			fcName := proptools.StringDefault(a.properties.File_contexts, ctx.ModuleName())
+			fileContextsPath := "system/sepolicy/apex/" + fcName + "-file_contexts"
+			fileContextsOptionalPath := android.ExistentPathForSource(ctx, fileContextsPath)
+			if !fileContextsOptionalPath.Valid() {
+				ctx.ModuleErrorf("Cannot find file_contexts file: %q", fileContextsPath)
+				return
+			}
+			fileContexts = fileContextsOptionalPath.Path()
 		}
-		fileContexts := fileContextsOptionalPath.Path()
 
 		optFlags := []string{}
 
 		// Additional implicit inputs.
 		implicitInputs = append(implicitInputs, cannedFsConfig, fileContexts, a.private_key_file, a.public_key_file)
+		if a.properties.File_contexts_policy != nil {
+			// The compiled file contexts are only built to fail the build when file_contexts
+			// doesn't match the policy; apexer compiles file_contexts on its own.
+			policy := android.PathForModuleSrc(ctx, *a.properties.File_contexts_policy)
+			compiledFileContexts := android.PathForModuleOut(ctx, "file_contexts.bin")
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        validateFileContexts,
+				Input:       fileContexts,
+				Implicit:    policy,
+				Output:      compiledFileContexts,
+				Description: "validate file_contexts",
+				Args: map[string]string{
+					"policy": policy.String(),
+				},
+			})
+			implicitInputs = append(implicitInputs, compiledFileContexts)
+		}
 		optFlags = append(optFlags, "--pubkey "+a.public_key_file.String())
 
 		manifestPackageName, overridden := ctx.DeviceConfig().OverrideManifestPackageNameFor(ctx.ModuleName())
@@ -1039,12 +1249,85 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext, apexType ap
 		},
 	})
 
+	if apexType == imageApex {
+		a.buildSizeReport(ctx)
+	}
+
 	// Install to $OUT/soong/{target,host}/.../apex
 	if a.installable() && (!ctx.Config().FlattenApex() || apexType.zip()) {
 		ctx.InstallFile(a.installDir, ctx.ModuleName()+suffix, a.outputFiles[apexType])
 	}
 }
 
+// buildSizeReport writes the size breakdown of the image APEX, which is dist'ed with the APEX.
+func (a *apexBundle) buildSizeReport(ctx android.ModuleContext) {
+	apexFile := a.outputFiles[imageApex]
+	a.sizeReport = android.PathForModuleOut(ctx, ctx.ModuleName()+"-size_report.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        apexSizeReportRule,
+		Implicits:   android.Paths{a.contentManifest, apexFile},
+		Output:      a.sizeReport,
+		Description: "size report",
+		Args: map[string]string{
+			"content_manifest": a.contentManifest.String(),
+			"apex":             apexFile.String(),
+		},
+	})
+}
+
+// fileContextsLabel returns the label of the files of the given class in the generated file
+// contexts, or an empty string if they get the default label.
+func (a *apexBundle) fileContextsLabel(class apexFileClass) string {
+	props := a.properties.Generated_file_contexts
+	switch class {
+	case etc:
+		return String(props.Etc_label)
+	case nativeSharedLib:
+		return String(props.Lib_label)
+	case nativeExecutable, shBinary, pyBinary, goBinary:
+		return String(props.Bin_label)
+	case javaSharedLib:
+		return String(props.Javalib_label)
+	default:
+		panic(fmt.Errorf("unknown class %d", class))
+	}
+}
+
+// buildFileContexts generates a file contexts file that gives every file in the APEX bundle the
+// default label, and the files of classes that have a label of their own that label.
+func (a *apexBundle) buildFileContexts(ctx android.ModuleContext) android.Path {
+	props := a.properties.Generated_file_contexts
+	lines := []string{"(/.*)? " + proptools.StringDefault(props.Default_label, "u:object_r:system_file:s0")}
+
+	var labeled []string
+	for _, f := range a.filesInfo {
+		label := a.fileContextsLabel(f.class)
+		if label == "" {
+			continue
+		}
+		paths := []string{filepath.Join(f.installDir, f.builtFile.Base())}
+		for _, sym := range f.symlinks {
+			paths = append(paths, filepath.Join(f.installDir, sym))
+		}
+		for _, path := range paths {
+			labeled = append(labeled, "/"+regexp.QuoteMeta(path)+" "+label)
+		}
+	}
+	sort.Strings(labeled)
+	lines = append(lines, android.FirstUniqueStrings(labeled)...)
+
+	fileContexts := android.PathForModuleOut(ctx, "file_contexts")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Output:      fileContexts,
+		Description: "generate file_contexts",
+		Args: map[string]string{
+			"content": strings.Join(lines, "\\n"),
+		},
+	})
+	return fileContexts
+}
+
 func (a *apexBundle) buildFlattenedApex(ctx android.ModuleContext) {
 	if a.installable() {
 		// For flattened APEX, do nothing but make sure that apex_manifest.json and apex_pubkey are also copied along
@@ -1226,6 +1509,11 @@ func (a *apexBundle) androidMkForType(apexType apexPackaging) android.AndroidMkD
 					fmt.Fprintln(w, "ALL_MODULES.$(LOCAL_MODULE).BUNDLE :=", a.bundleModuleFile.String())
 				}
 			}
+
+			if apexType == imageApex && a.sizeReport != nil {
+				fmt.Fprintln(w, "$(call dist-for-goals,droidcore,"+
+					a.sizeReport.String()+":apex_size/"+a.sizeReport.Base()+")")
+			}
 		}}
 }
 
@@ -1308,6 +1596,9 @@ type PrebuiltProperties struct {
 		Arm64 struct {
 			Src *string
 		}
+		Riscv64 struct {
+			Src *string
+		}
 		X86 struct {
 			Src *string
 		}
@@ -1357,6 +1648,8 @@ func (p *Prebuilt) DepsMutator(ctx android.BottomUpMutatorContext) {
 		src = String(p.properties.Arch.Arm.Src)
 	case android.Arm64:
 		src = String(p.properties.Arch.Arm64.Src)
+	case android.Riscv64:
+		src = String(p.properties.Arch.Riscv64.Src)
 	case android.X86:
 		src = String(p.properties.Arch.X86.Src)
 	case android.X86_64:
@@ -182,6 +182,9 @@ func testApex(t *testing.T, bp string) *android.TestContext {
 		"myapex-arm64.apex":                    nil,
 		"myapex-arm.apex":                      nil,
 		"frameworks/base/api/current.txt":      nil,
+		"myapex.fs_config":                     nil,
+		"myapex.allowed_deps":                  nil,
+		"sepolicy.bin":                         nil,
 	})
 	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
 	android.FailIfErrored(t, errs)
@@ -741,6 +744,185 @@ func TestFilesInSubDir(t *testing.T) {
 	ensureListContains(t, dirs, "bin/foo/bar")
 }
 
+func TestFsConfigAndGeneratedFileContexts(t *testing.T) {
+	ctx := testApex(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			native_shared_libs: ["mylib"],
+			binaries: ["mybin"],
+			prebuilts: ["myetc"],
+			fs_config: "myapex.fs_config",
+			file_contexts_policy: "sepolicy.bin",
+			generated_file_contexts: {
+				enabled: true,
+				bin_label: "u:object_r:mybin_exec:s0",
+				lib_label: "u:object_r:mylib_file:s0",
+			},
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		prebuilt_etc {
+			name: "myetc",
+			src: "myprebuilt",
+		}
+
+		cc_library {
+			name: "mylib",
+			srcs: ["mylib.cpp"],
+			system_shared_libs: [],
+			stl: "none",
+		}
+
+		cc_binary {
+			name: "mybin",
+			srcs: ["mylib.cpp"],
+			system_shared_libs: [],
+			static_executable: true,
+			stl: "none",
+		}
+	`)
+
+	module := ctx.ModuleForTests("myapex", "android_common_myapex")
+
+	// Ensure that the default fs config is post-processed with the config.fs file
+	generateFsRule := module.Rule("generateFsConfig")
+	ensureContains(t, generateFsRule.Output.String(), "canned_fs_config.default")
+	applyFsRule := module.Rule("applyFsConfig")
+	if g, w := applyFsRule.Input.String(), generateFsRule.Output.String(); g != w {
+		t.Errorf("expected applyFsConfig input %q, got %q", w, g)
+	}
+	ensureContains(t, applyFsRule.Args["fs_config"], "myapex.fs_config")
+	ensureContains(t, applyFsRule.RuleParams.Command, "-aid_header ${aid_header}")
+
+	// Ensure that file_contexts is generated with the labels of each class
+	fileContexts := module.Output("file_contexts")
+	lines := strings.Split(fileContexts.Args["content"], "\\n")
+	ensureListContains(t, lines, "(/.*)? u:object_r:system_file:s0")
+	ensureListContains(t, lines, "/lib64/mylib\\.so u:object_r:mylib_file:s0")
+	ensureListContains(t, lines, "/bin/mybin u:object_r:mybin_exec:s0")
+	for _, line := range lines {
+		ensureNotContains(t, line, "myprebuilt")
+	}
+
+	// Ensure that file_contexts is validated against the policy
+	validateRule := module.Rule("validateFileContexts")
+	if g, w := validateRule.Input.String(), fileContexts.Output.String(); g != w {
+		t.Errorf("expected validateFileContexts input %q, got %q", w, g)
+	}
+	ensureContains(t, validateRule.Args["policy"], "sepolicy.bin")
+
+	apexRule := module.Rule("apexRule")
+	if g, w := apexRule.Args["canned_fs_config"], applyFsRule.Output.String(); g != w {
+		t.Errorf("expected canned_fs_config %q, got %q", w, g)
+	}
+	if g, w := apexRule.Args["file_contexts"], fileContexts.Output.String(); g != w {
+		t.Errorf("expected file_contexts %q, got %q", w, g)
+	}
+	ensureListContains(t, apexRule.Implicits.Strings(), validateRule.Output.String())
+}
+
+func TestContentManifestAndAllowedDeps(t *testing.T) {
+	ctx := testApex(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			native_shared_libs: ["mylib"],
+			prebuilts: ["myetc"],
+			allowed_deps: "myapex.allowed_deps",
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		prebuilt_etc {
+			name: "myetc",
+			src: "myprebuilt",
+		}
+
+		cc_library {
+			name: "mylib",
+			srcs: ["mylib.cpp"],
+			shared_libs: ["mylib2"],
+			system_shared_libs: [],
+			stl: "none",
+		}
+
+		cc_library {
+			name: "mylib2",
+			srcs: ["mylib.cpp"],
+			system_shared_libs: [],
+			stl: "none",
+		}
+	`)
+
+	module := ctx.ModuleForTests("myapex", "android_common_myapex")
+
+	// Ensure that the transitive dependencies are in the content manifest
+	contents := strings.Split(module.Output("content_manifest.list").Args["content"], "\\n")
+	mylib := ctx.ModuleForTests("mylib", "android_arm64_armv8-a_core_shared_myapex").Module().(*cc.Module)
+	ensureListContains(t, contents, "mylib platform lib64/mylib.so "+mylib.OutputFile().Path().String())
+	found := false
+	for _, line := range contents {
+		if strings.HasPrefix(line, "mylib2 platform lib64/mylib2.so ") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("mylib2 is not found in the content manifest %q", contents)
+	}
+	module.Output("myapex-content_manifest.txt")
+
+	// Ensure that all the dependencies are checked against allowed_deps
+	deps := module.Output("deps.txt").Args["content"]
+	if g, w := deps, "myetc\\nmylib\\nmylib2"; g != w {
+		t.Errorf("expected deps %q, got %q", w, g)
+	}
+	allowedDepsRule := module.Rule("apexAllowedDepsRule")
+	ensureContains(t, allowedDepsRule.Args["allowed_deps"], "myapex.allowed_deps")
+
+	// Ensure that the APEX is not built unless the check passes
+	apexRule := module.Rule("apexRule")
+	ensureListContains(t, apexRule.Implicits.Strings(), allowedDepsRule.Output.String())
+}
+
+func TestApexSizeReport(t *testing.T) {
+	ctx := testApex(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			prebuilts: ["myetc"],
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		prebuilt_etc {
+			name: "myetc",
+			src: "myprebuilt",
+		}
+	`)
+
+	module := ctx.ModuleForTests("myapex", "android_common_myapex")
+
+	// Ensure that the size report is computed from the content manifest and the signed APEX
+	sizeReport := module.Rule("apexSizeReportRule")
+	ensureContains(t, sizeReport.Output.String(), "myapex-size_report.txt")
+	ensureContains(t, sizeReport.Args["content_manifest"], "myapex-content_manifest.txt")
+	ensureContains(t, sizeReport.Args["apex"], "myapex.apex")
+}
+
 func TestUseVendor(t *testing.T) {
 	ctx := testApex(t, `
 		apex {
@@ -23,11 +23,13 @@ import (
 	_ "android/soong/cc/config"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 func init() {
 	android.RegisterModuleType("bpf", bpfFactory)
 	pctx.Import("android/soong/cc/config")
+	pctx.HostBinToolVariable("checkBpfCmd", "check_bpf")
 }
 
 var (
@@ -41,12 +43,27 @@ var (
 			CommandDeps: []string{"$ccCmd"},
 		},
 		"ccCmd", "cFlags")
+
+	// The bpfloader only finds out at boot that an object can't be loaded, check it at build time
+	// instead.
+	check = pctx.AndroidStaticRule("check",
+		blueprint.RuleParams{
+			Command:     "$checkBpfCmd -o $out $in",
+			CommandDeps: []string{"$checkBpfCmd"},
+		})
 )
 
 type BpfProperties struct {
 	Srcs         []string `android:"path"`
 	Cflags       []string
 	Include_dirs []string
+
+	// Additional cflags for individual sources, as <src>=<flags>, e.g. "foo.c=-DFOO -DBAR".
+	Src_cflags []string
+
+	// Generate BTF type information with -g.  The bpfloader ignores it, but it is needed by
+	// tools that inspect the programs and maps.
+	Btf *bool
 }
 
 type bpf struct {
@@ -74,23 +91,54 @@ func (bpf *bpf) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		cflags = append(cflags, "-I "+dir.String())
 	}
 
+	if proptools.Bool(bpf.properties.Btf) {
+		cflags = append(cflags, "-g")
+	}
+
 	cflags = append(cflags, bpf.properties.Cflags...)
 
 	srcs := android.PathsForModuleSrc(ctx, bpf.properties.Srcs)
 
+	srcCflags := make(map[string][]string)
+	for _, srcFlags := range bpf.properties.Src_cflags {
+		kv := strings.SplitN(srcFlags, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			ctx.PropertyErrorf("src_cflags", "expected <src>=<flags>, got %q", srcFlags)
+			continue
+		}
+		found := false
+		for _, src := range srcs {
+			if src.Rel() == kv[0] {
+				found = true
+			}
+		}
+		if !found {
+			ctx.PropertyErrorf("src_cflags", "%q is not in srcs", kv[0])
+			continue
+		}
+		srcCflags[kv[0]] = append(srcCflags[kv[0]], kv[1])
+	}
+
 	for _, src := range srcs {
+		unchecked := android.ObjPathWithExt(ctx, "unchecked", src, "o")
 		obj := android.ObjPathWithExt(ctx, "", src, "o")
 
 		ctx.Build(pctx, android.BuildParams{
 			Rule:   cc,
 			Input:  src,
-			Output: obj,
+			Output: unchecked,
 			Args: map[string]string{
-				"cFlags": strings.Join(cflags, " "),
+				"cFlags": strings.Join(append(cflags, srcCflags[src.Rel()]...), " "),
 				"ccCmd":  "${config.ClangBin}/clang",
 			},
 		})
 
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   check,
+			Input:  unchecked,
+			Output: obj,
+		})
+
 		bpf.objs = append(bpf.objs, obj.WithoutRel())
 	}
 }
@@ -17,6 +17,8 @@ package bpf
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"android/soong/android"
@@ -190,3 +192,42 @@ func TestBpfDataDependency(t *testing.T) {
 	// value is not available for testing from this package.
 	// TODO(jungjw): Add a check for data or move this test to the cc package.
 }
+
+func TestBpfCheckAndFlags(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	bp := `
+		bpf {
+			name: "bpf.o",
+			srcs: ["bpf.c"],
+			cflags: ["-DALL"],
+			src_cflags: ["bpf.c=-DONLY_BPF"],
+			btf: true,
+		}
+	`
+
+	ctx := testContext(bp)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if errs == nil {
+		_, errs = ctx.PrepareBuildActions(config)
+	}
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	module := ctx.ModuleForTests("bpf.o", "android_common")
+
+	compile := module.Rule("cc")
+	for _, w := range []string{"-g", "-DALL", "-DONLY_BPF"} {
+		if !android.InList(w, strings.Fields(compile.Args["cFlags"])) {
+			t.Errorf("expected %q in cFlags, got %q", w, compile.Args["cFlags"])
+		}
+	}
+
+	check := module.Rule("check")
+	if g, w := check.Input.String(), compile.Output.String(); g != w {
+		t.Errorf("expected the check of %q, got %q", w, g)
+	}
+	if g, w := module.Module().(*bpf).objs.Strings(), []string{check.Output.String()}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected the checked objects %q, got %q", w, g)
+	}
+}
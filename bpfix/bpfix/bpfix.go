@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/google/blueprint/parser"
@@ -114,6 +115,18 @@ func (r FixRequest) AddAll() (result FixRequest) {
 	return result
 }
 
+// AddRemoveDeps returns a copy of the FixRequest that also removes dependencies from list
+// properties, keyed by module name and then by property name, for example the dependencies
+// reported as unused by cc_unused_deps.
+func (r FixRequest) AddRemoveDeps(deps map[string]map[string][]string) (result FixRequest) {
+	result.steps = append([]fixStep(nil), r.steps...)
+	result.steps = append(result.steps, fixStep{
+		name: "removeDeps",
+		fix:  runPatchListMod(removeDeps(deps)),
+	})
+	return result
+}
+
 type Fixer struct {
 	tree *parser.File
 }
@@ -897,6 +910,52 @@ func removeMatchingModuleListProperties(mod *parser.Module, patchList *parser.Pa
 	return nil
 }
 
+// removeDeps returns a function that removes the given dependencies from the literal list properties
+// of a module, and removes the property entirely if the list becomes empty.
+func removeDeps(deps map[string]map[string][]string) func(mod *parser.Module, buf []byte, patchList *parser.PatchList) error {
+	return func(mod *parser.Module, buf []byte, patchList *parser.PatchList) error {
+		name, ok := getLiteralStringPropertyValue(mod, "name")
+		if !ok {
+			return nil
+		}
+
+		var propNames []string
+		for propName := range deps[name] {
+			propNames = append(propNames, propName)
+		}
+		sort.Strings(propNames)
+
+		for _, propName := range propNames {
+			prop, ok := mod.GetProperty(propName)
+			if !ok {
+				continue
+			}
+			list, ok := prop.Value.(*parser.List)
+			if !ok || len(list.Values) == 0 {
+				continue
+			}
+
+			removals := &parser.List{}
+			for _, dep := range deps[name][propName] {
+				removals.Values = append(removals.Values, &parser.String{Value: dep})
+			}
+
+			localPatches := parser.PatchList{}
+			filterExpressionList(&localPatches, list, removals)
+
+			if len(list.Values) == 0 {
+				patchList.Add(prop.Pos().Offset, prop.End().Offset+2, "")
+			} else {
+				for _, p := range localPatches {
+					patchList.Add(p.Start, p.End, p.Replacement)
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
 func hasNonEmptyLiteralListProperty(mod *parser.Module, name string) bool {
 	list, found := getLiteralListProperty(mod, name)
 	return found && len(list.Values) > 0
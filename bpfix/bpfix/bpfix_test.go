@@ -784,3 +784,76 @@ func TestRewriteAndroidTest(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveDeps(t *testing.T) {
+	deps := map[string]map[string][]string{
+		"foo": {
+			"shared_libs": {"libbar", "libbaz"},
+			"header_libs": {"libhdr"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			name: "simple",
+			in: `
+				cc_library {
+					name: "foo",
+					shared_libs: [
+						"libbar",
+						"libc",
+					],
+				}
+			`,
+			out: `
+				cc_library {
+					name: "foo",
+					shared_libs: [
+						"libc",
+					],
+				}
+			`,
+		},
+		{
+			name: "fully removed",
+			in: `
+				cc_library {
+					name: "foo",
+					shared_libs: ["libbar", "libbaz"],
+					header_libs: ["libhdr"],
+					static_libs: ["libbar"],
+				}
+			`,
+			out: `
+				cc_library {
+					name: "foo",
+					static_libs: ["libbar"],
+				}
+			`,
+		},
+		{
+			name: "other module",
+			in: `
+				cc_library {
+					name: "foo2",
+					shared_libs: ["libbar"],
+				}
+			`,
+			out: `
+				cc_library {
+					name: "foo2",
+					shared_libs: ["libbar"],
+				}
+			`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runPass(t, test.in, test.out, runPatchListMod(removeDeps(deps)))
+		})
+	}
+}
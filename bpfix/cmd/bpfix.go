@@ -27,6 +27,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/google/blueprint/parser"
 
@@ -38,6 +39,11 @@ var (
 	list   = flag.Bool("l", false, "list files whose formatting differs from bpfmt's")
 	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
 	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+
+	// instead of the standard fixes, remove the dependencies listed in the report written by
+	// the cc-unused-deps goal
+	removeUnusedCcDeps = flag.String("remove_unused_cc_deps", "",
+		"remove the dependencies listed in the given cc_unused_deps.txt report instead of applying the standard fixes")
 )
 
 var (
@@ -128,12 +134,62 @@ func walkDir(path string, fixRequest bpfix.FixRequest) {
 	filepath.Walk(path, makeFileVisitor(fixRequest))
 }
 
+// readUnusedCcDeps reads a report written by cc_unused_deps, where each line holds the Android.bp
+// file, module, property and dependency separated by tabs.  It returns the dependencies keyed by
+// module and property, and the Android.bp files that contain them.
+func readUnusedCcDeps(filename string) (map[string]map[string][]string, []string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deps := make(map[string]map[string][]string)
+	var files []string
+	seenFiles := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, nil, fmt.Errorf("%s: malformed line %q", filename, line)
+		}
+		file, module, property, dep := fields[0], fields[1], fields[2], fields[3]
+		if deps[module] == nil {
+			deps[module] = make(map[string][]string)
+		}
+		deps[module][property] = append(deps[module][property], dep)
+		if !seenFiles[file] {
+			seenFiles[file] = true
+			files = append(files, file)
+		}
+	}
+
+	return deps, files, nil
+}
+
 func main() {
 	flag.Parse()
 
 	fixRequest := bpfix.NewFixRequest().AddAll()
+	args := flag.Args()
+
+	if *removeUnusedCcDeps != "" {
+		deps, files, err := readUnusedCcDeps(*removeUnusedCcDeps)
+		if err != nil {
+			report(err)
+			return
+		}
+		fixRequest = bpfix.NewFixRequest().AddRemoveDeps(deps)
+		if len(args) == 0 {
+			args = files
+			if len(args) == 0 {
+				return
+			}
+		}
+	}
 
-	if flag.NArg() == 0 {
+	if len(args) == 0 {
 		if *write {
 			fmt.Fprintln(os.Stderr, "error: cannot use -w with standard input")
 			exitCode = 2
@@ -145,8 +201,7 @@ func main() {
 		return
 	}
 
-	for i := 0; i < flag.NArg(); i++ {
-		path := flag.Arg(i)
+	for _, path := range args {
 		switch dir, err := os.Stat(path); {
 		case err != nil:
 			report(err)
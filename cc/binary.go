@@ -85,6 +85,9 @@ type binaryDecorator struct {
 	// Location of the linked, unstripped binary
 	unstrippedOutputFile android.Path
 
+	// Location of the link map of the unstripped binary, if one was written
+	linkMap android.OptionalPath
+
 	// Names of symlinks to be installed for use in LOCAL_MODULE_SYMLINKS
 	symlinks []string
 
@@ -382,7 +385,7 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 	linkerDeps = append(linkerDeps, objs.tidyFiles...)
 	linkerDeps = append(linkerDeps, flags.LdFlagsDeps...)
 
-	TransformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs, deps.StaticLibs,
+	binary.linkMap = TransformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs, deps.StaticLibs,
 		deps.LateStaticLibs, deps.WholeStaticLibs, linkerDeps, deps.CrtBegin, deps.CrtEnd, true,
 		builderFlags, outputFile)
 
@@ -413,6 +416,10 @@ func (binary *binaryDecorator) unstrippedOutputFilePath() android.Path {
 	return binary.unstrippedOutputFile
 }
 
+func (binary *binaryDecorator) linkMapPath() android.OptionalPath {
+	return binary.linkMap
+}
+
 func (binary *binaryDecorator) symlinkList() []string {
 	return binary.symlinks
 }
@@ -62,6 +62,18 @@ var (
 		},
 		"ccCmd", "cFlags")
 
+	// ccPostDeps is cc followed by $postDeps, commands that read the depfile, which have to run in
+	// the same command because ninja deletes the depfile once it has read it.
+	ccPostDeps = pctx.AndroidGomaStaticRule("ccPostDeps",
+		blueprint.RuleParams{
+			Depfile: "${out}.d",
+			Deps:    blueprint.DepsGCC,
+			Command: "$relPwd ${config.CcWrapper}$ccCmd -c $cFlags -MD -MF ${out}.d -o $out $in && " +
+				"($postDeps || (rm -f $out && false))",
+			CommandDeps: []string{"$ccCmd"},
+		},
+		"ccCmd", "cFlags", "postDeps")
+
 	ld = pctx.AndroidStaticRule("ld",
 		blueprint.RuleParams{
 			Command: "$ldCmd ${crtBegin} @${out}.rsp " +
@@ -255,6 +267,7 @@ type builderFlags struct {
 	yaccFlags       string
 	tidyFlags       string
 	sAbiFlags       string
+	layeringFlags   string
 	yasmFlags       string
 	aidlFlags       string
 	rsFlags         string
@@ -263,6 +276,8 @@ type builderFlags struct {
 	tidy            bool
 	coverage        bool
 	sAbiDump        bool
+	saveDeps        bool
+	linkMap         bool
 
 	systemIncludeFlags string
 
@@ -284,6 +299,7 @@ type Objects struct {
 	tidyFiles     android.Paths
 	coverageFiles android.Paths
 	sAbiDumpFiles android.Paths
+	depFiles      android.Paths // Copies of the depfiles, only saved when needed
 }
 
 func (a Objects) Copy() Objects {
@@ -292,6 +308,7 @@ func (a Objects) Copy() Objects {
 		tidyFiles:     append(android.Paths{}, a.tidyFiles...),
 		coverageFiles: append(android.Paths{}, a.coverageFiles...),
 		sAbiDumpFiles: append(android.Paths{}, a.sAbiDumpFiles...),
+		depFiles:      append(android.Paths{}, a.depFiles...),
 	}
 }
 
@@ -301,6 +318,7 @@ func (a Objects) Append(b Objects) Objects {
 		tidyFiles:     append(a.tidyFiles, b.tidyFiles...),
 		coverageFiles: append(a.coverageFiles, b.coverageFiles...),
 		sAbiDumpFiles: append(a.sAbiDumpFiles, b.sAbiDumpFiles...),
+		depFiles:      append(a.depFiles, b.depFiles...),
 	}
 }
 
@@ -317,6 +335,10 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 	if flags.coverage {
 		coverageFiles = make(android.Paths, 0, len(srcFiles))
 	}
+	var depFiles android.Paths
+	if flags.saveDeps {
+		depFiles = make(android.Paths, 0, len(srcFiles))
+	}
 
 	commonFlags := strings.Join([]string{
 		flags.globalFlags,
@@ -445,18 +467,42 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			coverageFiles = append(coverageFiles, gcnoFile)
 		}
 
+		args := map[string]string{
+			"cFlags": moduleCflags + extraFlags,
+			"ccCmd":  ccCmd,
+		}
+		implicits := cFlagsDeps
+
+		if rule == cc {
+			var postDeps []string
+			depFile := objFile.String() + ".d"
+			if flags.layeringFlags != "" {
+				layeringCheck := ctx.Config().HostToolPath(ctx, "header_layering_check")
+				implicits = append(android.Paths{layeringCheck}, implicits...)
+				postDeps = append(postDeps, strings.Join([]string{layeringCheck.String(),
+					flags.layeringFlags, depFile}, " "))
+			}
+			if flags.saveDeps {
+				savedDepFile := android.ObjPathWithExt(ctx, subdir, srcFile, "deps")
+				implicitOutputs = append(implicitOutputs, savedDepFile)
+				depFiles = append(depFiles, savedDepFile)
+				postDeps = append(postDeps, "cp -f "+depFile+" "+savedDepFile.String())
+			}
+			if len(postDeps) > 0 {
+				rule = ccPostDeps
+				args["postDeps"] = strings.Join(postDeps, " && ")
+			}
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:            rule,
 			Description:     ccDesc + " " + srcFile.Rel(),
 			Output:          objFile,
 			ImplicitOutputs: implicitOutputs,
 			Input:           srcFile,
-			Implicits:       cFlagsDeps,
+			Implicits:       implicits,
 			OrderOnly:       pathDeps,
-			Args: map[string]string{
-				"cFlags": moduleCflags + extraFlags,
-				"ccCmd":  ccCmd,
-			},
+			Args:            args,
 		})
 
 		if tidy {
@@ -502,6 +548,7 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 		tidyFiles:     tidyFiles,
 		coverageFiles: coverageFiles,
 		sAbiDumpFiles: sAbiDumpFiles,
+		depFiles:      depFiles,
 	}
 }
 
@@ -613,10 +660,12 @@ func transformDarwinObjToStaticLib(ctx android.ModuleContext, objFiles android.P
 }
 
 // Generate a rule for compiling multiple .o files, plus static libraries, whole static libraries,
-// and shared libraries, to a shared library (.so) or dynamic executable
+// and shared libraries, to a shared library (.so) or dynamic executable.  Returns the link map if
+// one was written.
 func TransformObjToDynamicBinary(ctx android.ModuleContext,
 	objFiles, sharedLibs, staticLibs, lateStaticLibs, wholeStaticLibs, deps android.Paths,
-	crtBegin, crtEnd android.OptionalPath, groupLate bool, flags builderFlags, outputFile android.WritablePath) {
+	crtBegin, crtEnd android.OptionalPath, groupLate bool, flags builderFlags,
+	outputFile android.WritablePath) android.OptionalPath {
 
 	var ldCmd string
 	var extraFlags string
@@ -670,12 +719,22 @@ func TransformObjToDynamicBinary(ctx android.ModuleContext,
 		deps = append(deps, crtBegin.Path(), crtEnd.Path())
 	}
 
+	var implicitOutputs android.WritablePaths
+	var linkMap android.OptionalPath
+	if flags.linkMap && !ctx.Darwin() {
+		mapFile := linkMapPath(ctx, outputFile)
+		implicitOutputs = append(implicitOutputs, mapFile)
+		extraFlags += " -Wl,-Map=" + mapFile.String()
+		linkMap = android.OptionalPathForPath(mapFile)
+	}
+
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        ld,
-		Description: "link " + outputFile.Base(),
-		Output:      outputFile,
-		Inputs:      objFiles,
-		Implicits:   deps,
+		Rule:            ld,
+		Description:     "link " + outputFile.Base(),
+		Output:          outputFile,
+		ImplicitOutputs: implicitOutputs,
+		Inputs:          objFiles,
+		Implicits:       deps,
 		Args: map[string]string{
 			"ldCmd":    ldCmd,
 			"crtBegin": crtBegin.String(),
@@ -684,6 +743,14 @@ func TransformObjToDynamicBinary(ctx android.ModuleContext,
 			"crtEnd":   crtEnd.String(),
 		},
 	})
+
+	return linkMap
+}
+
+// linkMapPath returns the path of the link map written by TransformObjToDynamicBinary for
+// outputFile when the linkMap flag is set.
+func linkMapPath(ctx android.ModuleContext, outputFile android.Path) android.ModuleOutPath {
+	return android.PathForModuleOut(ctx, outputFile.Base()+".map")
 }
 
 // Generate a rule to combine .dump sAbi dump files from multiple source files
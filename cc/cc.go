@@ -60,6 +60,9 @@ func init() {
 		ctx.TopDown("tsan_deps", sanitizerDepsMutator(tsan))
 		ctx.BottomUp("tsan", sanitizerMutator(tsan)).Parallel()
 
+		ctx.TopDown("fuzzer_deps", sanitizerDepsMutator(fuzzer))
+		ctx.BottomUp("fuzzer", sanitizerMutator(fuzzer)).Parallel()
+
 		ctx.TopDown("sanitize_runtime_deps", sanitizerRuntimeDepsMutator)
 		ctx.BottomUp("sanitize_runtime", sanitizerRuntimeMutator).Parallel()
 
@@ -143,6 +146,7 @@ type Flags struct {
 	libFlags        []string // Flags to add libraries early to the link order
 	TidyFlags       []string // Flags that apply to clang-tidy
 	SAbiFlags       []string // Flags that apply to header-abi-dumper
+	LayeringFlags   []string // Flags that apply to header_layering_check, which runs if non-empty
 	YasmFlags       []string // Flags that apply to yasm assembly source files
 
 	// Global include flags that apply to C, C++, and assembly source files
@@ -154,6 +158,8 @@ type Flags struct {
 	Tidy      bool
 	Coverage  bool
 	SAbiDump  bool
+	SaveDeps  bool // Whether to keep a copy of the depfile of each object
+	LinkMap   bool // Whether to write a link map next to the linked output
 
 	RequiredInstructionSet string
 	DynamicLinker          string
@@ -410,6 +416,12 @@ type Module struct {
 
 	// only non-nil when this is a shared library that reuses the objects of a static library
 	staticVariant *Module
+
+	// Report of the dependencies used by the module, generated when SOONG_CC_UNUSED_DEPS is set
+	unusedDepsReport android.Path
+
+	// Size attribution of the linked module, generated when SOONG_CC_SIZE_REPORT is set
+	sizeReport android.Path
 }
 
 func (c *Module) OutputFile() android.OptionalPath {
@@ -423,6 +435,15 @@ func (c *Module) UnstrippedOutputFile() android.Path {
 	return nil
 }
 
+// linkMap returns the link map written when the unstripped output file was linked, which is not
+// valid for prebuilts or when flags.LinkMap was not set.
+func (c *Module) linkMap() android.OptionalPath {
+	if l, ok := c.linker.(interface{ linkMapPath() android.OptionalPath }); ok {
+		return l.linkMapPath()
+	}
+	return android.OptionalPath{}
+}
+
 func (c *Module) RelativeInstallPath() string {
 	if c.installer != nil {
 		return c.installer.relativeInstallPath()
@@ -603,6 +624,10 @@ func (c *Module) HasStubsVariants() bool {
 	return false
 }
 
+func (c *Module) SdkVersion() string {
+	return String(c.Properties.Sdk_version)
+}
+
 func (c *Module) bootstrap() bool {
 	return Bool(c.Properties.Bootstrap)
 }
@@ -965,6 +990,14 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 	flags.CppFlags, _ = filterList(flags.CppFlags, config.IllegalFlags)
 	flags.ConlyFlags, _ = filterList(flags.ConlyFlags, config.IllegalFlags)
 
+	if unusedDepsEnabled(ctx) {
+		flags.SaveDeps = true
+		flags.LinkMap = true
+	}
+	if sizeReportEnabled(ctx) {
+		flags.LinkMap = true
+	}
+
 	flags.GlobalFlags = append(flags.GlobalFlags, deps.Flags...)
 	c.flags = flags
 	// We need access to all the flags seen by a source file.
@@ -995,6 +1028,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 		}
 		c.outputFile = android.OptionalPathForPath(outputFile)
 
+		if unusedDepsEnabled(ctx) {
+			c.unusedDepsBuildActions(ctx, deps.Objs.Copy().Append(objs))
+		}
+
 		// If a lib is directly included in any of the APEXes, unhide the stubs
 		// variant having the latest version gets visible to make. In addition,
 		// the non-stubs variant is renamed to <libname>.bootstrap. This is to
@@ -1016,6 +1053,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 			return
 		}
 	}
+
+	if sizeReportEnabled(ctx) {
+		c.sizeReportBuildActions(ctx)
+	}
 }
 
 func (c *Module) toolchain(ctx android.BaseContext) config.Toolchain {
@@ -1906,6 +1947,22 @@ func (c *Module) HostToolPath() android.OptionalPath {
 	return c.installer.hostToolPath()
 }
 
+func (c *Module) TestModuleInfo() *android.TestModuleInfo {
+	switch test := c.linker.(type) {
+	case *testBinary:
+		return &android.TestModuleInfo{
+			TestSuites: test.Properties.Test_suites,
+			TestConfig: test.testConfig,
+		}
+	case *benchmarkDecorator:
+		return &android.TestModuleInfo{
+			TestSuites: test.Properties.Test_suites,
+			TestConfig: test.testConfig,
+		}
+	}
+	return nil
+}
+
 func (c *Module) IntermPathForModuleOut() android.OptionalPath {
 	return c.outputFile
 }
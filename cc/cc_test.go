@@ -2165,3 +2165,88 @@ func TestStaticDepsOrderWithStubs(t *testing.T) {
 		)
 	}
 }
+
+func TestLayeringCheck(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c", "bar.s"],
+			local_include_dirs: ["private"],
+			export_include_dirs: ["include"],
+			shared_libs: ["libbar"],
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libbar",
+			export_include_dirs: ["bar_include"],
+			shared_libs: ["libbaz"],
+			export_shared_lib_headers: ["libbaz"],
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libbaz",
+			export_include_dirs: ["baz_include"],
+			stl: "none",
+		}`
+
+	ctx := testCc(t, bp)
+	foo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_static")
+	if rule := foo.Output("obj/foo.o").Rule; rule != cc {
+		t.Errorf("expected foo.o to be built with cc without CC_LAYERING_CHECK, got %v", rule)
+	}
+
+	config := android.TestArchConfig(buildDir, map[string]string{"CC_LAYERING_CHECK": "warn"})
+	ctx = testCcWithConfig(t, bp, config)
+	foo = ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_static")
+	obj := foo.Output("obj/foo.o")
+	if obj.Rule != ccPostDeps {
+		t.Errorf("expected foo.o to be built with ccPostDeps, got %v", obj.Rule)
+	}
+	postDeps := obj.Args["postDeps"]
+	for _, w := range []string{
+		"header_layering_check -warn -module libfoo ",
+		" -allow private ",
+		" -allow include ",
+		" -allow bar_include ",
+		// Directories re-exported by a direct dependency are allowed too.
+		" -allow baz_include ",
+		" " + obj.Output.String() + ".d",
+	} {
+		if !strings.Contains(postDeps, w) {
+			t.Errorf("expected postDeps to contain %q, got %q", w, postDeps)
+		}
+	}
+	if rule := foo.Output("obj/bar.o").Rule; rule != ccNoDeps {
+		t.Errorf("expected bar.o to be built with ccNoDeps, got %v", rule)
+	}
+}
+
+func TestLinuxArm64Host(t *testing.T) {
+	bp := `
+		cc_binary_host {
+			name: "foo",
+			srcs: ["foo.c"],
+			stl: "none",
+			target: {
+				linux_glibc_arm64: {
+					cflags: ["-DARM64_HOST"],
+				},
+			},
+		}`
+
+	config := android.TestArchConfig(buildDir, nil)
+	config.Targets[android.BuildOs] = []android.Target{
+		{android.BuildOs, android.Arch{ArchType: android.Arm64}},
+	}
+	ctx := testCcWithConfigForOs(t, bp, config, android.BuildOs)
+
+	foo := ctx.ModuleForTests("foo", "linux_glibc_arm64")
+	cFlags := foo.Rule("cc").Args["cFlags"]
+	for _, w := range []string{"-target aarch64-linux-gnu", "-DARM64_HOST"} {
+		if !strings.Contains(cFlags, w) {
+			t.Errorf("expected cflags to contain %q, got %q", w, cFlags)
+		}
+	}
+}
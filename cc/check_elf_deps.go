@@ -0,0 +1,235 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// This singleton verifies the dynamic linking of every installed binary and shared library of the
+// device, so that broken dependencies across the system and vendor linker namespaces are found at
+// build time instead of at boot.  Each installed ELF file is described by a JSON file listing the
+// linker namespaces it is installed in and the namespaces its DT_NEEDED entries are loaded from,
+// and check_elf_deps verifies that the DT_SONAME of shared libraries matches their installed name,
+// that every DT_NEEDED entry resolves to a library installed in one of those namespaces, and that
+// the undefined symbols are defined by the loaded libraries.  Prebuilts with check_elf_files: false
+// are only used to resolve the dependencies of the other files.
+//
+// Shared libraries that are not installed by Soong modules can also be loaded.  The variants of the
+// libraries with stubs that are built for an APEX are visible to the system namespace, and the
+// libraries installed by Make are listed in the CheckElfDepsExternalLibraries product variable as
+// <namespace>:<file name>.  The external libraries can't be read, so the undefined symbols of the
+// files that load them are not checked.  The check is built with the check-elf-deps goal.
+
+func init() {
+	android.RegisterSingletonType("check_elf_deps", checkElfDepsSingletonFactory)
+
+	pctx.HostBinToolVariable("checkElfDepsCmd", "check_elf_deps")
+}
+
+var checkElfDeps = pctx.AndroidStaticRule("checkElfDeps",
+	blueprint.RuleParams{
+		Command:        "$checkElfDepsCmd -o $out @$out.rsp",
+		CommandDeps:    []string{"$checkElfDepsCmd"},
+		Rspfile:        "$out.rsp",
+		RspfileContent: "$in",
+	})
+
+// checkElfDepsEntry is the description of an installed ELF file read by check_elf_deps.
+type checkElfDepsEntry struct {
+	Module    string
+	Variant   string
+	Blueprint string
+
+	// File is empty for the libraries installed outside of Soong.
+	File    string
+	Install string
+
+	Arch string
+
+	Namespaces []string
+	Search     []string
+
+	SharedLibrary         bool
+	Check                 bool
+	AllowUndefinedSymbols bool
+}
+
+// checkElfDepsNamespaces returns the linker namespaces that the installed file of a module is
+// loaded from, and the namespaces that its dependencies are loaded from.
+func checkElfDepsNamespaces(ctx android.SingletonContext, m *Module) (namespaces, search []string) {
+	switch {
+	case m.InstallInRecovery():
+		return []string{"recovery"}, []string{"recovery"}
+	case m.useVndk() && m.isVndk() && !m.isVndkExt():
+		return []string{"vndk"}, []string{"vndk", "llndk"}
+	case m.useVndk():
+		return []string{"vendor"}, []string{"vendor", "vndk", "llndk"}
+	case m.ProductSpecific() || m.ProductServicesSpecific():
+		return []string{"product"}, []string{"product", "system"}
+	}
+
+	namespaces = []string{"system"}
+	if m.isLlndk() {
+		namespaces = append(namespaces, "llndk")
+	}
+	// The vendor variants of VNDK libraries are not installed when the core variants are used
+	// instead.
+	if m.isVndk() && ctx.DeviceConfig().VndkUseCoreVariant() && !m.mustUseVendorVariant() {
+		namespaces = append(namespaces, "vndk")
+	}
+	return namespaces, []string{"system"}
+}
+
+func checkElfDepsSingletonFactory() android.Singleton {
+	return &checkElfDepsSingleton{}
+}
+
+type checkElfDepsSingleton struct {
+	stamp android.Path
+}
+
+func (s *checkElfDepsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var entries, elfs android.Paths
+	writeEntry := func(entry checkElfDepsEntry, out android.WritablePath) {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			ctx.Errorf("failed to describe %q for check-elf-deps: %s", entry.Module, err.Error())
+			return
+		}
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.WriteFile,
+			Description: "check-elf-deps " + entry.Module,
+			Output:      out,
+			Args: map[string]string{
+				"content": string(data),
+			},
+		})
+		entries = append(entries, out)
+	}
+
+	var arches []string
+	ctx.VisitAllModules(func(module android.Module) {
+		m, ok := module.(*Module)
+		if !ok || !m.Enabled() || m.Os() != android.Android || !m.outputFile.Valid() ||
+			m.static() || m.InstallInSanitizerDir() {
+			return
+		}
+		arch := m.Target().Arch.ArchType.String()
+		if !android.InList(arch, arches) {
+			arches = append(arches, arch)
+		}
+
+		entry := checkElfDepsEntry{
+			Module:    ctx.ModuleName(module),
+			Variant:   ctx.ModuleSubDir(module),
+			Blueprint: ctx.BlueprintFile(module),
+			File:      m.outputFile.String(),
+			Arch:      arch,
+			Check:     true,
+		}
+
+		if !m.IsForPlatform() {
+			// The libraries in an APEX are checked when the APEX is built, but the ones with
+			// stubs are loaded by the files of the system namespace.
+			if !m.HasStubsVariants() || m.IsStubs() {
+				return
+			}
+			libDir := "lib"
+			if m.Target().Arch.ArchType.Multilib == "lib64" {
+				libDir = "lib64"
+			}
+			entry.Install = filepath.Join("/apex", m.ApexName(), libDir, m.outputFile.Path().Base())
+			entry.Namespaces = []string{"system"}
+			entry.SharedLibrary = true
+			entry.Check = false
+		} else {
+			installer, ok := m.installer.(interface{ installedFile() android.OptionalPath })
+			if !ok {
+				return
+			}
+			installed := installer.installedFile()
+			if !installed.Valid() {
+				return
+			}
+			entry.Install = installed.String()
+			entry.Namespaces, entry.Search = checkElfDepsNamespaces(ctx, m)
+			_, entry.SharedLibrary = m.linker.(libraryInterface)
+			if prebuilt, ok := m.linker.(interface{ checkElfFiles() bool }); ok {
+				entry.Check = prebuilt.checkElfFiles()
+			}
+			for _, p := range m.linker.linkerProps() {
+				if p, ok := p.(*BaseLinkerProperties); ok {
+					entry.AllowUndefinedSymbols = Bool(p.Allow_undefined_symbols)
+				}
+			}
+		}
+
+		writeEntry(entry, android.PathForOutput(ctx, "check_elf_deps", ctx.ModuleDir(module), entry.Module,
+			entry.Variant+".json"))
+		elfs = append(elfs, m.outputFile.Path())
+	})
+
+	if len(entries) == 0 {
+		return
+	}
+
+	for _, lib := range ctx.DeviceConfig().CheckElfDepsExternalLibraries() {
+		parts := strings.Split(lib, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			ctx.Errorf("malformed CheckElfDepsExternalLibraries entry %q, expected <namespace>:<file name>", lib)
+			continue
+		}
+		for _, arch := range arches {
+			writeEntry(checkElfDepsEntry{
+				Module:        parts[1],
+				Variant:       "external",
+				Install:       parts[1],
+				Arch:          arch,
+				Namespaces:    []string{parts[0]},
+				SharedLibrary: true,
+			}, android.PathForOutput(ctx, "check_elf_deps", "external", parts[0], arch, parts[1]+".json"))
+		}
+	}
+
+	stamp := android.PathForOutput(ctx, "check_elf_deps.stamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        checkElfDeps,
+		Description: "check elf deps",
+		Inputs:      entries,
+		Implicits:   elfs,
+		Output:      stamp,
+	})
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Phony,
+		Output:      android.PathForPhony(ctx, "check-elf-deps"),
+		Input:       stamp,
+		Description: "check elf deps",
+	})
+	s.stamp = stamp
+}
+
+func (s *checkElfDepsSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.stamp != nil {
+		ctx.Strict("SOONG_CHECK_ELF_DEPS_STAMP", s.stamp.String())
+	}
+}
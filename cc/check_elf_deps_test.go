@@ -0,0 +1,127 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestCheckElfDeps(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	config.TestProductVariables.CheckElfDepsExternalLibraries = []string{"system:libmake.so"}
+
+	ctx := createTestContext(t, config, `
+		cc_binary {
+			name: "bin",
+			srcs: ["foo.c"],
+			shared_libs: ["libvndk"],
+			nocrt: true,
+		}
+
+		cc_library {
+			name: "libvndk",
+			vendor_available: true,
+			vndk: {
+				enabled: true,
+			},
+			nocrt: true,
+		}
+
+		cc_library_shared {
+			name: "libvendor",
+			vendor: true,
+			shared_libs: ["libvndk"],
+			allow_undefined_symbols: true,
+			nocrt: true,
+		}
+
+		cc_prebuilt_library_shared {
+			name: "libprebuilt",
+			srcs: ["libprebuilt.so"],
+			vendor: true,
+			check_elf_files: false,
+		}
+	`, map[string][]byte{
+		"libprebuilt.so": nil,
+	}, android.Android)
+	ctx.RegisterModuleType("cc_prebuilt_library_shared", android.ModuleFactoryAdaptor(prebuiltSharedLibraryFactory))
+	ctx.PreArchMutators(android.RegisterPrebuiltsPreArchMutators)
+	ctx.PostDepsMutators(android.RegisterPrebuiltsPostDepsMutators)
+	ctx.RegisterSingletonType("check_elf_deps", android.SingletonFactoryAdaptor(checkElfDepsSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	singleton := ctx.SingletonForTests("check_elf_deps")
+	entry := func(module, variant string) checkElfDepsEntry {
+		t.Helper()
+		out := singleton.Output("check_elf_deps/" + module + "/" + variant + ".json")
+		var e checkElfDepsEntry
+		if err := json.Unmarshal([]byte(out.Args["content"]), &e); err != nil {
+			t.Fatalf("failed to parse the entry of %s: %s", module, err)
+		}
+		return e
+	}
+
+	check := func(module, variant string, namespaces, search []string, shared, checked, allowUndefined bool) {
+		t.Helper()
+		e := entry(module, variant)
+		if !reflect.DeepEqual(e.Namespaces, namespaces) || !reflect.DeepEqual(e.Search, search) {
+			t.Errorf("%s: expected namespaces %q and search %q, got %q and %q",
+				module, namespaces, search, e.Namespaces, e.Search)
+		}
+		if e.SharedLibrary != shared || e.Check != checked || e.AllowUndefinedSymbols != allowUndefined {
+			t.Errorf("%s: expected shared %t, check %t and allow undefined %t, got %t, %t and %t",
+				module, shared, checked, allowUndefined, e.SharedLibrary, e.Check, e.AllowUndefinedSymbols)
+		}
+	}
+
+	check("bin", "android_arm64_armv8-a_core", []string{"system"}, []string{"system"}, false, true, false)
+	check("libvndk", coreVariant, []string{"system"}, []string{"system"}, true, true, false)
+	check("libvndk", vendorVariant, []string{"vndk"}, []string{"vndk", "llndk"}, true, true, false)
+	check("libvendor", vendorVariant, []string{"vendor"}, []string{"vendor", "vndk", "llndk"}, true, true, true)
+	check("prebuilt_libprebuilt", vendorVariant, []string{"vendor"}, []string{"vendor", "vndk", "llndk"}, true, false, false)
+
+	if e := entry("libvndk", vendorVariant); !strings.Contains(e.Install, "vndk-VER/libvndk.so") {
+		t.Errorf("expected libvndk to be installed in vndk-VER, got %q", e.Install)
+	}
+
+	for _, arch := range []string{"arm64", "arm"} {
+		out := singleton.Output("check_elf_deps/external/system/" + arch + "/libmake.so.json")
+		var e checkElfDepsEntry
+		if err := json.Unmarshal([]byte(out.Args["content"]), &e); err != nil {
+			t.Fatalf("failed to parse the entry of libmake.so: %s", err)
+		}
+		if e.File != "" || e.Check || !e.SharedLibrary || !reflect.DeepEqual(e.Namespaces, []string{"system"}) {
+			t.Errorf("expected an unchecked external provider in system for %s, got %+v", arch, e)
+		}
+	}
+
+	stamp := singleton.Output("check_elf_deps.stamp")
+	bin := ctx.ModuleForTests("bin", "android_arm64_armv8-a_core").Module().(*Module)
+	if !android.InList(bin.outputFile.String(), stamp.Implicits.Strings()) {
+		t.Errorf("expected the check to depend on %q, got %q", bin.outputFile, stamp.Implicits)
+	}
+}
@@ -517,6 +517,32 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 		flags.CFlags = append(flags.CFlags, "-fopenmp")
 	}
 
+	// Check that every header in the depfile of each object comes from the module's own directories,
+	// its own include directories, the include directories exported or re-exported by its direct
+	// dependencies, or the global and toolchain include directories.  CC_LAYERING_CHECK=true fails
+	// the compile on a violation, CC_LAYERING_CHECK=warn only prints it.
+	switch layering := ctx.Config().Getenv("CC_LAYERING_CHECK"); layering {
+	case "true", "warn":
+		if layering == "warn" {
+			flags.LayeringFlags = append(flags.LayeringFlags, "-warn")
+		}
+		flags.LayeringFlags = append(flags.LayeringFlags, "-module", ctx.ModuleName())
+
+		allowed := []string{android.PathForModuleSrc(ctx).String(), android.PathForModuleOut(ctx).String()}
+		allowed = append(allowed, includeDirsFromFlags(flags.GlobalFlags)...)
+		allowed = append(allowed, includeDirsFromFlags(deps.Flags)...)
+		for _, dir := range android.FirstUniqueStrings(allowed) {
+			flags.LayeringFlags = append(flags.LayeringFlags, "-allow", dir)
+		}
+
+		// The global and toolchain include directories are only known as ninja variables here, so
+		// pass the flags for the tool to find the directories in.
+		systemFlags := append([]string{"-isystem ${config.ClangPath}"}, flags.SystemIncludeFlags...)
+		systemFlags = append(systemFlags, tc.ClangCflags())
+		flags.LayeringFlags = append(flags.LayeringFlags,
+			"-system '"+strings.Join(systemFlags, " ")+"'")
+	}
+
 	return flags
 }
 
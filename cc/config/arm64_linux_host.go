@@ -0,0 +1,99 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	linuxArm64ClangCflags = append(ClangFilterUnknownCflags(linuxCflags), []string{
+		"--gcc-toolchain=${LinuxArm64GccRoot}",
+		"--sysroot ${LinuxArm64GccRoot}/sysroot",
+		"-fstack-protector-strong",
+	}...)
+
+	linuxArm64ClangLdflags = append(ClangFilterUnknownCflags(linuxLdflags), []string{
+		"--gcc-toolchain=${LinuxArm64GccRoot}",
+		"--sysroot ${LinuxArm64GccRoot}/sysroot",
+		"-B${LinuxArm64GccRoot}/lib/gcc/${LinuxArm64GccTriple}/${LinuxGccVersion}",
+		"-L${LinuxArm64GccRoot}/lib/gcc/${LinuxArm64GccTriple}/${LinuxGccVersion}",
+		"-L${LinuxArm64GccRoot}/${LinuxArm64GccTriple}/lib64",
+	}...)
+
+	linuxArm64ClangLldflags = ClangFilterUnknownLldflags(linuxArm64ClangLdflags)
+)
+
+func init() {
+	// The sysroot is selected by the prebuilts tag of the machine running the build, so an x86_64
+	// machine uses a cross toolchain to produce arm64 host binaries.
+	pctx.SourcePathVariable("LinuxArm64GccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/host/aarch64-linux-glibc${LinuxGlibcVersion}-${ShortLinuxGccVersion}")
+
+	pctx.StaticVariable("LinuxArm64GccTriple", "aarch64-linux")
+
+	pctx.StaticVariable("LinuxArm64ClangCflags", strings.Join(linuxArm64ClangCflags, " "))
+	pctx.StaticVariable("LinuxArm64ClangLdflags", strings.Join(linuxArm64ClangLdflags, " "))
+	pctx.StaticVariable("LinuxArm64ClangLldflags", strings.Join(linuxArm64ClangLldflags, " "))
+}
+
+type toolchainLinuxArm64 struct {
+	toolchain64Bit
+	toolchainLinux
+}
+
+func (t *toolchainLinuxArm64) Name() string {
+	return "arm64"
+}
+
+func (t *toolchainLinuxArm64) GccRoot() string {
+	return "${config.LinuxArm64GccRoot}"
+}
+
+func (t *toolchainLinuxArm64) GccTriple() string {
+	return "${config.LinuxArm64GccTriple}"
+}
+
+func (t *toolchainLinuxArm64) ClangTriple() string {
+	return "aarch64-linux-gnu"
+}
+
+func (t *toolchainLinuxArm64) ClangCflags() string {
+	return "${config.LinuxArm64ClangCflags}"
+}
+
+func (t *toolchainLinuxArm64) ClangCppflags() string {
+	return ""
+}
+
+func (t *toolchainLinuxArm64) ClangLdflags() string {
+	return "${config.LinuxArm64ClangLdflags}"
+}
+
+func (t *toolchainLinuxArm64) ClangLldflags() string {
+	return "${config.LinuxArm64ClangLldflags}"
+}
+
+var toolchainLinuxArm64Singleton Toolchain = &toolchainLinuxArm64{}
+
+func linuxArm64ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxArm64Singleton
+}
+
+func init() {
+	registerToolchainFactory(android.Linux, android.Arm64, linuxArm64ToolchainFactory)
+}
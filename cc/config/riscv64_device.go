@@ -0,0 +1,145 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	riscv64Cflags = []string{
+		// Help catch common 32/64-bit errors.
+		"-Werror=implicit-function-declaration",
+	}
+
+	riscv64ArchVariantCflags = map[string][]string{
+		"": []string{
+			"-march=rv64gc",
+			"-mabi=lp64d",
+		},
+	}
+
+	riscv64Ldflags = []string{
+		"-Wl,--hash-style=gnu",
+		// There is no gold linker for riscv64, always use lld.
+		"-fuse-ld=lld",
+	}
+
+	riscv64Lldflags = append(ClangFilterUnknownLldflags(riscv64Ldflags),
+		"-Wl,-z,max-page-size=4096")
+
+	riscv64Cppflags = []string{}
+)
+
+const (
+	riscv64GccVersion = "4.9"
+)
+
+func init() {
+	pctx.StaticVariable("riscv64GccVersion", riscv64GccVersion)
+
+	pctx.SourcePathVariable("Riscv64GccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/riscv64/riscv64-linux-android-${riscv64GccVersion}")
+
+	pctx.StaticVariable("Riscv64Ldflags", strings.Join(riscv64Ldflags, " "))
+	pctx.StaticVariable("Riscv64Lldflags", strings.Join(riscv64Lldflags, " "))
+	pctx.StaticVariable("Riscv64IncludeFlags", bionicHeaders("riscv"))
+
+	pctx.StaticVariable("Riscv64ClangCflags", strings.Join(ClangFilterUnknownCflags(riscv64Cflags), " "))
+	pctx.StaticVariable("Riscv64ClangLdflags", strings.Join(ClangFilterUnknownCflags(riscv64Ldflags), " "))
+	pctx.StaticVariable("Riscv64ClangLldflags", strings.Join(ClangFilterUnknownCflags(riscv64Lldflags), " "))
+	pctx.StaticVariable("Riscv64ClangCppflags", strings.Join(ClangFilterUnknownCflags(riscv64Cppflags), " "))
+
+	// Architecture variant cflags
+	for variant, cflags := range riscv64ArchVariantCflags {
+		pctx.StaticVariable("Riscv64"+variant+"VariantClangCflags",
+			strings.Join(ClangFilterUnknownCflags(cflags), " "))
+	}
+}
+
+type toolchainRiscv64 struct {
+	toolchain64Bit
+
+	toolchainClangCflags string
+}
+
+func (t *toolchainRiscv64) Name() string {
+	return "riscv64"
+}
+
+func (t *toolchainRiscv64) GccRoot() string {
+	return "${config.Riscv64GccRoot}"
+}
+
+func (t *toolchainRiscv64) GccTriple() string {
+	return "riscv64-linux-android"
+}
+
+func (t *toolchainRiscv64) GccVersion() string {
+	return riscv64GccVersion
+}
+
+func (t *toolchainRiscv64) IncludeFlags() string {
+	return "${config.Riscv64IncludeFlags}"
+}
+
+func (t *toolchainRiscv64) ClangTriple() string {
+	return t.GccTriple()
+}
+
+func (t *toolchainRiscv64) ClangCflags() string {
+	return "${config.Riscv64ClangCflags}"
+}
+
+func (t *toolchainRiscv64) ClangCppflags() string {
+	return "${config.Riscv64ClangCppflags}"
+}
+
+func (t *toolchainRiscv64) ClangLdflags() string {
+	return "${config.Riscv64Ldflags}"
+}
+
+func (t *toolchainRiscv64) ClangLldflags() string {
+	return "${config.Riscv64Lldflags}"
+}
+
+func (t *toolchainRiscv64) ToolchainClangCflags() string {
+	return t.toolchainClangCflags
+}
+
+func (toolchainRiscv64) LibclangRuntimeLibraryArch() string {
+	return "riscv64"
+}
+
+func riscv64ToolchainFactory(arch android.Arch) Toolchain {
+	if _, ok := riscv64ArchVariantCflags[arch.ArchVariant]; !ok {
+		panic(fmt.Sprintf("Unknown RISC-V architecture version: %q", arch.ArchVariant))
+	}
+
+	toolchainClangCflags := []string{
+		"${config.Riscv64" + arch.ArchVariant + "VariantClangCflags}",
+	}
+
+	return &toolchainRiscv64{
+		toolchainClangCflags: strings.Join(toolchainClangCflags, " "),
+	}
+}
+
+func init() {
+	registerToolchainFactory(android.Android, android.Riscv64, riscv64ToolchainFactory)
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestRiscv64Toolchain(t *testing.T) {
+	tc := FindToolchain(android.Android, android.Arch{ArchType: android.Riscv64})
+
+	if g, w := tc.Name(), "riscv64"; g != w {
+		t.Errorf("want name %q, got %q", w, g)
+	}
+	if g, w := tc.ClangTriple(), "riscv64-linux-android"; g != w {
+		t.Errorf("want clang triple %q, got %q", w, g)
+	}
+	if !tc.Is64Bit() {
+		t.Errorf("expected riscv64 toolchain to be 64-bit")
+	}
+	if g, w := BuiltinsRuntimeLibrary(tc), "libclang_rt.builtins-riscv64-android"; g != w {
+		t.Errorf("want builtins runtime library %q, got %q", w, g)
+	}
+}
+
+func TestLinuxArm64Toolchain(t *testing.T) {
+	tc := FindToolchain(android.Linux, android.Arch{ArchType: android.Arm64})
+
+	if g, w := tc.ClangTriple(), "aarch64-linux-gnu"; g != w {
+		t.Errorf("want clang triple %q, got %q", w, g)
+	}
+	if tc.Bionic() {
+		t.Errorf("expected linux_glibc arm64 toolchain not to use bionic")
+	}
+	if g, w := tc.GccRoot(), "${config.LinuxArm64GccRoot}"; g != w {
+		t.Errorf("want gcc root %q, got %q", w, g)
+	}
+}
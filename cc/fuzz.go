@@ -0,0 +1,365 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/cc/config"
+)
+
+type FuzzProperties struct {
+	// list of files or filegroup modules that provide the seed corpus, installed into the
+	// corpus directory next to the fuzz target.
+	Corpus []string `android:"path"`
+
+	// dictionary of tokens used by the fuzzer to mutate its inputs, installed next to the fuzz
+	// target as <module name>.dict.
+	Dictionary *string `android:"path"`
+
+	// libFuzzer options (for example "max_len=1024"), written to <module name>.options next to
+	// the fuzz target.
+	Options []string
+}
+
+func init() {
+	android.RegisterModuleType("cc_fuzz", FuzzFactory)
+	android.RegisterSingletonType("cc_fuzz_packaging", fuzzPackagingFactory)
+}
+
+// cc_fuzz creates a libFuzzer fuzz target.  The target and all of its static and shared library
+// dependencies are built in the fuzzer sanitizer variant, and the target is linked against
+// libFuzzer, so it only needs to define LLVMFuzzerTestOneInput.  The target is installed into
+// data/fuzz/<arch>/<module name> (fuzz/<arch>/<module name> for host targets) together with its
+// corpus, dictionary, options and instrumented shared libraries.
+func FuzzFactory() android.Module {
+	module := NewFuzz(android.HostAndDeviceSupported)
+	return module.Init()
+}
+
+func NewFuzzInstaller() *baseInstaller {
+	return NewBaseInstaller("fuzz", "fuzz", InstallInData)
+}
+
+type fuzzBinary struct {
+	*binaryDecorator
+	*baseCompiler
+
+	Properties FuzzProperties
+
+	binary     android.Path
+	corpus     android.Paths
+	dictionary android.Path
+	options    android.Path
+
+	// copies of the corpus and the instrumented shared libraries in packageDir, laid out the same
+	// way as in the install directory.
+	packageDir      android.Path
+	installedCorpus android.Paths
+	sharedLibs      android.Paths
+}
+
+func (fuzz *fuzzBinary) linkerProps() []interface{} {
+	props := fuzz.binaryDecorator.linkerProps()
+	props = append(props, &fuzz.Properties)
+	return props
+}
+
+func (fuzz *fuzzBinary) linkerInit(ctx BaseModuleContext) {
+	// Add ../../../lib[64] to rpath so that out/host/linux-x86/fuzz/<arch>/<fuzzer>/<fuzzer> can
+	// find out/host/linux-x86/lib[64]/library.so
+	runpath := "../../../lib"
+	if ctx.toolchain().Is64Bit() {
+		runpath += "64"
+	}
+	fuzz.binaryDecorator.baseLinker.dynamicProperties.RunPaths = append(
+		fuzz.binaryDecorator.baseLinker.dynamicProperties.RunPaths, runpath)
+
+	// add "lib" to rpath so that fuzz targets find the instrumented libraries installed with them
+	fuzz.binaryDecorator.baseLinker.dynamicProperties.RunPaths = append(
+		fuzz.binaryDecorator.baseLinker.dynamicProperties.RunPaths, "lib")
+
+	fuzz.binaryDecorator.linkerInit(ctx)
+}
+
+func (fuzz *fuzzBinary) linkerDeps(ctx DepsContext, deps Deps) Deps {
+	if lib := config.LibFuzzerRuntimeLibrary(ctx.toolchain()); lib != "" {
+		deps.StaticLibs = append(deps.StaticLibs, lib)
+	}
+	deps = fuzz.binaryDecorator.linkerDeps(ctx, deps)
+	return deps
+}
+
+func (fuzz *fuzzBinary) compilerFlags(ctx ModuleContext, flags Flags, deps PathDeps) Flags {
+	flags = fuzz.baseCompiler.compilerFlags(ctx, flags, deps)
+	flags.CFlags = append(flags.CFlags, "-fsanitize=fuzzer")
+	return flags
+}
+
+func (fuzz *fuzzBinary) linkerFlags(ctx ModuleContext, flags Flags) Flags {
+	flags = fuzz.binaryDecorator.linkerFlags(ctx, flags)
+	if config.LibFuzzerRuntimeLibrary(ctx.toolchain()) == "" {
+		// There is no libFuzzer module for the host, let the driver link the one that is
+		// shipped with clang.
+		flags.LdFlags = append(flags.LdFlags, "-fsanitize=fuzzer")
+	}
+	return flags
+}
+
+func (fuzz *fuzzBinary) install(ctx ModuleContext, file android.Path) {
+	fuzz.binary = file
+	fuzz.packageDir = android.PathForModuleOut(ctx, "fuzz")
+
+	fuzz.corpus = android.PathsForModuleSrc(ctx, fuzz.Properties.Corpus)
+	for _, entry := range fuzz.corpus {
+		out := android.PathForModuleOut(ctx, "fuzz", "corpus", entry.Base())
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.Cp,
+			Description: "copy corpus " + entry.Base(),
+			Input:       entry,
+			Output:      out,
+		})
+		fuzz.installedCorpus = append(fuzz.installedCorpus, out)
+	}
+
+	if fuzz.Properties.Dictionary != nil {
+		dictionary := android.PathForModuleSrc(ctx, *fuzz.Properties.Dictionary)
+		if dictionary.Ext() != ".dict" {
+			ctx.PropertyErrorf("dictionary", "fuzzer dictionary %q does not have the .dict extension",
+				dictionary.String())
+		}
+		out := android.PathForModuleOut(ctx, "fuzz", ctx.ModuleName()+".dict")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.Cp,
+			Description: "copy dictionary",
+			Input:       dictionary,
+			Output:      out,
+		})
+		fuzz.dictionary = out
+	}
+
+	if len(fuzz.Properties.Options) > 0 {
+		var lines []string
+		for _, option := range fuzz.Properties.Options {
+			kv := strings.SplitN(option, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				ctx.PropertyErrorf("options", "expected <name>=<value>, got %q", option)
+				continue
+			}
+			lines = append(lines, kv[0]+" = "+kv[1])
+		}
+		out := android.PathForModuleOut(ctx, "fuzz", ctx.ModuleName()+".options")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.WriteFile,
+			Description: "fuzzer options",
+			Output:      out,
+			Args: map[string]string{
+				"content": "[libfuzzer]\\n" + strings.Join(lines, "\\n"),
+			},
+		})
+		fuzz.options = out
+	}
+
+	// The fuzzer variants of shared libraries are not installed anywhere else, copy them next to
+	// the fuzz target.
+	ctx.WalkDeps(func(child, parent android.Module) bool {
+		switch ctx.OtherModuleDependencyTag(child) {
+		case sharedDepTag, sharedExportDepTag, lateSharedDepTag, earlySharedDepTag:
+		default:
+			return false
+		}
+		c, ok := child.(*Module)
+		if !ok || !c.sanitize.isSanitizerEnabled(fuzzer) || !c.OutputFile().Valid() {
+			return false
+		}
+		lib := c.OutputFile().Path()
+		out := android.PathForModuleOut(ctx, "fuzz", "lib", lib.Base())
+		if !android.InList(out.String(), fuzz.sharedLibs.Strings()) {
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        android.Cp,
+				Description: "copy " + lib.Base(),
+				Input:       lib,
+				Output:      out,
+			})
+			fuzz.sharedLibs = append(fuzz.sharedLibs, out)
+		}
+		return true
+	})
+
+	// The primary arch is the only one built for cc_fuzz, so the arch is added to the install
+	// directory explicitly to keep the targets of different products apart.
+	archDir := filepath.Join("fuzz", ctx.Arch().ArchType.String())
+	fuzz.binaryDecorator.baseInstaller.dir = archDir
+	fuzz.binaryDecorator.baseInstaller.dir64 = archDir
+	fuzz.binaryDecorator.baseInstaller.relative = ctx.ModuleName()
+	fuzz.binaryDecorator.baseInstaller.install(ctx, file)
+}
+
+// packagedFiles returns the files that are installed with the fuzz target.
+func (fuzz *fuzzBinary) packagedFiles() android.Paths {
+	var ret android.Paths
+	ret = append(ret, fuzz.installedCorpus...)
+	if fuzz.dictionary != nil {
+		ret = append(ret, fuzz.dictionary)
+	}
+	if fuzz.options != nil {
+		ret = append(ret, fuzz.options)
+	}
+	ret = append(ret, fuzz.sharedLibs...)
+	return ret
+}
+
+func (fuzz *fuzzBinary) AndroidMk(ctx AndroidMkContext, ret *android.AndroidMkData) {
+	ctx.subAndroidMk(ret, fuzz.binaryDecorator)
+
+	var fuzzFiles []string
+	for _, f := range fuzz.packagedFiles() {
+		rel, err := filepath.Rel(fuzz.packageDir.String(), f.String())
+		if err != nil {
+			panic(err)
+		}
+		fuzzFiles = append(fuzzFiles, fuzz.packageDir.String()+":"+rel)
+	}
+	if len(fuzzFiles) > 0 {
+		ret.Extra = append(ret.Extra, func(w io.Writer, outputFile android.Path) {
+			fmt.Fprintln(w, "LOCAL_TEST_DATA := "+strings.Join(fuzzFiles, " "))
+		})
+	}
+}
+
+func NewFuzz(hod android.HostOrDeviceSupported) *Module {
+	module, binary := NewBinary(hod)
+
+	binary.baseInstaller = NewFuzzInstaller()
+	module.sanitize.SetSanitizer(fuzzer, true)
+
+	fuzz := &fuzzBinary{
+		binaryDecorator: binary,
+		baseCompiler:    NewBaseCompiler(),
+	}
+	module.compiler = fuzz
+	module.linker = fuzz
+	module.installer = fuzz
+
+	// libFuzzer is only available for Linux hosts.
+	android.AddLoadHook(module, func(ctx android.LoadHookContext) {
+		type props struct {
+			Target struct {
+				Darwin struct {
+					Enabled *bool
+				}
+				Windows struct {
+					Enabled *bool
+				}
+			}
+		}
+		p := &props{}
+		p.Target.Darwin.Enabled = BoolPtr(false)
+		p.Target.Windows.Enabled = BoolPtr(false)
+		ctx.AppendProperties(p)
+	})
+
+	return module
+}
+
+func fuzzPackagingFactory() android.Singleton {
+	return &fuzzPackager{}
+}
+
+// fuzzPackager zips up all of the fuzz targets for each os and arch so that they can be run
+// offline by fuzzing infrastructure.  Each target is placed in a directory named after the module
+// containing the target, its corpus directory, dictionary, options and shared libraries.
+type fuzzPackager struct {
+	packages android.Paths
+}
+
+func (s *fuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
+	type fuzzTarget struct {
+		name string
+		fuzz *fuzzBinary
+	}
+	targets := make(map[string][]fuzzTarget)
+
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() || c.Properties.PreventInstall {
+			return
+		}
+		fuzz, ok := c.installer.(*fuzzBinary)
+		if !ok || fuzz.binary == nil {
+			return
+		}
+		key := c.Os().Name + "-" + c.Arch().ArchType.String()
+		targets[key] = append(targets[key], fuzzTarget{ctx.ModuleName(module), fuzz})
+	})
+
+	var keys []string
+	for key := range targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		sort.Slice(targets[key], func(i, j int) bool { return targets[key][i].name < targets[key][j].name })
+
+		out := android.PathForOutput(ctx, "fuzz-"+key+".zip")
+		rule := android.NewRuleBuilder()
+		cmd := rule.Command().Tool(ctx.Config().HostToolPath(ctx, "soong_zip")).
+			FlagWithOutput("-o ", out).
+			Flag("-j")
+		for _, t := range targets[key] {
+			cmd.FlagWithArg("-P ", t.name).FlagWithInput("-f ", t.fuzz.binary)
+			if t.fuzz.dictionary != nil {
+				cmd.FlagWithInput("-f ", t.fuzz.dictionary)
+			}
+			if t.fuzz.options != nil {
+				cmd.FlagWithInput("-f ", t.fuzz.options)
+			}
+			if len(t.fuzz.installedCorpus) > 0 {
+				cmd.FlagWithArg("-P ", t.name+"/corpus")
+				for _, f := range t.fuzz.installedCorpus {
+					cmd.FlagWithInput("-f ", f)
+				}
+			}
+			if len(t.fuzz.sharedLibs) > 0 {
+				cmd.FlagWithArg("-P ", t.name+"/lib")
+				for _, f := range t.fuzz.sharedLibs {
+					cmd.FlagWithInput("-f ", f)
+				}
+			}
+		}
+		rule.Build(pctx, ctx, "fuzz_packaging_"+key, fmt.Sprintf("fuzz package %s", key))
+		s.packages = append(s.packages, out)
+	}
+
+	if len(s.packages) > 0 {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.Phony,
+			Output:      android.PathForPhony(ctx, "fuzz-packages"),
+			Inputs:      s.packages,
+			Description: "fuzz packages",
+		})
+	}
+}
+
+func (s *fuzzPackager) MakeVars(ctx android.MakeVarsContext) {
+	// Make dists the packages when building the fuzz-packages goal.
+	ctx.Strict("SOONG_FUZZ_PACKAGING_ZIPS", strings.Join(s.packages.Strings(), " "))
+}
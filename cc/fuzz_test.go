@@ -0,0 +1,121 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func testFuzz(t *testing.T, bp string) *android.TestContext {
+	t.Helper()
+	config := android.TestArchConfig(buildDir, nil)
+
+	bp += `
+		toolchain_library {
+			name: "libclang_rt.fuzzer-aarch64-android",
+			vendor_available: true,
+			recovery_available: true,
+			src: "",
+		}
+	`
+
+	ctx := createTestContext(t, config, bp, map[string][]byte{
+		"corpus/seed1": nil,
+		"corpus/seed2": nil,
+		"fuzz.dict":    nil,
+	}, android.Android)
+	ctx.RegisterModuleType("cc_fuzz", android.ModuleFactoryAdaptor(FuzzFactory))
+	ctx.PostDepsMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.TopDown("fuzzer_deps", sanitizerDepsMutator(fuzzer))
+		ctx.BottomUp("fuzzer", sanitizerMutator(fuzzer)).Parallel()
+		ctx.BottomUp("sanitize_runtime", sanitizerRuntimeMutator).Parallel()
+	})
+	ctx.RegisterSingletonType("cc_fuzz_packaging", android.SingletonFactoryAdaptor(fuzzPackagingFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	return ctx
+}
+
+func TestFuzz(t *testing.T) {
+	ctx := testFuzz(t, `
+		cc_fuzz {
+			name: "fuzz_foo",
+			srcs: ["foo.c"],
+			shared_libs: ["libfoo"],
+			corpus: ["corpus/*"],
+			dictionary: "fuzz.dict",
+			options: ["max_len=1024"],
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["bar.c"],
+		}
+	`)
+
+	variant := "android_arm64_armv8-a_core_fuzzer"
+	fuzz := ctx.ModuleForTests("fuzz_foo", variant)
+
+	cc := fuzz.Rule("cc")
+	if !strings.Contains(cc.Args["cFlags"], "-fsanitize=fuzzer ") {
+		t.Errorf("expected fuzz target cflags to contain -fsanitize=fuzzer, got %q", cc.Args["cFlags"])
+	}
+	if !strings.Contains(cc.Args["cFlags"], "-fsanitize=fuzzer-no-link") {
+		t.Errorf("expected fuzz target cflags to contain -fsanitize=fuzzer-no-link, got %q", cc.Args["cFlags"])
+	}
+
+	ld := fuzz.Rule("ld")
+	if !strings.Contains(ld.Args["libFlags"], "libclang_rt.fuzzer-aarch64-android") {
+		t.Errorf("expected fuzz target to link libFuzzer, got %q", ld.Args["libFlags"])
+	}
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_shared_fuzzer")
+	libfooCc := libfoo.Rule("cc")
+	if !strings.Contains(libfooCc.Args["cFlags"], "-fsanitize=fuzzer-no-link") {
+		t.Errorf("expected libfoo cflags to contain -fsanitize=fuzzer-no-link, got %q", libfooCc.Args["cFlags"])
+	}
+	if !libfoo.Module().(*Module).Properties.HideFromMake {
+		t.Errorf("expected fuzzer variant of libfoo to be hidden from make")
+	}
+
+	fuzz.Output("fuzz/corpus/seed1")
+	fuzz.Output("fuzz/corpus/seed2")
+	fuzz.Output("fuzz/fuzz_foo.dict")
+	fuzz.Output("fuzz/lib/libfoo.so")
+	options := fuzz.Output("fuzz/fuzz_foo.options")
+	if g, w := options.Args["content"], `[libfuzzer]\nmax_len = 1024`; g != w {
+		t.Errorf("want options %q, got %q", w, g)
+	}
+
+	installed := fuzz.Module().(*Module).installer.(*fuzzBinary).binaryDecorator.baseInstaller.path
+	if g, w := installed.String(), "target/product/test_device/data/fuzz/arm64/fuzz_foo/fuzz_foo"; !strings.HasSuffix(g, w) {
+		t.Errorf("want install path ending in %q, got %q", w, g)
+	}
+
+	zip := ctx.SingletonForTests("cc_fuzz_packaging").Output("fuzz-android-arm64.zip")
+	for _, w := range []string{"-P fuzz_foo ", "-P fuzz_foo/corpus ", "-P fuzz_foo/lib "} {
+		if !strings.Contains(zip.RuleParams.Command, w) {
+			t.Errorf("expected fuzz package command to contain %q, got %q", w, zip.RuleParams.Command)
+		}
+	}
+}
@@ -80,6 +80,14 @@ func (installer *baseInstaller) install(ctx ModuleContext, file android.Path) {
 	installer.path = ctx.InstallFile(installer.installDir(ctx), file.Base(), file)
 }
 
+// installedFile returns the path the output file was installed to, if it was installed.
+func (installer *baseInstaller) installedFile() android.OptionalPath {
+	if installer.path.Rel() == "" {
+		return android.OptionalPath{}
+	}
+	return android.OptionalPathForPath(installer.path)
+}
+
 func (installer *baseInstaller) inData() bool {
 	return installer.location == InstallInData
 }
@@ -126,12 +126,13 @@ func normalizeNdkApiLevel(ctx android.BaseContext, apiLevel string,
 
 	minVersion := ctx.Config().MinSupportedSdkVersion()
 	firstArchVersions := map[android.ArchType]int{
-		android.Arm:    minVersion,
-		android.Arm64:  21,
-		android.Mips:   minVersion,
-		android.Mips64: 21,
-		android.X86:    minVersion,
-		android.X86_64: 21,
+		android.Arm:     minVersion,
+		android.Arm64:   21,
+		android.Mips:    minVersion,
+		android.Mips64:  21,
+		android.Riscv64: android.FutureApiLevel,
+		android.X86:     minVersion,
+		android.X86_64:  21,
 	}
 
 	firstArchVersion, ok := firstArchVersions[arch.ArchType]
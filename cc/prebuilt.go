@@ -53,6 +53,11 @@ func (p *prebuiltLinker) PrebuiltSrcs() []string {
 	return p.properties.Srcs
 }
 
+// checkElfFiles returns true if the prebuilt ELF files should be checked by check-elf-deps.
+func (p *prebuiltLinker) checkElfFiles() bool {
+	return BoolDefault(p.properties.Check_elf_files, true)
+}
+
 type prebuiltLibraryInterface interface {
 	libraryInterface
 	prebuiltLinkerInterface
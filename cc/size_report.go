@@ -0,0 +1,163 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// This singleton generates a report attributing the bytes of every shared library and binary to
+// the static libraries and object files that were linked into it.  It is enabled by setting
+// SOONG_CC_SIZE_REPORT=true, which makes every link write a link map, and is built with the
+// cc-size-report goal into out/soong/cc_size_report.json and out/soong/cc_size_report.txt, a
+// table sorted by size.  If SOONG_CC_SIZE_REPORT_BASELINE is set to the path of the JSON report of
+// an earlier build, the changes since that build are also written to
+// out/soong/cc_size_report_diff.txt.
+
+func init() {
+	android.RegisterSingletonType("cc_size_report", sizeReportSingletonFactory)
+
+	pctx.HostBinToolVariable("sizeReportCmd", "cc_size_report")
+}
+
+const (
+	envVariableSizeReport         = "SOONG_CC_SIZE_REPORT"
+	envVariableSizeReportBaseline = "SOONG_CC_SIZE_REPORT_BASELINE"
+)
+
+var (
+	sizeReport = pctx.AndroidStaticRule("sizeReport",
+		blueprint.RuleParams{
+			Command:     "$sizeReportCmd -module $module -variant $variant -elf $elf -map $in -o $out",
+			CommandDeps: []string{"$sizeReportCmd"},
+		},
+		"module", "variant", "elf")
+
+	sizeReportMerge = pctx.AndroidStaticRule("sizeReportMerge",
+		blueprint.RuleParams{
+			Command:        "$sizeReportCmd -merge -o $out -table $table @$out.rsp",
+			CommandDeps:    []string{"$sizeReportCmd"},
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$in",
+		},
+		"table")
+
+	sizeReportDiff = pctx.AndroidStaticRule("sizeReportDiff",
+		blueprint.RuleParams{
+			Command:     "$sizeReportCmd -diff $baseline -o $out $in",
+			CommandDeps: []string{"$sizeReportCmd"},
+		},
+		"baseline")
+)
+
+func sizeReportEnabled(ctx android.BaseContext) bool {
+	return ctx.Config().IsEnvTrue(envVariableSizeReport)
+}
+
+// sizeReportBuildActions attributes the bytes of the installed file of a shared library or binary,
+// or of its output file if it is not installed, using the link map written when it was linked.
+// Modules that were not linked with a link map, like prebuilts, are skipped.
+func (c *Module) sizeReportBuildActions(ctx ModuleContext) {
+	linkMap := c.linkMap()
+	if !linkMap.Valid() || !c.outputFile.Valid() || ctx.Darwin() || ctx.Windows() {
+		return
+	}
+
+	elf := c.outputFile.Path()
+	if i, ok := c.installer.(interface{ installedFile() android.OptionalPath }); ok {
+		if installed := i.installedFile(); installed.Valid() {
+			elf = installed.Path()
+		}
+	}
+
+	c.sizeReport = android.PathForModuleOut(ctx, "size_report.json")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        sizeReport,
+		Description: "size report",
+		Input:       linkMap.Path(),
+		Implicit:    elf,
+		Output:      c.sizeReport,
+		Args: map[string]string{
+			"module":  ctx.ModuleName(),
+			"variant": ctx.ModuleSubDir(),
+			"elf":     elf.String(),
+		},
+	})
+}
+
+func sizeReportSingletonFactory() android.Singleton {
+	return &sizeReportSingleton{}
+}
+
+type sizeReportSingleton struct {
+	report android.Path
+}
+
+func (s *sizeReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !ctx.Config().IsEnvTrue(envVariableSizeReport) {
+		return
+	}
+
+	var reports android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if ccModule, ok := module.(*Module); ok && ccModule.sizeReport != nil {
+			reports = append(reports, ccModule.sizeReport)
+		}
+	})
+
+	report := android.PathForOutput(ctx, "cc_size_report.json")
+	table := android.PathForOutput(ctx, "cc_size_report.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:           sizeReportMerge,
+		Description:    "merge size reports",
+		Inputs:         reports,
+		Output:         report,
+		ImplicitOutput: table,
+		Args: map[string]string{
+			"table": table.String(),
+		},
+	})
+
+	outputs := android.Paths{report, table}
+	if baseline := ctx.Config().Getenv(envVariableSizeReportBaseline); baseline != "" {
+		diff := android.PathForOutput(ctx, "cc_size_report_diff.txt")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        sizeReportDiff,
+			Description: "diff size reports",
+			Input:       report,
+			Output:      diff,
+			Args: map[string]string{
+				"baseline": baseline,
+			},
+		})
+		outputs = append(outputs, diff)
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Phony,
+		Output:      android.PathForPhony(ctx, "cc-size-report"),
+		Inputs:      outputs,
+		Description: "cc size report",
+	})
+	s.report = report
+}
+
+func (s *sizeReportSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.report != nil {
+		ctx.Strict("SOONG_CC_SIZE_REPORT", s.report.String())
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestSizeReport(t *testing.T) {
+	config := android.TestArchConfig(buildDir, map[string]string{
+		"SOONG_CC_SIZE_REPORT":          "true",
+		"SOONG_CC_SIZE_REPORT_BASELINE": "/tmp/old_size_report.json",
+	})
+	ctx := createTestContext(t, config, `
+		cc_binary {
+			name: "bin",
+			srcs: ["foo.c"],
+			static_libs: ["libbaz"],
+			stl: "none",
+		}
+
+		cc_library_static {
+			name: "libbaz",
+			srcs: ["baz.c"],
+			stl: "none",
+		}
+
+		cc_prebuilt_library_shared {
+			name: "libprebuilt",
+			srcs: ["libprebuilt.so"],
+		}
+	`, map[string][]byte{
+		"libprebuilt.so": nil,
+	}, android.Android)
+	ctx.RegisterModuleType("cc_prebuilt_library_shared", android.ModuleFactoryAdaptor(prebuiltSharedLibraryFactory))
+	ctx.PreArchMutators(android.RegisterPrebuiltsPreArchMutators)
+	ctx.PostDepsMutators(android.RegisterPrebuiltsPostDepsMutators)
+	ctx.RegisterSingletonType("cc_size_report", android.SingletonFactoryAdaptor(sizeReportSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	bin := ctx.ModuleForTests("bin", "android_arm64_armv8-a_core")
+
+	ld := bin.Rule("ld")
+	if len(ld.ImplicitOutputs) != 1 {
+		t.Fatalf("expected the link map as the only implicit output, got %v", ld.ImplicitOutputs)
+	}
+	linkMap := ld.ImplicitOutputs[0].String()
+	if w := "-Wl,-Map=" + linkMap; !strings.Contains(ld.Args["ldFlags"], w) {
+		t.Errorf("expected ldFlags to contain %q, got %q", w, ld.Args["ldFlags"])
+	}
+
+	report := bin.Output("size_report.json")
+	if g, w := report.Input.String(), linkMap; g != w {
+		t.Errorf("expected size report input %q, got %q", w, g)
+	}
+	if g, w := report.Args["elf"], "target/product/test_device/system/bin/bin"; !strings.HasSuffix(g, w) {
+		t.Errorf("expected size report of the installed file ending in %q, got %q", w, g)
+	}
+	if g, w := report.Args["variant"], "android_arm64_armv8-a_core"; g != w {
+		t.Errorf("expected variant %q, got %q", w, g)
+	}
+
+	if libbaz := ctx.ModuleForTests("libbaz", "android_arm64_armv8-a_core_static"); libbaz.Module().(*Module).sizeReport != nil {
+		t.Errorf("expected no size report for a static library")
+	}
+	if prebuilt := ctx.ModuleForTests("prebuilt_libprebuilt", "android_arm64_armv8-a_core_shared"); prebuilt.Module().(*Module).sizeReport != nil {
+		t.Errorf("expected no size report for a prebuilt without a link map")
+	}
+
+	singleton := ctx.SingletonForTests("cc_size_report")
+	merge := singleton.Output("cc_size_report.json")
+	found := false
+	for _, input := range merge.Inputs {
+		if input.String() == report.Output.String() {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected merged report inputs to contain %q, got %v", report.Output, merge.Inputs)
+	}
+	if merge.ImplicitOutput == nil || !strings.HasSuffix(merge.ImplicitOutput.String(), "cc_size_report.txt") {
+		t.Errorf("expected the table as an implicit output, got %v", merge.ImplicitOutput)
+	}
+
+	diff := singleton.Output("cc_size_report_diff.txt")
+	if g, w := diff.Args["baseline"], "/tmp/old_size_report.json"; g != w {
+		t.Errorf("expected baseline %q, got %q", w, g)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// This singleton publishes the unstripped ELF files of every device cc module, including the
+// prebuilts, by their build ID into the symbols/.build-id/xx/yyyy.debug tree of the product and into
+// symbols-by-build-id.zip next to it, so that crash tooling can find the symbols for the build IDs
+// in a tombstone without knowing where the files were installed.  It is built with the
+// symbols-by-build-id goal, and the tree is tracked by the symbols/.build-id.stamp file as ninja
+// cannot declare the files in it.  bpf objects are not cc modules and are not included.
+
+func init() {
+	android.RegisterSingletonType("symbols_by_build_id", symbolsByBuildIdSingletonFactory)
+
+	pctx.HostBinToolVariable("symbolsByBuildIdCmd", "symbols_by_build_id")
+}
+
+var symbolsByBuildId = pctx.AndroidStaticRule("symbolsByBuildId",
+	blueprint.RuleParams{
+		Command: "rm -rf $dir/.build-id $stamp && $symbolsByBuildIdCmd -o $out -d $dir @$out.rsp && " +
+			"touch $stamp",
+		CommandDeps:    []string{"$symbolsByBuildIdCmd"},
+		Rspfile:        "$out.rsp",
+		RspfileContent: "$in",
+	},
+	"dir", "stamp")
+
+func symbolsByBuildIdSingletonFactory() android.Singleton {
+	return &symbolsByBuildIdSingleton{}
+}
+
+type symbolsByBuildIdSingleton struct {
+	zip android.Path
+}
+
+func (s *symbolsByBuildIdSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var files android.Paths
+	seen := make(map[string]bool)
+	ctx.VisitAllModules(func(module android.Module) {
+		ccModule, ok := module.(*Module)
+		if !ok || !ccModule.Enabled() || ccModule.IsStubs() {
+			return
+		}
+		if ccModule.Os().Class != android.Device {
+			return
+		}
+		if unstripped := ccModule.UnstrippedOutputFile(); unstripped != nil && !seen[unstripped.String()] {
+			seen[unstripped.String()] = true
+			files = append(files, unstripped)
+		}
+	})
+
+	productOut := android.PathForOutput(ctx, "target", "product", ctx.Config().DeviceName())
+	zip := productOut.Join(ctx, "symbols-by-build-id.zip")
+	dir := productOut.Join(ctx, "symbols")
+	stamp := dir.Join(ctx, ".build-id.stamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:           symbolsByBuildId,
+		Description:    "symbols by build id",
+		Inputs:         files,
+		Output:         zip,
+		ImplicitOutput: stamp,
+		Args: map[string]string{
+			"dir":   dir.String(),
+			"stamp": stamp.String(),
+		},
+	})
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Phony,
+		Output:      android.PathForPhony(ctx, "symbols-by-build-id"),
+		Input:       zip,
+		Description: "symbols by build id",
+	})
+	s.zip = zip
+}
+
+func (s *symbolsByBuildIdSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.zip != nil {
+		ctx.Strict("SOONG_SYMBOLS_BY_BUILD_ID_ZIP", s.zip.String())
+	}
+}
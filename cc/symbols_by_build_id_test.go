@@ -0,0 +1,83 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestSymbolsByBuildId(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	ctx := createTestContext(t, config, `
+		cc_binary {
+			name: "bin",
+			srcs: ["foo.c"],
+			shared_libs: ["libfoo"],
+			host_supported: true,
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			stl: "none",
+		}
+	`, nil, android.Android)
+	ctx.RegisterSingletonType("symbols_by_build_id", android.SingletonFactoryAdaptor(symbolsByBuildIdSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	zip := ctx.SingletonForTests("symbols_by_build_id").Output("symbols-by-build-id.zip")
+
+	var inputs []string
+	for _, input := range zip.Inputs {
+		inputs = append(inputs, input.String())
+	}
+
+	expected := []android.Module{
+		ctx.ModuleForTests("bin", "android_arm64_armv8-a_core").Module(),
+		ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_shared").Module(),
+	}
+	for _, m := range expected {
+		unstripped := m.(*Module).UnstrippedOutputFile().String()
+		if !android.InList(unstripped, inputs) {
+			t.Errorf("expected %q in the inputs, got %q", unstripped, inputs)
+		}
+	}
+
+	static := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_static").Module()
+	if u := static.(*Module).UnstrippedOutputFile(); u != nil && android.InList(u.String(), inputs) {
+		t.Errorf("unexpected static library %q in the inputs", u)
+	}
+
+	host := ctx.ModuleForTests("bin", "linux_glibc_x86_64").Module()
+	if u := host.(*Module).UnstrippedOutputFile(); u != nil && android.InList(u.String(), inputs) {
+		t.Errorf("unexpected host binary %q in the inputs", u)
+	}
+
+	if g, w := zip.Args["dir"], "target/product/test_device/symbols"; !strings.HasSuffix(g, w) {
+		t.Errorf("expected dir ending in %q, got %q", w, g)
+	}
+	if zip.ImplicitOutput == nil || zip.ImplicitOutput.String() != zip.Args["stamp"] {
+		t.Errorf("expected the stamp %q as an implicit output, got %v", zip.Args["stamp"], zip.ImplicitOutput)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// This singleton generates a report of the shared_libs, static_libs, whole_static_libs and
+// header_libs entries of cc modules that contribute no symbols and no headers to the modules that
+// list them.  It is enabled by setting SOONG_CC_UNUSED_DEPS=true, which makes every compile keep a
+// copy of its depfile and every link write a link map, and is built with the cc-unused-deps goal
+// into out/soong/cc_unused_deps.txt.  Each line of the report holds the Android.bp file, module,
+// property and dependency separated by tabs, and a dependency is only reported if it was unused in
+// every variant of the module.  Modules that are not linked, like static libraries, only have their
+// header_libs checked, as their other dependencies are only used when they are linked into another
+// module.  The report can be passed to bpfix -remove_unused_cc_deps to remove the entries from the
+// Android.bp files.
+
+func init() {
+	android.RegisterSingletonType("cc_unused_deps", unusedDepsSingletonFactory)
+
+	pctx.HostBinToolVariable("unusedDepsCmd", "cc_unused_deps")
+}
+
+const envVariableUnusedDeps = "SOONG_CC_UNUSED_DEPS"
+
+var (
+	unusedDeps = pctx.AndroidStaticRule("unusedDeps",
+		blueprint.RuleParams{
+			Command:     "$unusedDepsCmd $flags -o $out",
+			CommandDeps: []string{"$unusedDepsCmd"},
+		},
+		"flags")
+
+	unusedDepsMerge = pctx.AndroidStaticRule("unusedDepsMerge",
+		blueprint.RuleParams{
+			Command:        "$unusedDepsCmd -merge -o $out @$out.rsp",
+			CommandDeps:    []string{"$unusedDepsCmd"},
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$in",
+		})
+)
+
+func unusedDepsEnabled(ctx android.BaseContext) bool {
+	return ctx.Config().IsEnvTrue(envVariableUnusedDeps)
+}
+
+// unusedDepsBuildActions checks which of the dependencies listed in the properties of a module
+// were used by it.  objs must include the objects reused from other variants, so that all the
+// saved depfiles are checked.  Modules that were not linked with a link map, like static libraries,
+// only have their header_libs checked against the depfiles, and modules without objects, like
+// prebuilts, are skipped.
+func (c *Module) unusedDepsBuildActions(ctx ModuleContext, objs Objects) {
+	if ctx.Darwin() || ctx.Windows() {
+		return
+	}
+	linked := c.UnstrippedOutputFile()
+	linkMap := c.linkMap()
+	isLinked := linked != nil && linkMap.Valid()
+	if !isLinked && len(objs.depFiles) == 0 {
+		return
+	}
+
+	var props *BaseLinkerProperties
+	for _, p := range c.linker.linkerProps() {
+		if p, ok := p.(*BaseLinkerProperties); ok {
+			props = p
+		}
+	}
+	if props == nil {
+		return
+	}
+
+	properties := map[dependencyTag]struct {
+		name string
+		list []string
+	}{
+		sharedDepTag:      {"shared_libs", props.Shared_libs},
+		staticDepTag:      {"static_libs", props.Static_libs},
+		wholeStaticDepTag: {"whole_static_libs", props.Whole_static_libs},
+		headerDepTag:      {"header_libs", props.Header_libs},
+	}
+
+	var flags []string
+	var implicits android.Paths
+	seen := make(map[string]bool)
+
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		ccDep, ok := dep.(*Module)
+		if !ok {
+			return
+		}
+		tag, ok := ctx.OtherModuleDependencyTag(dep).(dependencyTag)
+		if !ok {
+			return
+		}
+		tag.explicitlyVersioned = false
+		property, ok := properties[tag]
+		if !ok || !isLinked && property.name != "header_libs" {
+			return
+		}
+
+		// Only check the dependencies that are listed in the property, and not the ones that
+		// are added implicitly like the system libraries or the STL.
+		name := strings.TrimPrefix(ctx.OtherModuleName(dep), "prebuilt_")
+		listed := false
+		for _, lib := range property.list {
+			if strings.Split(lib, "#")[0] == name {
+				listed = true
+			}
+		}
+		if !listed {
+			return
+		}
+
+		arg := property.name + ":" + name
+		if property.name != "header_libs" && ccDep.outputFile.Valid() {
+			arg += ":" + ccDep.outputFile.String()
+			implicits = append(implicits, ccDep.outputFile.Path())
+		}
+		flags = append(flags, "-dep "+arg)
+
+		if !seen[name] {
+			seen[name] = true
+			if i, ok := ccDep.linker.(exportedFlagsProducer); ok {
+				for _, dir := range includeDirsFromFlags(i.exportedFlags()) {
+					flags = append(flags, "-include "+name+":"+dir)
+				}
+			}
+		}
+	})
+
+	if len(flags) == 0 {
+		return
+	}
+
+	flags = append([]string{
+		"-module " + ctx.ModuleName(),
+		"-bp " + ctx.BlueprintsFile(),
+	}, flags...)
+	if isLinked {
+		flags = append(flags, "-elf "+linked.String(), "-map "+linkMap.String())
+		implicits = append(implicits, linked, linkMap.Path())
+	}
+	for _, depFile := range objs.depFiles {
+		flags = append(flags, "-deps "+depFile.String())
+		implicits = append(implicits, depFile)
+	}
+
+	c.unusedDepsReport = android.PathForModuleOut(ctx, "unused_deps.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        unusedDeps,
+		Description: "check unused deps",
+		Output:      c.unusedDepsReport,
+		Implicits:   implicits,
+		Args: map[string]string{
+			"flags": strings.Join(flags, " "),
+		},
+	})
+}
+
+// includeDirsFromFlags returns the directories passed with -I or -isystem in a list of flags, where
+// each entry may hold multiple flags.
+func includeDirsFromFlags(flags []string) []string {
+	var dirs []string
+	fields := strings.Fields(strings.Join(flags, " "))
+	for i := 0; i < len(fields); i++ {
+		switch {
+		case fields[i] == "-isystem" && i+1 < len(fields):
+			i++
+			dirs = append(dirs, fields[i])
+		case strings.HasPrefix(fields[i], "-isystem"):
+			dirs = append(dirs, strings.TrimPrefix(fields[i], "-isystem"))
+		case strings.HasPrefix(fields[i], "-I"):
+			dirs = append(dirs, strings.TrimPrefix(fields[i], "-I"))
+		}
+	}
+	return dirs
+}
+
+func unusedDepsSingletonFactory() android.Singleton {
+	return &unusedDepsSingleton{}
+}
+
+type unusedDepsSingleton struct {
+	report android.Path
+}
+
+func (s *unusedDepsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !ctx.Config().IsEnvTrue(envVariableUnusedDeps) {
+		return
+	}
+
+	var reports android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if ccModule, ok := module.(*Module); ok && ccModule.unusedDepsReport != nil {
+			reports = append(reports, ccModule.unusedDepsReport)
+		}
+	})
+
+	report := android.PathForOutput(ctx, "cc_unused_deps.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        unusedDepsMerge,
+		Description: "merge unused deps reports",
+		Inputs:      reports,
+		Output:      report,
+	})
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Phony,
+		Output:      android.PathForPhony(ctx, "cc-unused-deps"),
+		Input:       report,
+		Description: "cc unused deps",
+	})
+	s.report = report
+}
+
+func (s *unusedDepsSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.report != nil {
+		ctx.Strict("SOONG_CC_UNUSED_DEPS_REPORT", s.report.String())
+	}
+}
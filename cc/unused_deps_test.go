@@ -0,0 +1,137 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestUnusedDeps(t *testing.T) {
+	config := android.TestArchConfig(buildDir, map[string]string{"SOONG_CC_UNUSED_DEPS": "true"})
+	ctx := createTestContext(t, config, `
+		cc_binary {
+			name: "bin",
+			srcs: ["foo.c"],
+			shared_libs: ["libbar"],
+			static_libs: ["libbaz"],
+			header_libs: ["libhdr"],
+			stl: "none",
+		}
+
+		cc_library {
+			name: "libbar",
+			srcs: ["bar.c"],
+			stl: "none",
+		}
+
+		cc_library_static {
+			name: "libbaz",
+			srcs: ["baz.c"],
+			shared_libs: ["libbar"],
+			header_libs: ["libhdr"],
+			stl: "none",
+		}
+
+		cc_library_headers {
+			name: "libhdr",
+			export_include_dirs: ["include"],
+		}
+
+		cc_prebuilt_library_shared {
+			name: "libprebuilt",
+			srcs: ["libprebuilt.so"],
+			shared_libs: ["libbar"],
+		}
+	`, map[string][]byte{
+		"include/hdr.h":  nil,
+		"libprebuilt.so": nil,
+	}, android.Android)
+	ctx.RegisterModuleType("cc_prebuilt_library_shared", android.ModuleFactoryAdaptor(prebuiltSharedLibraryFactory))
+	ctx.PreArchMutators(android.RegisterPrebuiltsPreArchMutators)
+	ctx.PostDepsMutators(android.RegisterPrebuiltsPostDepsMutators)
+	ctx.RegisterSingletonType("cc_unused_deps", android.SingletonFactoryAdaptor(unusedDepsSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	bin := ctx.ModuleForTests("bin", "android_arm64_armv8-a_core")
+
+	obj := bin.Output("obj/foo.o")
+	if obj.Rule != ccPostDeps {
+		t.Errorf("expected foo.o to be built with ccPostDeps, got %v", obj.Rule)
+	}
+	depFile := strings.TrimSuffix(obj.Output.String(), ".o") + ".deps"
+	if w := "cp -f " + obj.Output.String() + ".d " + depFile; !strings.Contains(obj.Args["postDeps"], w) {
+		t.Errorf("expected postDeps to contain %q, got %q", w, obj.Args["postDeps"])
+	}
+
+	ld := bin.Rule("ld")
+	if len(ld.ImplicitOutputs) != 1 || ld.ImplicitOutputs[0].Rel() != "bin.map" {
+		t.Fatalf("expected link to write bin.map, got %q", ld.ImplicitOutputs.Strings())
+	}
+	linkMap := ld.ImplicitOutputs[0].String()
+	if w := "-Wl,-Map=" + linkMap; !strings.Contains(ld.Args["ldFlags"], w) {
+		t.Errorf("expected ldFlags to contain %q, got %q", w, ld.Args["ldFlags"])
+	}
+
+	check := bin.Rule("unusedDeps")
+	libbar := ctx.ModuleForTests("libbar", "android_arm64_armv8-a_core_shared").Module().(*Module).outputFile.Path()
+	libbaz := ctx.ModuleForTests("libbaz", "android_arm64_armv8-a_core_static").Module().(*Module).outputFile.Path()
+	for _, w := range []string{
+		"-module bin ",
+		"-bp Android.bp ",
+		"-map " + linkMap,
+		"-deps " + depFile,
+		"-dep shared_libs:libbar:" + libbar.String(),
+		"-dep static_libs:libbaz:" + libbaz.String(),
+		"-dep header_libs:libhdr ",
+		"-include libhdr:include",
+	} {
+		if !strings.Contains(check.Args["flags"]+" ", w) {
+			t.Errorf("expected unused deps flags to contain %q, got %q", w, check.Args["flags"])
+		}
+	}
+	if strings.Contains(check.Args["flags"], "libc") {
+		t.Errorf("expected implicit dependencies not to be checked, got %q", check.Args["flags"])
+	}
+
+	// Static libraries are not linked, so only their header_libs are checked.
+	static := ctx.ModuleForTests("libbaz", "android_arm64_armv8-a_core_static").Rule("unusedDeps")
+	if w := "-dep header_libs:libhdr"; !strings.Contains(static.Args["flags"], w) {
+		t.Errorf("expected static library flags to contain %q, got %q", w, static.Args["flags"])
+	}
+	for _, w := range []string{"-elf", "-map", "libbar"} {
+		if strings.Contains(static.Args["flags"], w) {
+			t.Errorf("expected static library flags not to contain %q, got %q", w, static.Args["flags"])
+		}
+	}
+
+	// Prebuilts have no objects to check against.
+	prebuilt := ctx.ModuleForTests("prebuilt_libprebuilt", "android_arm64_armv8-a_core_shared")
+	if rule := prebuilt.MaybeRule("unusedDeps").Rule; rule != nil {
+		t.Errorf("expected no unused deps check for a prebuilt, got %v", rule)
+	}
+
+	merge := ctx.SingletonForTests("cc_unused_deps").Output("cc_unused_deps.txt")
+	if !android.InList(check.Output.String(), merge.Inputs.Strings()) {
+		t.Errorf("expected %q in merged report inputs, got %q", check.Output.String(), merge.Inputs.Strings())
+	}
+}
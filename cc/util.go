@@ -74,12 +74,15 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 		libFlags:        strings.Join(in.libFlags, " "),
 		tidyFlags:       strings.Join(in.TidyFlags, " "),
 		sAbiFlags:       strings.Join(in.SAbiFlags, " "),
+		layeringFlags:   strings.Join(in.LayeringFlags, " "),
 		yasmFlags:       strings.Join(in.YasmFlags, " "),
 		toolchain:       in.Toolchain,
 		sdclang:         in.Sdclang,
 		coverage:        in.Coverage,
 		tidy:            in.Tidy,
 		sAbiDump:        in.SAbiDump,
+		saveDeps:        in.SaveDeps,
+		linkMap:         in.LinkMap,
 
 		systemIncludeFlags: strings.Join(in.SystemIncludeFlags, " "),
 
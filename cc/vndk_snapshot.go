@@ -0,0 +1,343 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// This singleton freezes the VNDK of the platform into vndk-snapshot.zip, which can be unzipped into
+// prebuilts/vndk/v<version>/<arch> to build a vendor image against it.  The zip is laid out as:
+//
+//   Android.bp                  vndk_prebuilt_shared modules for the VNDK libraries
+//   arch-<arch>-<variant>/
+//       shared/vndk-core/       VNDK-core libraries
+//       shared/vndk-sp/         VNDK-SP libraries
+//       shared/llndk-stub/      LLNDK stub libraries
+//   include/                    headers exported by the VNDK libraries, by source path
+//   NOTICE_FILES/               notice files of the libraries, as <lib>.so.txt
+//   configs/                    vndkcore, vndksp, llndk and vndkprivate .libraries.txt
+//
+// The snapshot is only generated when the device builds the VNDK from source and the product sets
+// VNDK_SNAPSHOT_BUILD_ARTIFACTS, as collecting the headers globs every exported include directory of
+// the VNDK libraries, and is built with the vndk-snapshot goal.
+
+func init() {
+	android.RegisterSingletonType("vndk_snapshot", vndkSnapshotSingletonFactory)
+
+	pctx.HostBinToolVariable("vndkSnapshotZipCmd", "soong_zip")
+}
+
+var vndkSnapshotZip = pctx.AndroidStaticRule("vndkSnapshotZip",
+	blueprint.RuleParams{
+		Command:        "$vndkSnapshotZipCmd -o $out -C $root -l $out.rsp",
+		CommandDeps:    []string{"$vndkSnapshotZipCmd"},
+		Rspfile:        "$out.rsp",
+		RspfileContent: "$in",
+	},
+	"root")
+
+var vndkSnapshotHeaderExts = []string{".h", ".hh", ".hpp", ".hxx", ".inc"}
+
+func vndkSnapshotSingletonFactory() android.Singleton {
+	return &vndkSnapshotSingleton{}
+}
+
+type vndkSnapshotSingleton struct {
+	zip android.Path
+}
+
+// vndkSnapshotLib is a library in the snapshot with the snapshot paths of its variants.
+type vndkSnapshotLib struct {
+	name       string
+	vndkType   string
+	private    bool
+	includes   []string
+	archSrcs   map[string]string
+	archOrders []string
+}
+
+// vndkSnapshotArchDir returns the directory of the snapshot for the variants of an arch, like
+// "arch-arm64-armv8-a".
+func vndkSnapshotArchDir(arch android.Arch) string {
+	dir := "arch-" + arch.ArchType.String()
+	if arch.ArchVariant != "" {
+		dir += "-" + arch.ArchVariant
+	}
+	return dir
+}
+
+// vndkSnapshotIncludeDirs returns the directories in a list of exported flags, like
+// "-Iexternal/foo/include" or "-isystem external/foo/include".
+func vndkSnapshotIncludeDirs(flags []string) []string {
+	var dirs []string
+	for _, flag := range flags {
+		var dir string
+		if strings.HasPrefix(flag, "-isystem") {
+			dir = strings.TrimSpace(strings.TrimPrefix(flag, "-isystem"))
+		} else if strings.HasPrefix(flag, "-I") {
+			dir = strings.TrimSpace(strings.TrimPrefix(flag, "-I"))
+		} else {
+			continue
+		}
+		if dir = filepath.Clean(dir); dir != "." && !android.InList(dir, dirs) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (s *vndkSnapshotSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if ctx.DeviceConfig().VndkVersion() != "current" || !ctx.DeviceConfig().VndkSnapshotBuildArtifacts() {
+		return
+	}
+
+	root := android.PathForOutput(ctx, "vndk-snapshot")
+	var files android.Paths
+	installed := make(map[string]bool)
+
+	copyFile := func(src android.Path, dest string) {
+		if installed[dest] {
+			return
+		}
+		installed[dest] = true
+		out := root.Join(ctx, dest)
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.Cp,
+			Description: "vndk snapshot " + dest,
+			Input:       src,
+			Output:      out,
+		})
+		files = append(files, out)
+	}
+
+	writeFile := func(dest string, lines []string) {
+		out := root.Join(ctx, dest)
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.WriteFile,
+			Description: "vndk snapshot " + dest,
+			Output:      out,
+			Args: map[string]string{
+				"content": strings.Join(lines, "\\n"),
+			},
+		})
+		files = append(files, out)
+	}
+
+	libs := make(map[string]*vndkSnapshotLib)
+	ctx.VisitAllModules(func(module android.Module) {
+		m, ok := module.(*Module)
+		if !ok || !m.Enabled() || !m.useVndk() || m.Os() != android.Android || !m.OutputFile().Valid() {
+			return
+		}
+
+		var name, vndkType string
+		var private bool
+		if stub, ok := m.linker.(*llndkStubDecorator); ok {
+			if !stub.shared() {
+				return
+			}
+			name = strings.TrimSuffix(m.Name(), llndkLibrarySuffix)
+			vndkType = "llndk-stub"
+			private = !Bool(stub.Properties.Vendor_available)
+		} else if library, ok := m.linker.(*libraryDecorator); ok && library.shared() {
+			if !m.isVndk() || m.isVndkExt() {
+				return
+			}
+			name = m.Name()
+			if m.isVndkSp() {
+				vndkType = "vndk-sp"
+			} else {
+				vndkType = "vndk-core"
+			}
+			private = !Bool(m.VendorProperties.Vendor_available)
+		} else {
+			return
+		}
+
+		lib := libs[name]
+		if lib == nil {
+			lib = &vndkSnapshotLib{
+				name:     name,
+				vndkType: vndkType,
+				private:  private,
+				archSrcs: make(map[string]string),
+			}
+			libs[name] = lib
+		}
+
+		arch := m.Target().Arch
+		src := filepath.Join(vndkSnapshotArchDir(arch), "shared", vndkType, name+".so")
+		copyFile(m.OutputFile().Path(), src)
+		if _, exists := lib.archSrcs[arch.ArchType.String()]; !exists {
+			lib.archOrders = append(lib.archOrders, arch.ArchType.String())
+		}
+		lib.archSrcs[arch.ArchType.String()] = src
+
+		if notice := m.NoticeFile(); notice.Valid() {
+			copyFile(notice.Path(), filepath.Join("NOTICE_FILES", name+".so.txt"))
+		}
+
+		// The headers of the LLNDK stubs are generated from the platform headers with the
+		// versioner and are not part of the snapshot.  Generated headers of the VNDK libraries
+		// are not either, only the exported directories in the source tree are.
+		if vndkType == "llndk-stub" {
+			return
+		}
+		exporter, ok := m.linker.(exportedFlagsProducer)
+		if !ok {
+			return
+		}
+		for _, dir := range vndkSnapshotIncludeDirs(exporter.exportedFlags()) {
+			if strings.HasPrefix(dir, ctx.Config().BuildDir()) || filepath.IsAbs(dir) {
+				continue
+			}
+			include := filepath.Join("include", dir)
+			if android.InList(include, lib.includes) {
+				continue
+			}
+			lib.includes = append(lib.includes, include)
+			for _, ext := range vndkSnapshotHeaderExts {
+				headers, err := ctx.GlobWithDeps(filepath.Join(dir, "**/*"+ext), nil)
+				if err != nil {
+					ctx.Errorf("failed to glob headers of %q in %q: %s", name, dir, err.Error())
+					continue
+				}
+				for _, header := range headers {
+					copyFile(android.PathForSource(ctx, header), filepath.Join("include", header))
+				}
+			}
+		}
+	})
+
+	if len(libs) == 0 {
+		return
+	}
+
+	var names []string
+	for name := range libs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configs := map[string][]string{}
+	for _, name := range names {
+		lib := libs[name]
+		switch lib.vndkType {
+		case "vndk-core":
+			configs["vndkcore"] = append(configs["vndkcore"], name+".so")
+		case "vndk-sp":
+			configs["vndksp"] = append(configs["vndksp"], name+".so")
+		case "llndk-stub":
+			configs["llndk"] = append(configs["llndk"], name+".so")
+		}
+		if lib.private {
+			configs["vndkprivate"] = append(configs["vndkprivate"], name+".so")
+		}
+	}
+	for _, config := range []string{"vndkcore", "vndksp", "llndk", "vndkprivate"} {
+		writeFile(filepath.Join("configs", config+".libraries.txt"), configs[config])
+	}
+
+	writeFile("Android.bp", vndkSnapshotAndroidBp(ctx, libs, names))
+
+	zip := android.PathForOutput(ctx, "vndk-snapshot.zip")
+	sort.Slice(files, func(i, j int) bool { return files[i].String() < files[j].String() })
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        vndkSnapshotZip,
+		Description: "vndk snapshot",
+		Inputs:      files,
+		Output:      zip,
+		Args: map[string]string{
+			"root": root.String(),
+		},
+	})
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Phony,
+		Output:      android.PathForPhony(ctx, "vndk-snapshot"),
+		Input:       zip,
+		Description: "vndk snapshot",
+	})
+	s.zip = zip
+}
+
+// vndkSnapshotAndroidBp returns the lines of the Android.bp file that defines a
+// vndk_prebuilt_shared module for each VNDK library of the snapshot.
+func vndkSnapshotAndroidBp(ctx android.SingletonContext, libs map[string]*vndkSnapshotLib, names []string) []string {
+	version := ctx.DeviceConfig().PlatformVndkVersion()
+	var targetArch string
+	if arches := ctx.DeviceConfig().Arches(); len(arches) > 0 {
+		targetArch = arches[0].ArchType.String()
+	}
+
+	quote := func(list []string) string {
+		var quoted []string
+		for _, s := range list {
+			quoted = append(quoted, fmt.Sprintf("%q", s))
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+
+	lines := []string{"// This file is autogenerated by the vndk_snapshot singleton.  DO NOT EDIT."}
+	for _, name := range names {
+		lib := libs[name]
+		if lib.vndkType == "llndk-stub" {
+			continue
+		}
+		lines = append(lines,
+			"",
+			"vndk_prebuilt_shared {",
+			fmt.Sprintf("    name: %q,", name),
+			fmt.Sprintf("    version: %q,", version),
+			fmt.Sprintf("    target_arch: %q,", targetArch))
+		if ctx.DeviceConfig().BinderBitness() == "32" {
+			lines = append(lines, "    binder32bit: true,")
+		}
+		lines = append(lines,
+			fmt.Sprintf("    vendor_available: %t,", !lib.private),
+			"    vndk: {",
+			"        enabled: true,")
+		if lib.vndkType == "vndk-sp" {
+			lines = append(lines, "        support_system_process: true,")
+		}
+		lines = append(lines, "    },")
+		if len(lib.includes) > 0 {
+			lines = append(lines, "    export_include_dirs: "+quote(lib.includes)+",")
+		}
+		lines = append(lines, "    arch: {")
+		for _, arch := range lib.archOrders {
+			lines = append(lines,
+				"        "+arch+": {",
+				"            srcs: "+quote([]string{lib.archSrcs[arch]})+",",
+				"        },")
+		}
+		lines = append(lines, "    },", "}")
+	}
+	return lines
+}
+
+func (s *vndkSnapshotSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.zip != nil {
+		ctx.Strict("SOONG_VNDK_SNAPSHOT_ZIP", s.zip.String())
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestVndkSnapshot(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+	config.TestProductVariables.VndkSnapshotBuildArtifacts = BoolPtr(true)
+
+	ctx := createTestContext(t, config, `
+		cc_library {
+			name: "libvndk",
+			vendor_available: true,
+			vndk: {
+				enabled: true,
+			},
+			nocrt: true,
+		}
+
+		cc_library {
+			name: "libvndk_sp_private",
+			vendor_available: false,
+			vndk: {
+				enabled: true,
+				support_system_process: true,
+			},
+			nocrt: true,
+		}
+
+		cc_library {
+			name: "libvendor",
+			vendor: true,
+			nocrt: true,
+		}
+
+		llndk_library {
+			name: "libllndk",
+			symbol_file: "",
+		}
+	`, nil, android.Android)
+	ctx.RegisterSingletonType("vndk_snapshot", android.SingletonFactoryAdaptor(vndkSnapshotSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	singleton := ctx.SingletonForTests("vndk_snapshot")
+	zip := singleton.Output("vndk-snapshot.zip")
+
+	var inputs []string
+	for _, input := range zip.Inputs {
+		inputs = append(inputs, strings.TrimPrefix(input.String(), zip.Args["root"]+"/"))
+	}
+
+	for _, w := range []string{
+		"arch-arm64-armv8-a/shared/vndk-core/libvndk.so",
+		"arch-arm-armv7-a-neon/shared/vndk-core/libvndk.so",
+		"arch-arm64-armv8-a/shared/vndk-sp/libvndk_sp_private.so",
+		"arch-arm64-armv8-a/shared/llndk-stub/libllndk.so",
+		"configs/vndkcore.libraries.txt",
+		"Android.bp",
+	} {
+		if !android.InList(w, inputs) {
+			t.Errorf("expected %q in the snapshot, got %q", w, inputs)
+		}
+	}
+	for _, input := range inputs {
+		if strings.Contains(input, "libvendor") {
+			t.Errorf("unexpected vendor library %q in the snapshot", input)
+		}
+	}
+
+	configs := map[string]string{
+		"vndkcore":    "libvndk.so",
+		"vndksp":      "libvndk_sp_private.so",
+		"llndk":       "libllndk.so",
+		"vndkprivate": "libvndk_sp_private.so",
+	}
+	for config, w := range configs {
+		out := singleton.Output("vndk-snapshot/configs/" + config + ".libraries.txt")
+		if g := out.Args["content"]; g != w {
+			t.Errorf("expected %s.libraries.txt to contain %q, got %q", config, w, g)
+		}
+	}
+
+	bp := strings.Split(singleton.Output("vndk-snapshot/Android.bp").Args["content"], "\\n")
+	for _, w := range []string{
+		`    name: "libvndk_sp_private",`,
+		`    version: "VER",`,
+		`    target_arch: "arm64",`,
+		`    vendor_available: false,`,
+		`        support_system_process: true,`,
+		`            srcs: ["arch-arm-armv7-a-neon/shared/vndk-core/libvndk.so"],`,
+	} {
+		if !android.InList(w, bp) {
+			t.Errorf("expected %q in Android.bp, got:\n%s", w, strings.Join(bp, "\n"))
+		}
+	}
+	if android.InList(`    name: "libllndk",`, bp) {
+		t.Errorf("unexpected module for the LLNDK stub in Android.bp")
+	}
+
+	if g, w := vndkSnapshotIncludeDirs([]string{"-Ia/include", "-isystem b/include", "-DFOO", "-Ia/include/"}),
+		[]string{"a/include", "b/include"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected include dirs %q, got %q", w, g)
+	}
+}
+
+func TestVndkSnapshotNotRequested(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+
+	ctx := createTestContext(t, config, `
+		cc_library {
+			name: "libvndk",
+			vendor_available: true,
+			vndk: {
+				enabled: true,
+			},
+			nocrt: true,
+		}
+	`, nil, android.Android)
+	ctx.RegisterSingletonType("vndk_snapshot", android.SingletonFactoryAdaptor(vndkSnapshotSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	if rule := ctx.SingletonForTests("vndk_snapshot").MaybeOutput("vndk-snapshot.zip").Rule; rule != nil {
+		t.Errorf("expected no snapshot without VndkSnapshotBuildArtifacts, got %v", rule)
+	}
+}
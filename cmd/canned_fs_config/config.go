@@ -0,0 +1,314 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// capabilities are the Linux capabilities by name, without the CAP_ prefix.
+var capabilities = map[string]uint{
+	"CHOWN":            0,
+	"DAC_OVERRIDE":     1,
+	"DAC_READ_SEARCH":  2,
+	"FOWNER":           3,
+	"FSETID":           4,
+	"KILL":             5,
+	"SETGID":           6,
+	"SETUID":           7,
+	"SETPCAP":          8,
+	"LINUX_IMMUTABLE":  9,
+	"NET_BIND_SERVICE": 10,
+	"NET_BROADCAST":    11,
+	"NET_ADMIN":        12,
+	"NET_RAW":          13,
+	"IPC_LOCK":         14,
+	"IPC_OWNER":        15,
+	"SYS_MODULE":       16,
+	"SYS_RAWIO":        17,
+	"SYS_CHROOT":       18,
+	"SYS_PTRACE":       19,
+	"SYS_PACCT":        20,
+	"SYS_ADMIN":        21,
+	"SYS_BOOT":         22,
+	"SYS_NICE":         23,
+	"SYS_RESOURCE":     24,
+	"SYS_TIME":         25,
+	"SYS_TTY_CONFIG":   26,
+	"MKNOD":            27,
+	"LEASE":            28,
+	"AUDIT_WRITE":      29,
+	"AUDIT_CONTROL":    30,
+	"SETFCAP":          31,
+	"MAC_OVERRIDE":     32,
+	"MAC_ADMIN":        33,
+	"SYSLOG":           34,
+	"WAKE_ALARM":       35,
+	"BLOCK_SUSPEND":    36,
+	"AUDIT_READ":       37,
+}
+
+// Override is a section of a config.fs file that changes the ownership, mode or capabilities of
+// the files matching its path.  A path ending in * matches all the paths it is a prefix of.
+type Override struct {
+	Path string
+	Line int
+
+	Mode *uint64
+	Uid  *uint64
+	Gid  *uint64
+	Caps *uint64
+
+	matched bool
+}
+
+func (o *Override) matches(path string) bool {
+	if strings.HasSuffix(o.Path, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(o.Path, "*"))
+	}
+	return path == o.Path
+}
+
+// Entry is a line of a canned fs_config file.
+type Entry struct {
+	Path string
+	Uid  uint64
+	Gid  uint64
+	Mode uint64
+	Caps uint64
+}
+
+// normalizePath returns a path without the leading and trailing slashes, so that paths from
+// config.fs files, where directories end in a slash, match the paths of canned fs_config files,
+// which start with a slash.
+func normalizePath(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// ReadAids reads the Android IDs defined in android_filesystem_config.h, from lines like:
+//
+//   #define AID_SYSTEM 1000 /* system server */
+//
+// The same way as fs_config_generator, every AID_* defined to a number can be used, and the
+// definitions that are not numbers are skipped.
+func ReadAids(r io.Reader) (map[string]uint64, error) {
+	ret := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "#define" || !strings.HasPrefix(fields[1], "AID_") {
+			continue
+		}
+		if id, err := strconv.ParseUint(fields[2], 0, 32); err == nil {
+			ret[fields[1]] = id
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func parseId(s string, aids map[string]uint64) (uint64, error) {
+	if id, ok := aids[strings.ToUpper(s)]; ok {
+		return id, nil
+	}
+	if id, ok := aids["AID_"+strings.ToUpper(s)]; ok {
+		return id, nil
+	}
+	id, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown user or group %q", s)
+	}
+	return id, nil
+}
+
+func parseCaps(s string) (uint64, error) {
+	var caps uint64
+	for _, c := range strings.Fields(s) {
+		name := strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+		if bit, ok := capabilities[name]; ok {
+			caps |= 1 << bit
+		} else if bit, err := strconv.ParseUint(c, 0, 6); err == nil {
+			caps |= 1 << bit
+		} else {
+			return 0, fmt.Errorf("unknown capability %q", c)
+		}
+	}
+	return caps, nil
+}
+
+// ParseConfigFs parses a config.fs style file, made of sections like:
+//
+//   [bin/foo]
+//   mode: 0750
+//   user: AID_SYSTEM
+//   group: AID_SHELL
+//   caps: NET_ADMIN NET_RAW
+//
+// where every key is optional.  Users and groups are names of aids, with or without the AID_
+// prefix, or numbers, and capabilities are names, with or without the CAP_ prefix, or bit numbers.
+func ParseConfigFs(r io.Reader, aids map[string]uint64) ([]*Override, error) {
+	var ret []*Override
+	var cur *Override
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("line %d: invalid section %q", line, text)
+			}
+			cur = &Override{Path: normalizePath(text[1 : len(text)-1]), Line: line}
+			ret = append(ret, cur)
+			continue
+		}
+
+		if cur == nil {
+			return nil, fmt.Errorf("line %d: %q is outside of a section", line, text)
+		}
+		i := strings.IndexAny(text, ":=")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected <key>: <value>, got %q", line, text)
+		}
+		key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+
+		var v uint64
+		var err error
+		switch key {
+		case "mode":
+			v, err = strconv.ParseUint(value, 8, 32)
+			if err == nil && v > 07777 {
+				err = fmt.Errorf("mode %q out of range", value)
+			}
+			cur.Mode = &v
+		case "user":
+			v, err = parseId(value, aids)
+			cur.Uid = &v
+		case "group":
+			v, err = parseId(value, aids)
+			cur.Gid = &v
+		case "caps":
+			v, err = parseCaps(value)
+			cur.Caps = &v
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ParseCanned parses a canned fs_config file with lines of "<path> <uid> <gid> <mode>" and an
+// optional "capabilities=<mask>".
+func ParseCanned(r io.Reader) ([]*Entry, error) {
+	var ret []*Entry
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 || len(fields) > 5 {
+			return nil, fmt.Errorf("line %d: expected <path> <uid> <gid> <mode> [capabilities=<mask>]", line)
+		}
+
+		e := &Entry{Path: fields[0]}
+		var err error
+		if e.Uid, err = strconv.ParseUint(fields[1], 10, 32); err != nil {
+			return nil, fmt.Errorf("line %d: invalid uid %q", line, fields[1])
+		}
+		if e.Gid, err = strconv.ParseUint(fields[2], 10, 32); err != nil {
+			return nil, fmt.Errorf("line %d: invalid gid %q", line, fields[2])
+		}
+		if e.Mode, err = strconv.ParseUint(fields[3], 8, 32); err != nil {
+			return nil, fmt.Errorf("line %d: invalid mode %q", line, fields[3])
+		}
+		if len(fields) == 5 {
+			caps := strings.TrimPrefix(fields[4], "capabilities=")
+			if e.Caps, err = strconv.ParseUint(caps, 0, 64); err != nil || caps == fields[4] {
+				return nil, fmt.Errorf("line %d: invalid capabilities %q", line, fields[4])
+			}
+		}
+		ret = append(ret, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Apply applies the overrides to the entries in order, so that later overrides win.  It is an
+// error for an override to match no entry.
+func Apply(entries []*Entry, overrides []*Override) error {
+	for _, e := range entries {
+		path := normalizePath(e.Path)
+		for _, o := range overrides {
+			if !o.matches(path) {
+				continue
+			}
+			o.matched = true
+			if o.Mode != nil {
+				e.Mode = *o.Mode
+			}
+			if o.Uid != nil {
+				e.Uid = *o.Uid
+			}
+			if o.Gid != nil {
+				e.Gid = *o.Gid
+			}
+			if o.Caps != nil {
+				e.Caps = *o.Caps
+			}
+		}
+	}
+
+	for _, o := range overrides {
+		if !o.matched {
+			return fmt.Errorf("line %d: [%s] does not match any file", o.Line, o.Path)
+		}
+	}
+	return nil
+}
+
+// WriteCanned writes the entries as a canned fs_config file.
+func WriteCanned(w io.Writer, entries []*Entry) error {
+	for _, e := range entries {
+		var err error
+		if e.Caps != 0 {
+			_, err = fmt.Fprintf(w, "%s %d %d %04o capabilities=0x%x\n", e.Path, e.Uid, e.Gid, e.Mode, e.Caps)
+		} else {
+			_, err = fmt.Fprintf(w, "%s %d %d %04o\n", e.Path, e.Uid, e.Gid, e.Mode)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAidHeader = `
+#ifndef _ANDROID_FILESYSTEM_CONFIG_H_
+#define _ANDROID_FILESYSTEM_CONFIG_H_
+
+#define AID_ROOT 0 /* traditional unix root user */
+#define AID_SYSTEM 1000 /* system server */
+#define AID_SHELL 2000 /* adb and debug shell user */
+#define AID_OEM_RESERVED_START 2900
+#define AID_APP AID_APP_START /* alias, not a number */
+
+#endif
+`
+
+func testAids(t *testing.T) map[string]uint64 {
+	t.Helper()
+	aids, err := ReadAids(strings.NewReader(testAidHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aids
+}
+
+func TestReadAids(t *testing.T) {
+	want := map[string]uint64{
+		"AID_ROOT":               0,
+		"AID_SYSTEM":             1000,
+		"AID_SHELL":              2000,
+		"AID_OEM_RESERVED_START": 2900,
+	}
+	if g := testAids(t); !reflect.DeepEqual(g, want) {
+		t.Errorf("expected %v, got %v", want, g)
+	}
+}
+
+func TestApply(t *testing.T) {
+	canned := `/ 1000 1000 0755
+/apex_manifest.json 1000 1000 0644
+/bin 0 2000 0755
+/bin/foo 0 2000 0755
+/bin/bar 0 2000 0755
+/lib64/libfoo.so 1000 1000 0644
+`
+	config := `
+# Comments and blank lines are skipped.
+[bin/foo]
+mode: 0750
+user: AID_SYSTEM
+group: shell
+caps: NET_ADMIN CAP_NET_RAW
+
+[lib64/*]
+mode: 0600
+
+[bin/]
+group: 1234
+`
+
+	overrides, err := ParseConfigFs(strings.NewReader(config), testAids(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ParseCanned(strings.NewReader(canned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(entries, overrides); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteCanned(buf, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `/ 1000 1000 0755
+/apex_manifest.json 1000 1000 0644
+/bin 0 1234 0755
+/bin/foo 1000 2000 0750 capabilities=0x3000
+/bin/bar 0 2000 0755
+/lib64/libfoo.so 1000 1000 0600
+`
+	if g := buf.String(); g != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, g)
+	}
+}
+
+func TestApplyUnmatched(t *testing.T) {
+	overrides, err := ParseConfigFs(strings.NewReader("[bin/missing]\nmode: 0755\n"), testAids(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ParseCanned(strings.NewReader("/bin/foo 0 2000 0755\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Apply(entries, overrides)
+	if g, w := errString(err), "line 1: [bin/missing] does not match any file"; g != w {
+		t.Errorf("expected error %q, got %q", w, g)
+	}
+}
+
+func TestParseConfigFsErrors(t *testing.T) {
+	testCases := []struct {
+		config string
+		err    string
+	}{
+		{"mode: 0755\n", `line 1: "mode: 0755" is outside of a section`},
+		{"[bin/foo\n", `line 1: invalid section "[bin/foo"`},
+		{"[bin/foo]\nmode 0755\n", `line 2: expected <key>: <value>, got "mode 0755"`},
+		{"[bin/foo]\nmode: 0999\n", `line 2: strconv.ParseUint: parsing "0999": invalid syntax`},
+		{"[bin/foo]\nuser: AID_UNKNOWN\n", `line 2: unknown user or group "AID_UNKNOWN"`},
+		{"[bin/foo]\ncaps: FLY\n", `line 2: unknown capability "FLY"`},
+		{"[bin/foo]\nowner: root\n", `line 2: unknown key "owner"`},
+	}
+
+	for _, tc := range testCases {
+		_, err := ParseConfigFs(strings.NewReader(tc.config), testAids(t))
+		if g := errString(err); g != tc.err {
+			t.Errorf("%q: expected error %q, got %q", tc.config, tc.err, g)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
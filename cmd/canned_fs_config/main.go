@@ -0,0 +1,100 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool changes the ownership, mode and capabilities of the files in a canned fs_config file,
+// as used by e2fsdroid, with the sections of a config.fs style file.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+var (
+	configFs  = flag.String("fs_config", "", "config.fs style file with the changes")
+	aidHeader = flag.String("aid_header", "", "android_filesystem_config.h defining the Android IDs")
+	output    = flag.String("o", "", "Output canned fs_config file")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -fs_config <config.fs> -aid_header <android_filesystem_config.h> "+
+			"-o <output> <canned fs_config>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *configFs == "" || *aidHeader == "" || *output == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(input string) error {
+	f, err := os.Open(*aidHeader)
+	if err != nil {
+		return err
+	}
+	aids, err := ReadAids(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", *aidHeader, err)
+	}
+
+	f, err = os.Open(*configFs)
+	if err != nil {
+		return err
+	}
+	overrides, err := ParseConfigFs(f, aids)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", *configFs, err)
+	}
+
+	f, err = os.Open(input)
+	if err != nil {
+		return err
+	}
+	entries, err := ParseCanned(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", input, err)
+	}
+
+	if err := Apply(entries, overrides); err != nil {
+		return fmt.Errorf("%s: %v", *configFs, err)
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	if err := WriteCanned(w, entries); err != nil {
+		out.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// indentWidth is the number of spaces lld indents the input sections by relative to the output
+// sections, and the symbols relative to the input sections.
+const indentWidth = 8
+
+// Contributor is the part of a linked ELF file that came from a single object file, which may be a
+// member of a static library.
+type Contributor struct {
+	Library  string `json:",omitempty"`
+	Object   string
+	Size     uint64
+	Sections map[string]uint64 `json:",omitempty"`
+}
+
+func (c Contributor) key() string {
+	return c.Library + "(" + c.Object + ")"
+}
+
+// ParseLinkMap reads a link map written by lld with -Map and returns the bytes contributed by each
+// input file to the allocated output sections, sorted by decreasing size.  Output sections at
+// address 0, like .comment or the debug sections, are not loaded and are skipped.
+func ParseLinkMap(r io.Reader) ([]Contributor, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	// The header names the columns, which are "VMA LMA Size Align Out In Symbol" in newer versions
+	// of lld and "Address Size Align Out In Symbol" in older ones.  The name after the numeric
+	// columns is indented by one level for input sections and by two for symbols.
+	header := scanner.Text()
+	fields := strings.Fields(header)
+	numeric, sizeColumn := -1, -1
+	for i, f := range fields {
+		switch f {
+		case "Size":
+			sizeColumn = i
+		case "Out":
+			numeric = i
+		}
+	}
+	if numeric < 0 || sizeColumn < 0 || sizeColumn >= numeric {
+		return nil, fmt.Errorf("unrecognized link map header %q", header)
+	}
+
+	byKey := make(map[string]*Contributor)
+	var outSection string
+	var loaded bool
+
+	for line := 2; scanner.Scan(); line++ {
+		text := scanner.Text()
+		fields := strings.Fields(text)
+		if len(fields) <= numeric {
+			continue
+		}
+
+		// Find how far the name is indented after the numeric columns to know which column it
+		// is in.
+		pos := 0
+		for i := 0; i < numeric; i++ {
+			pos += strings.Index(text[pos:], fields[i]) + len(fields[i])
+		}
+		name := strings.TrimLeft(text[pos:], " ")
+		indent := len(text[pos:]) - len(name)
+
+		if indent > 2*indentWidth {
+			continue
+		}
+
+		size, err := strconv.ParseUint(fields[sizeColumn], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid size %q", line, fields[sizeColumn])
+		}
+
+		if indent <= indentWidth {
+			outSection = name
+			address, err := strconv.ParseUint(fields[0], 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid address %q", line, fields[0])
+			}
+			loaded = address != 0
+			continue
+		}
+
+		if !loaded || size == 0 {
+			continue
+		}
+
+		library, object, ok := splitInputSection(name)
+		if !ok {
+			continue
+		}
+		c := byKey[library+"("+object+")"]
+		if c == nil {
+			c = &Contributor{Library: library, Object: object, Sections: make(map[string]uint64)}
+			byKey[c.key()] = c
+		}
+		c.Size += size
+		c.Sections[outSection] += size
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	ret := make([]Contributor, 0, len(byKey))
+	for _, c := range byKey {
+		ret = append(ret, *c)
+	}
+	sortContributors(ret)
+	return ret, nil
+}
+
+// splitInputSection splits an input section like "lib/libfoo.a(foo.o):(.text.foo)" into the
+// static library and the object file it came from.  Sections that were created by the linker
+// instead of coming from a file, like "<internal>:(.got)", are attributed to "<internal>".
+func splitInputSection(s string) (library, object string, ok bool) {
+	i := strings.LastIndex(s, ":(")
+	if i < 0 {
+		return "", "", false
+	}
+	file := s[:i]
+	if strings.HasSuffix(file, ")") {
+		if j := strings.LastIndex(file, "("); j > 0 {
+			return file[:j], file[j+1 : len(file)-1], true
+		}
+	}
+	return "", file, true
+}
+
+func sortContributors(list []Contributor) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Size != list[j].Size {
+			return list[i].Size > list[j].Size
+		}
+		return list[i].key() < list[j].key()
+	})
+}
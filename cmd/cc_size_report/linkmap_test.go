@@ -0,0 +1,113 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLinkMap(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		out  []Contributor
+	}{
+		{
+			name: "lld",
+			in: `             VMA              LMA     Size Align Out     In      Symbol
+             200             200       13     1 .interp
+             200             200       13     1         <internal>:(.interp)
+            1000            1000       60    16 .text
+            1000            1000       20    16         obj/foo.o:(.text)
+            1000            1000        0     1                 main
+            1020            1020       30    16         lib/libbar.a(bar.o):(.text.bar)
+            1020            1020       30     1                 bar
+            1050            1050       10    16         lib/libbar.a(bar.o):(.text.baz)
+            2000            2000        8     8 .data
+            2000            2000        8     8         obj/foo.o:(.data)
+               0               0       3f     1 .comment
+               0               0       3f     1         obj/foo.o:(.comment)
+`,
+			out: []Contributor{
+				{Library: "lib/libbar.a", Object: "bar.o", Size: 0x40, Sections: map[string]uint64{".text": 0x40}},
+				{Object: "obj/foo.o", Size: 0x28, Sections: map[string]uint64{".text": 0x20, ".data": 0x8}},
+				{Object: "<internal>", Size: 0x13, Sections: map[string]uint64{".interp": 0x13}},
+			},
+		},
+		{
+			name: "older lld",
+			in: `Address          Size             Align Out     In      Symbol
+0000000000001000 0000000000000030    16 .text
+0000000000001000 0000000000000010    16         obj/foo.o:(.text)
+0000000000001000 0000000000000000     0                 main
+0000000000001010 0000000000000020    16         lib/libbar.a(bar.o):(.text)
+`,
+			out: []Contributor{
+				{Library: "lib/libbar.a", Object: "bar.o", Size: 0x20, Sections: map[string]uint64{".text": 0x20}},
+				{Object: "obj/foo.o", Size: 0x10, Sections: map[string]uint64{".text": 0x10}},
+			},
+		},
+		{
+			name: "empty",
+			in:   "",
+			out:  nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			out, err := ParseLinkMap(strings.NewReader(testCase.in))
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if len(out) == 0 && len(testCase.out) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(out, testCase.out) {
+				t.Errorf("incorrect output:\nwant: %#v\n got: %#v", testCase.out, out)
+			}
+		})
+	}
+}
+
+func TestParseLinkMapBadHeader(t *testing.T) {
+	_, err := ParseLinkMap(strings.NewReader("Archive member included to satisfy reference by file (symbol)\n"))
+	if err == nil {
+		t.Errorf("expected an error for a map that was not written by lld")
+	}
+}
+
+func TestSplitInputSection(t *testing.T) {
+	testCases := []struct {
+		in              string
+		library, object string
+		ok              bool
+	}{
+		{"obj/foo.o:(.text)", "", "obj/foo.o", true},
+		{"lib/libfoo.a(foo.o):(.text.foo)", "lib/libfoo.a", "foo.o", true},
+		{"<internal>:(.got)", "", "<internal>", true},
+		{"main", "", "", false},
+	}
+
+	for _, testCase := range testCases {
+		library, object, ok := splitInputSection(testCase.in)
+		if library != testCase.library || object != testCase.object || ok != testCase.ok {
+			t.Errorf("splitInputSection(%q): want %q, %q, %v, got %q, %q, %v", testCase.in,
+				testCase.library, testCase.object, testCase.ok, library, object, ok)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool attributes the bytes of linked cc modules to the static libraries and object files
+// that contributed them, using the link maps written by lld.  For a single module it writes the
+// attribution as JSON.  With -merge, it combines the JSON of all modules into a single report,
+// optionally also written as a text table sorted by one of its columns.  With -diff, it compares a
+// report against an older one and lists the sizes that changed.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+var (
+	merge = flag.Bool("merge", false, "Merge the reports listed as arguments or in @file arguments")
+	diff  = flag.String("diff", "", "Compare the report passed as argument against this older report")
+
+	module  = flag.String("module", "", "Name of the module")
+	variant = flag.String("variant", "", "Variant of the module")
+	elfFile = flag.String("elf", "", "Installed ELF file of the module")
+	linkMap = flag.String("map", "", "Link map of the module")
+
+	output = flag.String("o", "", "Output JSON report, or text report with -diff")
+	table  = flag.String("table", "", "Output text table with -merge")
+	sortBy = flag.String("sort", "size", "Column to sort the table by: size, elf, library or object")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -module <name> [-variant <variant>] -elf <file> -map <file> -o <json>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -merge [-o <json>] [-table <txt> [-sort <column>]] <json>|@<list>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -diff <old json> -o <txt> <new json>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var err error
+	switch {
+	case *diff != "":
+		err = diffReports()
+	case *merge:
+		err = mergeReports()
+	default:
+		err = reportModule()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func reportModule() error {
+	if *module == "" || *elfFile == "" || *linkMap == "" || *output == "" {
+		return fmt.Errorf("-module, -elf, -map and -o are required")
+	}
+
+	f, err := os.Open(*linkMap)
+	if err != nil {
+		return err
+	}
+	contributors, err := ParseLinkMap(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", *linkMap, err)
+	}
+
+	info, err := os.Stat(*elfFile)
+	if err != nil {
+		return err
+	}
+
+	elf := Elf{
+		Module:       *module,
+		Variant:      *variant,
+		File:         *elfFile,
+		FileSize:     uint64(info.Size()),
+		Contributors: contributors,
+	}
+	return writeFile(*output, func(w io.Writer) error {
+		return WriteJSON(w, elf)
+	})
+}
+
+func mergeReports() error {
+	if *output == "" && *table == "" {
+		return fmt.Errorf("-o or -table is required with -merge")
+	}
+
+	var files []string
+	for _, arg := range flag.Args() {
+		if strings.HasPrefix(arg, "@") {
+			data, err := ioutil.ReadFile(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				return err
+			}
+			files = append(files, strings.Fields(string(data))...)
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	var reports []*Report
+	for _, file := range files {
+		report, err := readReportFile(file)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+	merged := Merge(reports)
+
+	if *output != "" {
+		err := writeFile(*output, func(w io.Writer) error {
+			return WriteJSON(w, merged)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *table != "" {
+		return writeFile(*table, func(w io.Writer) error {
+			return WriteTable(w, merged, *sortBy)
+		})
+	}
+	return nil
+}
+
+func diffReports() error {
+	if *output == "" || flag.NArg() != 1 {
+		return fmt.Errorf("-diff requires -o and a single new report")
+	}
+
+	old, err := readReportFile(*diff)
+	if err != nil {
+		return err
+	}
+	new, err := readReportFile(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return writeFile(*output, func(w io.Writer) error {
+		return WriteDiff(w, Diff(old, new))
+	})
+}
+
+func readReportFile(file string) (*Report, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	report, err := ReadReport(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return report, nil
+}
+
+func writeFile(file string, write func(io.Writer) error) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,245 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+// Elf is the size attribution of a single linked, and usually installed, ELF file.
+type Elf struct {
+	Module       string
+	Variant      string
+	File         string
+	FileSize     uint64
+	Contributors []Contributor
+}
+
+// Report is the size attribution of all the ELF files of a build.
+type Report struct {
+	Elfs []Elf
+}
+
+// ReadReport reads a report or a single Elf written as JSON.
+func ReadReport(r io.Reader) (*Report, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	if err := json.Unmarshal(raw, report); err != nil {
+		return nil, err
+	}
+	if report.Elfs == nil {
+		var elf Elf
+		if err := json.Unmarshal(raw, &elf); err != nil {
+			return nil, err
+		}
+		if elf.File != "" {
+			report.Elfs = []Elf{elf}
+		}
+	}
+	return report, nil
+}
+
+// WriteJSON writes v as indented JSON.
+func WriteJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+// Merge combines reports into a single report sorted by file.
+func Merge(reports []*Report) *Report {
+	ret := &Report{}
+	for _, r := range reports {
+		ret.Elfs = append(ret.Elfs, r.Elfs...)
+	}
+	sort.SliceStable(ret.Elfs, func(i, j int) bool {
+		return ret.Elfs[i].File < ret.Elfs[j].File
+	})
+	return ret
+}
+
+type row struct {
+	size    uint64
+	elf     string
+	library string
+	object  string
+}
+
+var sortKeys = map[string]func(a, b row) bool{
+	"size": func(a, b row) bool {
+		return a.size > b.size
+	},
+	"elf": func(a, b row) bool {
+		return a.elf < b.elf
+	},
+	"library": func(a, b row) bool {
+		return a.library < b.library
+	},
+	"object": func(a, b row) bool {
+		return a.object < b.object
+	},
+}
+
+// WriteTable writes a table with a row for each contributor to each ELF file, sorted by the
+// column named by sortBy: size, elf, library or object.  Ties are sorted by size.
+func WriteTable(w io.Writer, report *Report, sortBy string) error {
+	less, ok := sortKeys[sortBy]
+	if !ok {
+		return fmt.Errorf("unknown sort column %q", sortBy)
+	}
+
+	var rows []row
+	for _, elf := range report.Elfs {
+		for _, c := range elf.Contributors {
+			library := "-"
+			if c.Library != "" {
+				library = filepath.Base(c.Library)
+			}
+			rows = append(rows, row{
+				size:    c.Size,
+				elf:     elf.File,
+				library: library,
+				object:  filepath.Base(c.Object),
+			})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if less(rows[i], rows[j]) {
+			return true
+		} else if less(rows[j], rows[i]) {
+			return false
+		}
+		return sortKeys["size"](rows[i], rows[j])
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "SIZE\tELF\tLIBRARY\tOBJECT\n")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", r.size, r.elf, r.library, r.object)
+	}
+	return tw.Flush()
+}
+
+// Change is the difference in size of an ELF file, or of one of its contributors, between two
+// reports.
+type Change struct {
+	File     string
+	Library  string `json:",omitempty"`
+	Object   string `json:",omitempty"`
+	Old, New uint64
+}
+
+// Delta returns the number of bytes by which the size grew.
+func (c Change) Delta() int64 {
+	return int64(c.New) - int64(c.Old)
+}
+
+// Diff returns the ELF files and the contributors whose size differs between the old and the new
+// report, sorted by the decreasing magnitude of the change.  ELF files that were added or removed
+// are reported with an old or new size of 0.
+func Diff(old, new *Report) []Change {
+	type key struct{ file, library, object string }
+	sizes := make(map[key][2]uint64)
+	var keys []key
+
+	add := func(report *Report, i int) {
+		set := func(k key, size uint64) {
+			v, ok := sizes[k]
+			if !ok {
+				keys = append(keys, k)
+			}
+			v[i] += size
+			sizes[k] = v
+		}
+		for _, elf := range report.Elfs {
+			set(key{file: elf.File}, elf.FileSize)
+			for _, c := range elf.Contributors {
+				set(key{elf.File, c.Library, c.Object}, c.Size)
+			}
+		}
+	}
+	add(old, 0)
+	add(new, 1)
+
+	var changes []Change
+	for _, k := range keys {
+		v := sizes[k]
+		if v[0] != v[1] {
+			changes = append(changes, Change{
+				File:    k.file,
+				Library: k.library,
+				Object:  k.object,
+				Old:     v[0],
+				New:     v[1],
+			})
+		}
+	}
+
+	abs := func(i int64) int64 {
+		if i < 0 {
+			return -i
+		}
+		return i
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := abs(changes[i].Delta()), abs(changes[j].Delta())
+		if a != b {
+			return a > b
+		}
+		if changes[i].File != changes[j].File {
+			return changes[i].File < changes[j].File
+		}
+		return changes[i].Library+changes[i].Object < changes[j].Library+changes[j].Object
+	})
+	return changes
+}
+
+// WriteDiff writes a table of changes, with the total change of the ELF files first.
+func WriteDiff(w io.Writer, changes []Change) error {
+	var total int64
+	for _, c := range changes {
+		if c.Object == "" {
+			total += c.Delta()
+		}
+	}
+	fmt.Fprintf(w, "Total change in ELF file sizes: %+d bytes\n\n", total)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "DELTA\tOLD\tNEW\tELF\tLIBRARY\tOBJECT\n")
+	for _, c := range changes {
+		library, object := "-", "(file size)"
+		if c.Object != "" {
+			object = filepath.Base(c.Object)
+			if c.Library != "" {
+				library = filepath.Base(c.Library)
+			}
+		}
+		fmt.Fprintf(tw, "%+d\t%d\t%d\t%s\t%s\t%s\n", c.Delta(), c.Old, c.New, c.File, library, object)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,148 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	fooElf = Elf{
+		Module:   "foo",
+		File:     "system/bin/foo",
+		FileSize: 1000,
+		Contributors: []Contributor{
+			{Library: "out/libbar.a", Object: "bar.o", Size: 300},
+			{Object: "out/foo.o", Size: 200},
+		},
+	}
+	bazElf = Elf{
+		Module:   "libbaz",
+		File:     "system/lib64/libbaz.so",
+		FileSize: 500,
+		Contributors: []Contributor{
+			{Object: "out/baz.o", Size: 400},
+		},
+	}
+)
+
+func TestReadReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, fooElf); err != nil {
+		t.Fatal(err)
+	}
+	report, err := ReadReport(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if want := []Elf{fooElf}; !reflect.DeepEqual(report.Elfs, want) {
+		t.Errorf("reading a single ELF:\nwant: %#v\n got: %#v", want, report.Elfs)
+	}
+
+	merged := Merge([]*Report{{Elfs: []Elf{fooElf}}, {Elfs: []Elf{bazElf}}})
+	buf.Reset()
+	if err := WriteJSON(&buf, merged); err != nil {
+		t.Fatal(err)
+	}
+	report, err = ReadReport(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if want := []Elf{fooElf, bazElf}; !reflect.DeepEqual(report.Elfs, want) {
+		t.Errorf("reading a merged report:\nwant: %#v\n got: %#v", want, report.Elfs)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	report := Merge([]*Report{{Elfs: []Elf{fooElf, bazElf}}})
+
+	testCases := []struct {
+		sortBy string
+		rows   []string
+	}{
+		{
+			sortBy: "size",
+			rows:   []string{"400", "300", "200"},
+		},
+		{
+			sortBy: "library",
+			rows:   []string{"400", "200", "300"},
+		},
+		{
+			sortBy: "elf",
+			rows:   []string{"300", "200", "400"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.sortBy, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTable(&buf, report, testCase.sortBy); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 4 || !strings.HasPrefix(lines[0], "SIZE") {
+				t.Fatalf("unexpected table:\n%s", buf.String())
+			}
+			var sizes []string
+			for _, line := range lines[1:] {
+				sizes = append(sizes, strings.Fields(line)[0])
+			}
+			if !reflect.DeepEqual(sizes, testCase.rows) {
+				t.Errorf("want sizes %q, got %q in:\n%s", testCase.rows, sizes, buf.String())
+			}
+		})
+	}
+
+	if err := WriteTable(&bytes.Buffer{}, report, "bogus"); err == nil {
+		t.Errorf("expected an error for an unknown sort column")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &Report{Elfs: []Elf{fooElf, bazElf}}
+
+	newFoo := fooElf
+	newFoo.FileSize = 1100
+	newFoo.Contributors = []Contributor{
+		{Library: "out/libbar.a", Object: "bar.o", Size: 400},
+		{Object: "out/foo.o", Size: 200},
+		{Library: "out/libqux.a", Object: "qux.o", Size: 50},
+	}
+	new := &Report{Elfs: []Elf{newFoo}}
+
+	want := []Change{
+		{File: "system/lib64/libbaz.so", Old: 500, New: 0},
+		{File: "system/lib64/libbaz.so", Object: "out/baz.o", Old: 400, New: 0},
+		{File: "system/bin/foo", Old: 1000, New: 1100},
+		{File: "system/bin/foo", Library: "out/libbar.a", Object: "bar.o", Old: 300, New: 400},
+		{File: "system/bin/foo", Library: "out/libqux.a", Object: "qux.o", Old: 0, New: 50},
+	}
+	changes := Diff(old, new)
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("incorrect diff:\nwant: %#v\n got: %#v", want, changes)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, changes); err != nil {
+		t.Fatal(err)
+	}
+	if w := "Total change in ELF file sizes: -400 bytes"; !strings.HasPrefix(buf.String(), w) {
+		t.Errorf("expected diff to start with %q, got:\n%s", w, buf.String())
+	}
+}
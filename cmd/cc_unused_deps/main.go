@@ -0,0 +1,253 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool reports the shared_libs, static_libs, whole_static_libs and header_libs entries of a
+// cc module that contribute no symbols and no headers to it.  A shared library contributes symbols
+// if it defines one of the undefined dynamic symbols of the module, and a static library if the link
+// map lists one of its members.  Headers are found in the saved depfiles of the module's objects, so
+// modules that are not linked, like static libraries, are checked without -elf and -map and only
+// their header_libs are passed.  With -merge, it combines the reports of all variants of all modules into a list of the
+// dependencies that were unused in every variant that listed them.
+package main
+
+import (
+	"bufio"
+	"debug/elf"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+type multiString []string
+
+func (ms *multiString) String() string {
+	return strings.Join(*ms, ", ")
+}
+
+func (ms *multiString) Set(s string) error {
+	*ms = append(*ms, s)
+	return nil
+}
+
+var (
+	merge = flag.Bool("merge", false, "Merge the reports listed as arguments or in @file arguments")
+
+	module    = flag.String("module", "", "Name of the module")
+	blueprint = flag.String("bp", "", "Android.bp file that defines the module")
+	elfFile   = flag.String("elf", "", "Linked output of the module")
+	linkMap   = flag.String("map", "", "Link map of the module")
+	output    = flag.String("o", "", "Output report")
+
+	depFiles    multiString
+	deps        multiString
+	includeDirs multiString
+)
+
+func init() {
+	flag.Var(&depFiles, "deps", "Saved depfile of an object of the module")
+	flag.Var(&deps, "dep", "Dependency to check as <property>:<name>[:<file>]")
+	flag.Var(&includeDirs, "include", "Include directory exported by a dependency as <name>:<dir>")
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -module <name> -bp <Android.bp> [-elf <file>] [-map <file>] "+
+			"[-deps <file>]... [-dep <property>:<name>[:<file>]]... [-include <name>:<dir>]... -o <report>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -merge -o <report> <report>|@<list>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var entries []Entry
+	var err error
+	if *merge {
+		entries, err = mergeReports(flag.Args())
+	} else {
+		entries, err = checkModule()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	if err := WriteEntries(w, entries, !*merge); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func checkModule() ([]Entry, error) {
+	if *module == "" {
+		return nil, fmt.Errorf("-module is required")
+	}
+
+	var usage Usage
+
+	if *elfFile != "" {
+		f, err := elf.Open(*elfFile)
+		if err != nil {
+			return nil, err
+		}
+		usage.Undefined, err = UndefinedSymbols(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", *elfFile, err)
+		}
+	}
+
+	if *linkMap != "" {
+		data, err := ioutil.ReadFile(*linkMap)
+		if err != nil {
+			return nil, err
+		}
+		usage.LinkMap = string(data)
+	}
+
+	for _, depFile := range depFiles {
+		r, err := os.Open(depFile)
+		if err != nil {
+			return nil, err
+		}
+		headers, err := ReadDeps(depFile, r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		usage.Headers = append(usage.Headers, headers...)
+	}
+
+	var list []*Dep
+	byName := make(map[string]*Dep)
+	for _, arg := range deps {
+		parts := strings.SplitN(arg, ":", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("malformed -dep %q", arg)
+		}
+		key := parts[0] + ":" + parts[1]
+		dep := byName[key]
+		if dep == nil {
+			dep = &Dep{Property: parts[0], Name: parts[1]}
+			byName[key] = dep
+			list = append(list, dep)
+		}
+		if len(parts) == 3 && parts[2] != "" {
+			dep.Files = append(dep.Files, parts[2])
+		}
+	}
+	for _, arg := range includeDirs {
+		parts := strings.SplitN(arg, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed -include %q", arg)
+		}
+		for _, dep := range list {
+			if dep.Name == parts[0] {
+				dep.IncludeDirs = append(dep.IncludeDirs, parts[1])
+			}
+		}
+	}
+
+	var entries []Entry
+	for _, dep := range list {
+		used, err := isUsed(usage, dep)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Blueprint: *blueprint,
+			Module:    *module,
+			Property:  dep.Property,
+			Dep:       dep.Name,
+			Used:      used,
+		})
+	}
+	return entries, nil
+}
+
+func isUsed(usage Usage, dep *Dep) (bool, error) {
+	if usage.UsesHeaders(dep.IncludeDirs) {
+		return true, nil
+	}
+
+	for _, file := range dep.Files {
+		switch dep.Property {
+		case "shared_libs":
+			f, err := elf.Open(file)
+			if err != nil {
+				return false, err
+			}
+			defined, err := DefinedSymbols(f)
+			f.Close()
+			if err != nil {
+				return false, fmt.Errorf("%s: %v", file, err)
+			}
+			if usage.UsesSymbols(defined) {
+				return true, nil
+			}
+		case "static_libs", "whole_static_libs":
+			if usage.UsesArchive(file) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func mergeReports(args []string) ([]Entry, error) {
+	var reports []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") {
+			data, err := ioutil.ReadFile(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				return nil, err
+			}
+			reports = append(reports, strings.Fields(string(data))...)
+		} else {
+			reports = append(reports, arg)
+		}
+	}
+
+	var entries []Entry
+	for _, report := range reports {
+		f, err := os.Open(report)
+		if err != nil {
+			return nil, err
+		}
+		e, err := ReadEntries(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", report, err)
+		}
+		entries = append(entries, e...)
+	}
+
+	return MergeEntries(entries), nil
+}
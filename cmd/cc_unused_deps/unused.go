@@ -0,0 +1,230 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"debug/elf"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/androidmk/parser"
+)
+
+// Dep is a dependency listed in one of the properties of a module.
+type Dep struct {
+	Property    string   // shared_libs, static_libs, whole_static_libs or header_libs
+	Name        string   // Name of the dependency as listed in the property
+	Files       []string // The .so or .a files of the dependency that were linked
+	IncludeDirs []string // The include directories exported by the dependency
+}
+
+// Usage is what a linked module used from its dependencies.
+type Usage struct {
+	Undefined map[string]bool // The undefined dynamic symbols of the linked module
+	LinkMap   string          // The link map written by the linker
+	Headers   []string        // The headers listed in the depfiles of the module's objects
+}
+
+// UndefinedSymbols returns the undefined dynamic symbols of an ELF file.
+func UndefinedSymbols(f *elf.File) (map[string]bool, error) {
+	syms, err := f.DynamicSymbols()
+	if err != nil && err != elf.ErrNoSymbols {
+		return nil, err
+	}
+	ret := make(map[string]bool)
+	for _, sym := range syms {
+		if sym.Section == elf.SHN_UNDEF && sym.Name != "" {
+			ret[sym.Name] = true
+		}
+	}
+	return ret, nil
+}
+
+// DefinedSymbols returns the global and weak dynamic symbols defined by an ELF file.
+func DefinedSymbols(f *elf.File) ([]string, error) {
+	syms, err := f.DynamicSymbols()
+	if err != nil && err != elf.ErrNoSymbols {
+		return nil, err
+	}
+	var ret []string
+	for _, sym := range syms {
+		bind := elf.ST_BIND(sym.Info)
+		if sym.Section != elf.SHN_UNDEF && (bind == elf.STB_GLOBAL || bind == elf.STB_WEAK) {
+			ret = append(ret, sym.Name)
+		}
+	}
+	return ret, nil
+}
+
+// UsesSymbols returns true if any of the symbols defined by a shared library is undefined in the
+// linked module.
+func (u Usage) UsesSymbols(defined []string) bool {
+	for _, sym := range defined {
+		if u.Undefined[sym] {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesArchive returns true if the link map lists a member of the static library as an input, which
+// means that at least one section of the member was kept in the linked module.
+func (u Usage) UsesArchive(archive string) bool {
+	for _, line := range strings.Split(u.LinkMap, "\n") {
+		for _, field := range strings.Fields(line) {
+			if strings.HasPrefix(field, archive+"(") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UsesHeaders returns true if any of the headers is inside one of the include directories.
+func (u Usage) UsesHeaders(dirs []string) bool {
+	for _, header := range u.Headers {
+		header = filepath.Clean(header)
+		for _, dir := range dirs {
+			dir = filepath.Clean(dir)
+			if dir == "." || strings.HasPrefix(header, dir+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadDeps returns the headers listed in a make-style dependency file written by the compiler,
+// skipping the first prerequisite which is the source file.
+func ReadDeps(filename string, r io.Reader) ([]string, error) {
+	p := parser.NewParser(filename, r)
+	nodes, errs := p.Parse()
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	} else if len(errs) > 1 {
+		return nil, fmt.Errorf("many errors: %v", errs)
+	}
+
+	var ret []string
+	for _, node := range nodes {
+		switch x := node.(type) {
+		case *parser.Comment:
+			// Do nothing
+		case *parser.Rule:
+			if !x.Prerequisites.Const() {
+				return nil, fmt.Errorf("%s: unsupported variable expansion: %v",
+					p.Unpack(node.Pos()), x.Prerequisites.Dump())
+			}
+			for i, input := range x.Prerequisites.Words() {
+				if i > 0 {
+					ret = append(ret, input.Value(nil))
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s: unexpected line: %#v", p.Unpack(node.Pos()), node)
+		}
+	}
+
+	return ret, nil
+}
+
+// Entry is a line of a report, recording whether one variant of a module used a dependency.
+type Entry struct {
+	Blueprint string
+	Module    string
+	Property  string
+	Dep       string
+	Used      bool
+}
+
+func (e Entry) key() string {
+	return strings.Join([]string{e.Blueprint, e.Module, e.Property, e.Dep}, "\t")
+}
+
+// WriteEntries writes entries as tab separated lines of the Android.bp file, module, property,
+// dependency and, if withUsed is set, "used" or "unused".
+func WriteEntries(w io.Writer, entries []Entry, withUsed bool) error {
+	for _, e := range entries {
+		line := e.key()
+		if withUsed {
+			if e.Used {
+				line += "\tused"
+			} else {
+				line += "\tunused"
+			}
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadEntries reads entries written by WriteEntries.
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	var ret []Entry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 && len(fields) != 5 {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		e := Entry{
+			Blueprint: fields[0],
+			Module:    fields[1],
+			Property:  fields[2],
+			Dep:       fields[3],
+		}
+		if len(fields) == 5 {
+			e.Used = fields[4] == "used"
+		}
+		ret = append(ret, e)
+	}
+	return ret, scanner.Err()
+}
+
+// MergeEntries returns the dependencies that were unused in every variant of a module that listed
+// them, sorted by Android.bp file, module, property and dependency.
+func MergeEntries(entries []Entry) []Entry {
+	unused := make(map[string]Entry)
+	used := make(map[string]bool)
+	for _, e := range entries {
+		if e.Used {
+			used[e.key()] = true
+		} else {
+			unused[e.key()] = e
+		}
+	}
+
+	var ret []Entry
+	for key, e := range unused {
+		if !used[key] {
+			ret = append(ret, e)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].key() < ret[j].key()
+	})
+	return ret
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUsesArchive(t *testing.T) {
+	usage := Usage{
+		LinkMap: `             VMA              LMA     Size Align Out     In      Symbol
+             2a0              2a0       13     1 .interp
+             2a0              2a0       13     1         <internal>:(.interp)
+            1000             1000       20    16 .text
+            1000             1000       10    16         out/obj/foo.o:(.text)
+            1010             1010       10    16         out/libbar.a(bar.o):(.text.bar)
+`,
+	}
+
+	if !usage.UsesArchive("out/libbar.a") {
+		t.Errorf("expected out/libbar.a to be used")
+	}
+	if usage.UsesArchive("out/libbaz.a") {
+		t.Errorf("expected out/libbaz.a to be unused")
+	}
+	if usage.UsesArchive("libbar.a") {
+		t.Errorf("expected libbar.a to only match the full path")
+	}
+}
+
+func TestUsesSymbols(t *testing.T) {
+	usage := Usage{Undefined: map[string]bool{"malloc": true, "bar_init": true}}
+
+	if !usage.UsesSymbols([]string{"bar_close", "bar_init"}) {
+		t.Errorf("expected bar_init to be used")
+	}
+	if usage.UsesSymbols([]string{"baz_init"}) {
+		t.Errorf("expected baz_init to be unused")
+	}
+}
+
+func TestUsesHeaders(t *testing.T) {
+	headers, err := ReadDeps("foo.o.d", strings.NewReader(
+		"out/obj/foo.o: foo.c \\\n  external/bar/include/bar.h \\\n  external/bar/include/../src/bar_impl.h\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := Usage{Headers: headers}
+
+	testCases := []struct {
+		dirs []string
+		used bool
+	}{
+		{dirs: []string{"external/bar/include"}, used: true},
+		{dirs: []string{"external/bar/src"}, used: true},
+		{dirs: []string{"external/bar/inc"}, used: false},
+		{dirs: []string{"external/baz/include"}, used: false},
+		{dirs: nil, used: false},
+	}
+
+	for _, tc := range testCases {
+		if g := usage.UsesHeaders(tc.dirs); g != tc.used {
+			t.Errorf("UsesHeaders(%q): want %v, got %v", tc.dirs, tc.used, g)
+		}
+	}
+}
+
+func TestMergeEntries(t *testing.T) {
+	report := `a/Android.bp	libfoo	shared_libs	libbar	unused
+a/Android.bp	libfoo	shared_libs	libbaz	unused
+a/Android.bp	libfoo	header_libs	libhdr	unused
+a/Android.bp	libfoo	shared_libs	libbar	used
+a/Android.bp	libfoo	shared_libs	libbaz	unused
+b/Android.bp	bin	static_libs	libqux	unused
+`
+	entries, err := ReadEntries(strings.NewReader(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteEntries(buf, MergeEntries(entries), false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"a/Android.bp	libfoo	header_libs	libhdr",
+		"a/Android.bp	libfoo	shared_libs	libbaz",
+		"b/Android.bp	bin	static_libs	libqux",
+	}
+	if g := strings.Split(strings.TrimSpace(buf.String()), "\n"); !reflect.DeepEqual(g, want) {
+		t.Errorf("want %q, got %q", want, g)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	licenseSection = "license"
+	mapsSection    = "maps"
+
+	// mapDefSize is the size of struct bpf_map_def in bpf_helpers.h: the type, key_size,
+	// value_size, max_entries and map_flags fields followed by two words of padding.
+	mapDefSize = 7 * 4
+
+	// maxMapType is the highest map type known to the loader, BPF_MAP_TYPE_SOCKHASH.
+	maxMapType = 18
+
+	// rBpf64_64 is the R_BPF_64_64 relocation of a 64-bit immediate load.
+	rBpf64_64 = 1
+
+	// opLdImm64 is the opcode of the BPF_LD | BPF_IMM | BPF_DW instruction that loads a map fd.
+	opLdImm64 = 0x18
+)
+
+// programPrefixes are the section name prefixes that the loader maps to program types.
+var programPrefixes = []string{
+	"kprobe/",
+	"tracepoint/",
+	"skfilter/",
+	"cgroupskb/",
+	"schedcls/",
+	"cgroupsock/",
+}
+
+// MapDef is a map definition from the maps section.
+type MapDef struct {
+	Type       uint32
+	KeySize    uint32
+	ValueSize  uint32
+	MaxEntries uint32
+	MapFlags   uint32
+	Pad        [2]uint32
+}
+
+// Check returns the problems in a BPF object that would make the loader reject it at boot.
+func Check(f *elf.File) []error {
+	var errs []error
+	errorf := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	if f.Machine != elf.EM_BPF {
+		errorf("not a BPF object, machine is %s", f.Machine)
+		return errs
+	}
+
+	if license := f.Section(licenseSection); license == nil {
+		errorf("missing %q section", licenseSection)
+	} else if data, err := license.Data(); err != nil {
+		errorf("section %q: %s", licenseSection, err)
+	} else if i := bytes.IndexByte(data, 0); i <= 0 {
+		errorf("section %q must hold a non-empty NUL terminated string", licenseSection)
+	}
+
+	mapsIndex := -1
+	for i, s := range f.Sections {
+		if s.Name == mapsSection {
+			mapsIndex = i
+		}
+		if s.Flags&elf.SHF_EXECINSTR == 0 || s.Size == 0 {
+			continue
+		}
+		if !isProgramSection(s.Name) {
+			errorf("section %q: programs must be in a section named <type>/<name> with a type prefix of %s",
+				s.Name, strings.Join(programPrefixes, ", "))
+		}
+	}
+
+	syms, err := f.Symbols()
+	if err != nil && err != elf.ErrNoSymbols {
+		errorf("failed to read symbols: %s", err)
+		return errs
+	}
+
+	if mapsIndex >= 0 {
+		errs = append(errs, checkMaps(f, f.Sections[mapsIndex], elf.SectionIndex(mapsIndex), syms)...)
+	}
+
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_REL && s.Type != elf.SHT_RELA {
+			continue
+		}
+		if int(s.Info) >= len(f.Sections) {
+			errorf("section %q: invalid target section %d", s.Name, s.Info)
+			continue
+		}
+		target := f.Sections[s.Info]
+		// Relocations of the debug info are not applied by the loader.
+		if target.Flags&elf.SHF_EXECINSTR == 0 {
+			continue
+		}
+		if s.Type == elf.SHT_RELA {
+			errorf("section %q: RELA relocations are not supported", s.Name)
+			continue
+		}
+		errs = append(errs, checkRelocations(f, s, target, elf.SectionIndex(mapsIndex), syms)...)
+	}
+
+	return errs
+}
+
+func isProgramSection(name string) bool {
+	for _, prefix := range programPrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkMaps(f *elf.File, maps *elf.Section, index elf.SectionIndex, syms []elf.Symbol) []error {
+	var errs []error
+	errorf := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	data, err := maps.Data()
+	if err != nil {
+		errorf("section %q: %s", mapsSection, err)
+		return errs
+	}
+	if len(data)%mapDefSize != 0 {
+		errorf("section %q: size %d is not a multiple of the %d bytes of struct bpf_map_def",
+			mapsSection, len(data), mapDefSize)
+		return errs
+	}
+
+	for _, sym := range syms {
+		if sym.Section != index || elf.ST_TYPE(sym.Info) == elf.STT_SECTION {
+			continue
+		}
+		if sym.Value%mapDefSize != 0 || (sym.Size != 0 && sym.Size != mapDefSize) {
+			errorf("map %q: expected a struct bpf_map_def of %d bytes, got %d bytes at offset %d",
+				sym.Name, mapDefSize, sym.Size, sym.Value)
+			continue
+		}
+		if sym.Value+mapDefSize > uint64(len(data)) {
+			errorf("map %q: offset %d is outside of section %q", sym.Name, sym.Value, mapsSection)
+			continue
+		}
+
+		var def MapDef
+		err := binary.Read(bytes.NewReader(data[sym.Value:sym.Value+mapDefSize]), f.ByteOrder, &def)
+		if err != nil {
+			errorf("map %q: %s", sym.Name, err)
+			continue
+		}
+		switch {
+		case def.Type == 0 || def.Type > maxMapType:
+			errorf("map %q: unknown map type %d", sym.Name, def.Type)
+		case def.KeySize == 0:
+			errorf("map %q: key_size must not be 0", sym.Name)
+		case def.ValueSize == 0:
+			errorf("map %q: value_size must not be 0", sym.Name)
+		case def.MaxEntries == 0:
+			errorf("map %q: max_entries must not be 0", sym.Name)
+		}
+	}
+
+	return errs
+}
+
+func checkRelocations(f *elf.File, rel, target *elf.Section, mapsIndex elf.SectionIndex,
+	syms []elf.Symbol) []error {
+
+	var errs []error
+	errorf := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	data, err := rel.Data()
+	if err != nil {
+		errorf("section %q: %s", rel.Name, err)
+		return errs
+	}
+	code, err := target.Data()
+	if err != nil {
+		errorf("section %q: %s", target.Name, err)
+		return errs
+	}
+
+	if len(data)%binary.Size(elf.Rel64{}) != 0 {
+		errorf("section %q: invalid size %d", rel.Name, len(data))
+		return errs
+	}
+	rels := make([]elf.Rel64, len(data)/binary.Size(elf.Rel64{}))
+	if err := binary.Read(bytes.NewReader(data), f.ByteOrder, rels); err != nil {
+		errorf("section %q: %s", rel.Name, err)
+		return errs
+	}
+
+	for _, r := range rels {
+		symIndex := elf.R_SYM64(r.Info)
+		if symIndex == 0 || int(symIndex) > len(syms) {
+			errorf("section %q: relocation at offset %d has invalid symbol %d", target.Name, r.Off, symIndex)
+			continue
+		}
+		// debug/elf skips the null symbol at index 0.
+		sym := syms[symIndex-1]
+
+		if t := elf.R_TYPE64(r.Info); t != rBpf64_64 {
+			errorf("section %q: relocation of %q at offset %d has unsupported type %d",
+				target.Name, sym.Name, r.Off, t)
+			continue
+		}
+		if mapsIndex < 0 || sym.Section != mapsIndex {
+			errorf("section %q: relocation at offset %d refers to %q, only maps in section %q can be referenced",
+				target.Name, r.Off, sym.Name, mapsSection)
+			continue
+		}
+		if r.Off%8 != 0 || r.Off+16 > uint64(len(code)) || code[r.Off] != opLdImm64 {
+			errorf("section %q: relocation of %q at offset %d is not on a 64-bit immediate load",
+				target.Name, sym.Name, r.Off)
+		}
+	}
+
+	return errs
+}
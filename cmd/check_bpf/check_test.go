@@ -0,0 +1,213 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type testSection struct {
+	name  string
+	typ   elf.SectionType
+	flags elf.SectionFlag
+	data  []byte
+	info  uint32
+}
+
+type testSymbol struct {
+	name    string
+	section elf.SectionIndex
+	value   uint64
+	size    uint64
+}
+
+// buildElf returns an ELF relocatable file with the given sections, which are numbered from 1, and
+// a symbol table with the given symbols, which are numbered from 1.
+func buildElf(t *testing.T, machine elf.Machine, sections []testSection, symbols []testSymbol) *elf.File {
+	t.Helper()
+	le := binary.LittleEndian
+
+	strtab := []byte{0}
+	symtab := &bytes.Buffer{}
+	binary.Write(symtab, le, elf.Sym64{})
+	for _, s := range symbols {
+		binary.Write(symtab, le, elf.Sym64{
+			Name:  uint32(len(strtab)),
+			Info:  elf.ST_INFO(elf.STB_GLOBAL, elf.STT_OBJECT),
+			Shndx: uint16(s.section),
+			Value: s.value,
+			Size:  s.size,
+		})
+		strtab = append(strtab, append([]byte(s.name), 0)...)
+	}
+
+	symtabIndex := len(sections) + 1
+	sections = append(sections,
+		testSection{name: ".symtab", typ: elf.SHT_SYMTAB, data: symtab.Bytes()},
+		testSection{name: ".strtab", typ: elf.SHT_STRTAB, data: strtab},
+		testSection{name: ".shstrtab", typ: elf.SHT_STRTAB})
+
+	shstrtab := []byte{0}
+	names := make([]uint32, len(sections))
+	for i, s := range sections {
+		names[i] = uint32(len(shstrtab))
+		shstrtab = append(shstrtab, append([]byte(s.name), 0)...)
+	}
+	sections[len(sections)-1].data = shstrtab
+
+	buf := &bytes.Buffer{}
+	buf.Write(make([]byte, binary.Size(elf.Header64{})))
+	headers := []elf.Section64{{}}
+	for i, s := range sections {
+		for buf.Len()%8 != 0 {
+			buf.WriteByte(0)
+		}
+		h := elf.Section64{
+			Name:      names[i],
+			Type:      uint32(s.typ),
+			Flags:     uint64(s.flags),
+			Off:       uint64(buf.Len()),
+			Size:      uint64(len(s.data)),
+			Info:      s.info,
+			Addralign: 8,
+		}
+		switch s.typ {
+		case elf.SHT_SYMTAB:
+			h.Link = uint32(symtabIndex + 1)
+			h.Info = 1
+			h.Entsize = uint64(binary.Size(elf.Sym64{}))
+		case elf.SHT_REL:
+			h.Link = uint32(symtabIndex)
+			h.Entsize = uint64(binary.Size(elf.Rel64{}))
+		}
+		buf.Write(s.data)
+		headers = append(headers, h)
+	}
+	for buf.Len()%8 != 0 {
+		buf.WriteByte(0)
+	}
+	shoff := buf.Len()
+	binary.Write(buf, le, headers)
+
+	data := buf.Bytes()
+	header := elf.Header64{
+		Type:      uint16(elf.ET_REL),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Shoff:     uint64(shoff),
+		Ehsize:    uint16(binary.Size(elf.Header64{})),
+		Shentsize: uint16(binary.Size(elf.Section64{})),
+		Shnum:     uint16(len(headers)),
+		Shstrndx:  uint16(len(headers) - 1),
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	hbuf := &bytes.Buffer{}
+	binary.Write(hbuf, le, header)
+	copy(data, hbuf.Bytes())
+
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to build the ELF file: %s", err)
+	}
+	return f
+}
+
+func mapDef(def MapDef) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, def)
+	return buf.Bytes()
+}
+
+func rel(off uint64, sym uint32, typ uint32) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, elf.Rel64{Off: off, Info: elf.R_INFO(sym, typ)})
+	return buf.Bytes()
+}
+
+func errorStrings(errs []error) []string {
+	var ret []string
+	for _, err := range errs {
+		ret = append(ret, err.Error())
+	}
+	return ret
+}
+
+// ldImm64 is a 64-bit immediate load into r1 followed by an exit instruction.
+var ldImm64 = []byte{
+	opLdImm64, 0x01, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0x95, 0, 0, 0, 0, 0, 0, 0,
+}
+
+func TestCheckValid(t *testing.T) {
+	f := buildElf(t, elf.EM_BPF, []testSection{
+		{name: "license", typ: elf.SHT_PROGBITS, data: []byte("GPL\x00")},
+		{name: "maps", typ: elf.SHT_PROGBITS, data: mapDef(MapDef{Type: 1, KeySize: 4, ValueSize: 8, MaxEntries: 16})},
+		{name: "kprobe/foo", typ: elf.SHT_PROGBITS, flags: elf.SHF_EXECINSTR, data: ldImm64},
+		{name: ".relkprobe/foo", typ: elf.SHT_REL, info: 3, data: rel(0, 1, rBpf64_64)},
+	}, []testSymbol{
+		{name: "my_map", section: 2, size: mapDefSize},
+	})
+
+	if errs := Check(f); len(errs) > 0 {
+		t.Errorf("expected no errors, got %q", errorStrings(errs))
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	f := buildElf(t, elf.EM_BPF, []testSection{
+		{name: "maps", typ: elf.SHT_PROGBITS, data: append(
+			mapDef(MapDef{Type: 1, KeySize: 4, ValueSize: 8, MaxEntries: 0}),
+			mapDef(MapDef{Type: 99, KeySize: 4, ValueSize: 8, MaxEntries: 16})...)},
+		{name: "kprobe/foo", typ: elf.SHT_PROGBITS, flags: elf.SHF_EXECINSTR, data: ldImm64},
+		{name: ".relkprobe/foo", typ: elf.SHT_REL, info: 2, data: append(append(
+			rel(0, 3, rBpf64_64),
+			rel(16, 1, rBpf64_64)...),
+			rel(0, 1, 10)...)},
+		{name: ".text", typ: elf.SHT_PROGBITS, flags: elf.SHF_EXECINSTR, data: ldImm64},
+		{name: ".bss", typ: elf.SHT_NOBITS},
+	}, []testSymbol{
+		{name: "empty_map", section: 1, size: mapDefSize},
+		{name: "unknown_map", section: 1, value: mapDefSize, size: mapDefSize},
+		{name: "counter", section: 5, size: 8},
+	})
+
+	want := []string{
+		`missing "license" section`,
+		`section ".text": programs must be in a section named <type>/<name> with a type prefix of kprobe/, tracepoint/, skfilter/, cgroupskb/, schedcls/, cgroupsock/`,
+		`map "empty_map": max_entries must not be 0`,
+		`map "unknown_map": unknown map type 99`,
+		`section "kprobe/foo": relocation at offset 0 refers to "counter", only maps in section "maps" can be referenced`,
+		`section "kprobe/foo": relocation of "empty_map" at offset 16 is not on a 64-bit immediate load`,
+		`section "kprobe/foo": relocation of "empty_map" at offset 0 has unsupported type 10`,
+	}
+	if g := errorStrings(Check(f)); !reflect.DeepEqual(g, want) {
+		t.Errorf("expected errors:\n%q\ngot:\n%q", want, g)
+	}
+}
+
+func TestCheckNotBpf(t *testing.T) {
+	f := buildElf(t, elf.EM_X86_64, nil, nil)
+	if g, w := errorStrings(Check(f)), []string{"not a BPF object, machine is EM_X86_64"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected %q, got %q", w, g)
+	}
+}
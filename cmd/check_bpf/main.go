@@ -0,0 +1,66 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool verifies that a compiled BPF object can be loaded by the bpfloader: it must have a
+// license section, its programs must be in sections named after a known program type, its maps
+// must be struct bpf_map_def entries in the maps section, and its code may only be relocated to
+// load the maps.  If the object is valid it is copied to the output file.
+package main
+
+import (
+	"debug/elf"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+var output = flag.String("o", "", "Output file, a copy of the input if it is valid")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -o <output> <bpf object>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	input := flag.Arg(0)
+
+	f, err := elf.Open(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	errs := Check(f)
+	f.Close()
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", input, err)
+		}
+		os.Exit(1)
+	}
+
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*output, data, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
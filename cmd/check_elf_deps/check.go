@@ -0,0 +1,256 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"debug/elf"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxListedSymbols is the number of undefined symbols listed in a violation before the rest are
+// only counted.
+const maxListedSymbols = 20
+
+// Entry describes an installed ELF file of a module.
+type Entry struct {
+	Module    string
+	Variant   string
+	Blueprint string
+
+	// File is the built ELF file that is read, and Install the path it is installed to.  File is
+	// empty for the libraries installed outside of Soong.
+	File    string
+	Install string
+
+	Arch string
+
+	// Namespaces are the linker namespaces that the file can be loaded from if it is a shared
+	// library, and Search the namespaces that its DT_NEEDED entries are loaded from.
+	Namespaces []string
+	Search     []string
+
+	SharedLibrary         bool
+	Check                 bool
+	AllowUndefinedSymbols bool
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("%s: %s (%s) %s", e.Blueprint, e.Module, e.Variant, e.Install)
+}
+
+// Elf is the dynamic linking information of an ELF file.
+type Elf struct {
+	Soname    string
+	Needed    []string
+	Undefined []string
+	Defined   map[string]bool
+}
+
+// ReadElf reads the DT_SONAME, the DT_NEEDED entries and the dynamic symbols of an ELF file.
+// Undefined weak symbols are not required to resolve and are skipped.
+func ReadElf(f *elf.File) (*Elf, error) {
+	ret := &Elf{Defined: make(map[string]bool)}
+
+	sonames, err := f.DynString(elf.DT_SONAME)
+	if err != nil {
+		return nil, err
+	}
+	if len(sonames) > 0 {
+		ret.Soname = sonames[0]
+	}
+
+	if ret.Needed, err = f.ImportedLibraries(); err != nil {
+		return nil, err
+	}
+
+	syms, err := f.DynamicSymbols()
+	if err != nil && err != elf.ErrNoSymbols {
+		return nil, err
+	}
+	for _, sym := range syms {
+		if sym.Name == "" {
+			continue
+		}
+		bind := elf.ST_BIND(sym.Info)
+		if sym.Section == elf.SHN_UNDEF {
+			if bind != elf.STB_WEAK {
+				ret.Undefined = append(ret.Undefined, sym.Name)
+			}
+		} else if bind == elf.STB_GLOBAL || bind == elf.STB_WEAK || bind == elf.STB_LOOS {
+			// STB_LOOS is STB_GNU_UNIQUE.
+			ret.Defined[sym.Name] = true
+		}
+	}
+	return ret, nil
+}
+
+// Violation is a problem found in an installed ELF file.
+type Violation struct {
+	Entry   *Entry
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Entry.String() + ": " + v.Message
+}
+
+type checker struct {
+	elfs map[*Entry]*Elf
+
+	// providers maps the arch, namespace and file name to the shared libraries installed with
+	// that name.
+	providers map[string]map[string]map[string][]*Entry
+	byName    map[string]map[string][]*Entry
+}
+
+// Check verifies that the DT_SONAME of every checked shared library matches its installed name,
+// that every DT_NEEDED entry resolves to a shared library installed for the same arch in one of
+// the namespaces searched by the file, and that the undefined symbols of the file are defined by
+// the libraries it loads directly or indirectly.  The symbols are not checked when one of the
+// loaded libraries has no ELF information, like the libraries installed outside of Soong.
+func Check(entries []*Entry, elfs map[*Entry]*Elf) []Violation {
+	c := &checker{
+		elfs:      elfs,
+		providers: make(map[string]map[string]map[string][]*Entry),
+		byName:    make(map[string]map[string][]*Entry),
+	}
+	for _, e := range entries {
+		if !e.SharedLibrary {
+			continue
+		}
+		name := filepath.Base(e.Install)
+		if c.providers[e.Arch] == nil {
+			c.providers[e.Arch] = make(map[string]map[string][]*Entry)
+			c.byName[e.Arch] = make(map[string][]*Entry)
+		}
+		for _, ns := range e.Namespaces {
+			if c.providers[e.Arch][ns] == nil {
+				c.providers[e.Arch][ns] = make(map[string][]*Entry)
+			}
+			c.providers[e.Arch][ns][name] = append(c.providers[e.Arch][ns][name], e)
+		}
+		c.byName[e.Arch][name] = append(c.byName[e.Arch][name], e)
+	}
+
+	var violations []Violation
+	for _, e := range entries {
+		if e.Check {
+			violations = append(violations, c.check(e)...)
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Entry.String() < violations[j].Entry.String()
+	})
+	return violations
+}
+
+func (c *checker) resolve(e *Entry, needed string) *Entry {
+	for _, ns := range e.Search {
+		if providers := c.providers[e.Arch][ns][needed]; len(providers) > 0 {
+			return providers[0]
+		}
+	}
+	return nil
+}
+
+func (c *checker) check(e *Entry) []Violation {
+	var violations []Violation
+	report := func(format string, a ...interface{}) {
+		violations = append(violations, Violation{e, fmt.Sprintf(format, a...)})
+	}
+
+	info := c.elfs[e]
+	if info == nil {
+		return nil
+	}
+
+	if e.SharedLibrary {
+		if name := filepath.Base(e.Install); info.Soname == "" {
+			report("missing DT_SONAME, expected %q", name)
+		} else if info.Soname != name {
+			report("DT_SONAME %q does not match the installed name %q", info.Soname, name)
+		}
+	}
+
+	resolved := true
+	for _, needed := range info.Needed {
+		if c.resolve(e, needed) != nil {
+			continue
+		}
+		resolved = false
+		var elsewhere []string
+		for _, p := range c.byName[e.Arch][needed] {
+			elsewhere = append(elsewhere, fmt.Sprintf("%s in %s", p.Module, strings.Join(p.Namespaces, ",")))
+		}
+		if len(elsewhere) > 0 {
+			report("DT_NEEDED %q is not visible from %s, it is installed by %s",
+				needed, strings.Join(e.Namespaces, ","), strings.Join(elsewhere, ", "))
+		} else {
+			report("DT_NEEDED %q is not installed for %s", needed, e.Arch)
+		}
+	}
+
+	// Symbols can't be checked reliably when a library is missing, which was reported above.
+	if !resolved || e.AllowUndefinedSymbols || len(info.Undefined) == 0 {
+		return violations
+	}
+
+	loaded := []*Entry{e}
+	seen := map[*Entry]bool{e: true}
+	for i := 0; i < len(loaded); i++ {
+		l := c.elfs[loaded[i]]
+		if l == nil {
+			// The symbols defined by the library are unknown.
+			return violations
+		}
+		for _, needed := range l.Needed {
+			if p := c.resolve(loaded[i], needed); p != nil && !seen[p] {
+				seen[p] = true
+				loaded = append(loaded, p)
+			}
+		}
+	}
+
+	var undefined []string
+	for _, sym := range info.Undefined {
+		found := false
+		for _, l := range loaded {
+			if li := c.elfs[l]; li != nil && li.Defined[sym] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			undefined = append(undefined, sym)
+		}
+	}
+	if len(undefined) > 0 {
+		sort.Strings(undefined)
+		list := undefined
+		if len(list) > maxListedSymbols {
+			list = list[:maxListedSymbols]
+		}
+		msg := strings.Join(list, ", ")
+		if len(undefined) > len(list) {
+			msg += fmt.Sprintf(" and %d more", len(undefined)-len(list))
+		}
+		report("undefined symbols not defined by its DT_NEEDED libraries: %s", msg)
+	}
+
+	return violations
+}
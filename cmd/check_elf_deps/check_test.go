@@ -0,0 +1,130 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func defines(syms ...string) map[string]bool {
+	ret := make(map[string]bool)
+	for _, s := range syms {
+		ret[s] = true
+	}
+	return ret
+}
+
+func TestCheck(t *testing.T) {
+	libc := &Entry{Module: "libc", Install: "system/lib64/libc.so", Arch: "arm64",
+		Namespaces: []string{"system", "llndk"}, Search: []string{"system"}, SharedLibrary: true, Check: true}
+	libbase := &Entry{Module: "libbase", Install: "system/lib64/libbase.so", Arch: "arm64",
+		Namespaces: []string{"system"}, Search: []string{"system"}, SharedLibrary: true, Check: true}
+	libbase32 := &Entry{Module: "libbase", Install: "system/lib/libbase.so", Arch: "arm",
+		Namespaces: []string{"system"}, Search: []string{"system"}, SharedLibrary: true, Check: true}
+	libvndk := &Entry{Module: "libvndk", Install: "system/lib64/vndk-VER/libvndk.so", Arch: "arm64",
+		Namespaces: []string{"vndk"}, Search: []string{"vndk", "llndk"}, SharedLibrary: true, Check: true}
+	libvendor := &Entry{Module: "libvendor", Blueprint: "vendor/foo/Android.bp", Install: "vendor/lib64/libvendor.so",
+		Arch: "arm64", Namespaces: []string{"vendor"}, Search: []string{"vendor", "vndk", "llndk"}, SharedLibrary: true, Check: true}
+	vendorBin := &Entry{Module: "vendor_bin", Blueprint: "vendor/foo/Android.bp", Install: "vendor/bin/vendor_bin",
+		Arch: "arm64", Namespaces: []string{"vendor"}, Search: []string{"vendor", "vndk", "llndk"}, Check: true}
+	systemBin := &Entry{Module: "system_bin", Blueprint: "system/foo/Android.bp", Install: "system/bin/system_bin",
+		Arch: "arm64", Namespaces: []string{"system"}, Search: []string{"system"}, Check: true}
+	prebuilt := &Entry{Module: "libprebuilt", Install: "vendor/lib64/libprebuilt.so", Arch: "arm64",
+		Namespaces: []string{"vendor"}, Search: []string{"vendor", "vndk", "llndk"}, SharedLibrary: true}
+
+	elfs := map[*Entry]*Elf{
+		libc:      {Soname: "libc.so", Defined: defines("malloc", "free")},
+		libbase:   {Soname: "libbase.so", Needed: []string{"libc.so"}, Undefined: []string{"malloc"}, Defined: defines("base_log")},
+		libbase32: {Soname: "libbase.so", Defined: defines("base_log")},
+		libvndk:   {Soname: "libvndk.so", Needed: []string{"libc.so"}, Undefined: []string{"free"}, Defined: defines("vndk_init")},
+		libvendor: {Soname: "libvendor.so.1", Needed: []string{"libvndk.so"}, Undefined: []string{"vndk_init", "free"}},
+		vendorBin: {Needed: []string{"libvendor.so", "libbase.so"}, Undefined: []string{"base_log"}},
+		systemBin: {Needed: []string{"libbase.so"}, Undefined: []string{"base_log", "malloc", "missing_sym"}},
+		prebuilt:  {Soname: "wrong.so", Needed: []string{"libmissing.so"}},
+	}
+
+	entries := []*Entry{libc, libbase, libbase32, libvndk, libvendor, vendorBin, systemBin, prebuilt}
+
+	var got []string
+	for _, v := range Check(entries, elfs) {
+		got = append(got, v.String())
+	}
+
+	want := []string{
+		`system/foo/Android.bp: system_bin () system/bin/system_bin: undefined symbols not defined by its DT_NEEDED libraries: missing_sym`,
+		`vendor/foo/Android.bp: libvendor () vendor/lib64/libvendor.so: DT_SONAME "libvendor.so.1" does not match the installed name "libvendor.so"`,
+		`vendor/foo/Android.bp: vendor_bin () vendor/bin/vendor_bin: DT_NEEDED "libbase.so" is not visible from vendor, it is installed by libbase in system`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected violations:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestCheckMissingNeeded(t *testing.T) {
+	bin := &Entry{Module: "bin", Install: "system/bin/bin", Arch: "arm64",
+		Namespaces: []string{"system"}, Search: []string{"system"}, Check: true}
+	elfs := map[*Entry]*Elf{
+		bin: {Needed: []string{"libmissing.so"}, Undefined: []string{"missing_sym"}},
+	}
+
+	violations := Check([]*Entry{bin}, elfs)
+	if len(violations) != 1 {
+		t.Fatalf("expected a single violation, got %q", violations)
+	}
+	if g, w := violations[0].Message, `DT_NEEDED "libmissing.so" is not installed for arm64`; g != w {
+		t.Errorf("expected %q, got %q", w, g)
+	}
+}
+
+func TestCheckAllowUndefinedSymbols(t *testing.T) {
+	lib := &Entry{Module: "libfoo", Install: "system/lib64/libfoo.so", Arch: "arm64", Namespaces: []string{"system"},
+		Search: []string{"system"}, SharedLibrary: true, Check: true, AllowUndefinedSymbols: true}
+	elfs := map[*Entry]*Elf{
+		lib: {Soname: "libfoo.so", Undefined: []string{"provided_by_executable"}},
+	}
+
+	if violations := Check([]*Entry{lib}, elfs); len(violations) != 0 {
+		t.Errorf("expected no violations, got %q", violations)
+	}
+}
+
+func TestCheckExternalProviders(t *testing.T) {
+	// A library installed by Make, and a library with stubs built for an APEX.
+	external := &Entry{Module: "libmake.so", Variant: "external", Install: "libmake.so", Arch: "arm64",
+		Namespaces: []string{"system"}, SharedLibrary: true}
+	apex := &Entry{Module: "libapex", File: "libapex.so", Install: "/apex/com.android.foo/lib64/libapex.so",
+		Arch: "arm64", Namespaces: []string{"system"}, SharedLibrary: true}
+	bin := &Entry{Module: "bin", Install: "system/bin/bin", Arch: "arm64",
+		Namespaces: []string{"system"}, Search: []string{"system"}, Check: true}
+	apexBin := &Entry{Module: "apex_bin", Install: "system/bin/apex_bin", Arch: "arm64",
+		Namespaces: []string{"system"}, Search: []string{"system"}, Check: true}
+	elfs := map[*Entry]*Elf{
+		apex:    {Soname: "libapex.so", Defined: defines("apex_sym")},
+		bin:     {Needed: []string{"libmake.so", "libapex.so"}, Undefined: []string{"make_sym"}},
+		apexBin: {Needed: []string{"libapex.so"}, Undefined: []string{"apex_sym", "missing_sym"}},
+	}
+
+	var got []string
+	for _, v := range Check([]*Entry{external, apex, bin, apexBin}, elfs) {
+		got = append(got, v.Message)
+	}
+	// The symbols of bin can't be checked as libmake.so can't be read.
+	want := []string{"undefined symbols not defined by its DT_NEEDED libraries: missing_sym"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected violations %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool verifies the dynamic linking of the installed ELF files of a build.  Each argument is a
+// JSON file describing an installed ELF file, or an @file listing them.  Shared libraries must have
+// a DT_SONAME matching their installed name, every DT_NEEDED entry must resolve to a shared library
+// in one of the linker namespaces the file loads its dependencies from, and the undefined symbols
+// of the file must be defined by the libraries it loads.  Entries without a file describe libraries
+// installed outside of Soong, which resolve DT_NEEDED entries but can't be read.  The violations are printed with the
+// Android.bp file of the module, and the tool fails if there are any.
+package main
+
+import (
+	"debug/elf"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+var output = flag.String("o", "", "Output file written when there are no violations")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -o <stamp> <json>|@<list>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entries, err := readEntries(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	elfs := make(map[*Entry]*Elf)
+	for _, e := range entries {
+		if e.File == "" {
+			continue
+		}
+		f, err := elf.Open(e.File)
+		if err != nil {
+			log.Fatalf("%s: %v", e, err)
+		}
+		info, err := ReadElf(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %s: %v", e, e.File, err)
+		}
+		elfs[e] = info
+	}
+
+	violations := Check(entries, elfs)
+	if len(violations) > 0 {
+		for _, v := range violations {
+			fmt.Fprintln(os.Stderr, v)
+		}
+		fmt.Fprintf(os.Stderr, "%d ELF dependency violations found\n", len(violations))
+		os.Exit(1)
+	}
+
+	if err := ioutil.WriteFile(*output, nil, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readEntries(args []string) ([]*Entry, error) {
+	var files []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") {
+			data, err := ioutil.ReadFile(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, strings.Fields(string(data))...)
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	var entries []*Entry
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		e := &Entry{}
+		if err := json.Unmarshal(data, e); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"android/soong/androidmk/parser"
+)
+
+// systemDirFlags are the compiler flags that add a directory to the header search path.  Each may
+// be passed with its value attached or as the next argument.
+var systemDirFlags = []string{"-I", "-isystem", "-iquote", "-idirafter", "-isysroot", "--sysroot"}
+
+// SystemDirs returns the directories added to the header search path by global and toolchain flags,
+// for example the global include directories, the bionic headers or the sysroot.
+func SystemDirs(flags []string) []string {
+	var ret []string
+
+	value := func(i int, flag string) (string, int) {
+		arg := flags[i]
+		if arg == flag {
+			if i+1 < len(flags) {
+				return flags[i+1], i + 1
+			}
+			return "", i
+		}
+		return strings.TrimPrefix(strings.TrimPrefix(arg, flag), "="), i
+	}
+
+args:
+	for i := 0; i < len(flags); i++ {
+		for _, flag := range systemDirFlags {
+			if strings.HasPrefix(flags[i], flag) {
+				var dir string
+				dir, i = value(i, flag)
+				if dir != "" {
+					ret = append(ret, filepath.Clean(dir))
+				}
+				continue args
+			}
+		}
+	}
+
+	return ret
+}
+
+// ReadDeps returns the prerequisites listed in a make-style dependency file written by the compiler.
+// The first prerequisite is the source file.
+func ReadDeps(filename string, r io.Reader) ([]string, error) {
+	p := parser.NewParser(filename, r)
+	nodes, errs := p.Parse()
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	} else if len(errs) > 1 {
+		return nil, fmt.Errorf("many errors: %v", errs)
+	}
+
+	var ret []string
+	for _, node := range nodes {
+		switch x := node.(type) {
+		case *parser.Comment:
+			// Do nothing
+		case *parser.Rule:
+			if !x.Prerequisites.Const() {
+				return nil, fmt.Errorf("%s: unsupported variable expansion: %v",
+					p.Unpack(node.Pos()), x.Prerequisites.Dump())
+			}
+			for _, input := range x.Prerequisites.Words() {
+				ret = append(ret, input.Value(nil))
+			}
+		default:
+			return nil, fmt.Errorf("%s: unexpected line: %#v", p.Unpack(node.Pos()), node)
+		}
+	}
+
+	return ret, nil
+}
+
+func inDirs(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == "." || path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns the headers in inputs, as returned by ReadDeps, that are not inside one of the
+// allowed directories.  Headers next to the source file are always allowed, as they can be included
+// with a quoted include relative to the source.
+func Check(inputs []string, allowed []string) []string {
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	var dirs []string
+	for _, dir := range allowed {
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	dirs = append(dirs, filepath.Dir(filepath.Clean(inputs[0])))
+
+	var ret []string
+	for _, header := range inputs[1:] {
+		if !inDirs(filepath.Clean(header), dirs) {
+			ret = append(ret, header)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSystemDirs(t *testing.T) {
+	flags := []string{
+		"-isystem", "prebuilts/clang/host/linux-x86/clang-r353983c",
+		"-Isystem/core/include",
+		"-isystem", "bionic/libc/include",
+		"-isystembionic/libc/kernel/uapi",
+		"--sysroot=prebuilts/ndk/sysroot",
+		"-isysroot", "prebuilts/MacOSX.sdk",
+		"-DFOO=1",
+		"-O2",
+	}
+
+	got := SystemDirs(flags)
+	want := []string{
+		"prebuilts/clang/host/linux-x86/clang-r353983c",
+		"system/core/include",
+		"bionic/libc/include",
+		"bionic/libc/kernel/uapi",
+		"prebuilts/ndk/sysroot",
+		"prebuilts/MacOSX.sdk",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	allowed := []string{
+		"prebuilts/clang/host/linux-x86/clang-r353983c",
+		"external/foo/include",
+		// Re-exported by external/foo.
+		"external/bar/include/",
+		// A global include directory.
+		"system/core/include",
+	}
+
+	testCases := []struct {
+		name   string
+		input  string
+		output []string
+	}{
+		{
+			name:  "allowed",
+			input: "out/foo.o: src/foo.c external/foo/include/foo.h external/foo/include/foo/detail.h",
+		},
+		{
+			name:  "re-exported",
+			input: "out/foo.o: src/foo.c external/foo/include/foo.h external/bar/include/bar.h",
+		},
+		{
+			name:  "global include",
+			input: "out/foo.o: src/foo.c system/core/include/cutils/log.h",
+		},
+		{
+			name:  "next to source",
+			input: "out/foo.o: src/foo.c src/foo_internal.h",
+		},
+		{
+			name: "compiler builtin",
+			input: "out/foo.o: src/foo.c \\\n" +
+				"  prebuilts/clang/host/linux-x86/clang-r353983c/bin/../lib64/clang/9.0.3/include/stddef.h",
+		},
+		{
+			name:   "relative include out of an exported directory",
+			input:  "out/foo.o: src/foo.c external/foo/include/foo.h external/foo/include/../src/impl.h",
+			output: []string{"external/foo/include/../src/impl.h"},
+		},
+		{
+			name:   "not exported",
+			input:  "out/foo.o: src/foo.c \\\n  external/baz/include/baz.h \\\n  external/foo/include/foo.h\n",
+			output: []string{"external/baz/include/baz.h"},
+		},
+		{
+			name:   "prefix of include directory name",
+			input:  "out/foo.o: src/foo.c external/foo/include2/foo.h",
+			output: []string{"external/foo/include2/foo.h"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := ReadDeps("test.d", strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := Check(inputs, allowed)
+			if !reflect.DeepEqual(got, tc.output) {
+				t.Errorf("want %q, got %q", tc.output, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool reads the dependency file written by the compiler for a single object and reports any
+// header that is not inside a directory the module may include headers from.  The allowed
+// directories are passed by the build system: the module's own directories and include
+// directories, those exported or re-exported by its direct dependencies, and the global and
+// toolchain include directories.  Headers reached any other way, for example through a relative
+// include out of another library's exported directory, only build while the libraries in between
+// happen to keep their sources where they are.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+type multiString []string
+
+func (ms *multiString) String() string {
+	return strings.Join(*ms, ", ")
+}
+
+func (ms *multiString) Set(s string) error {
+	*ms = append(*ms, s)
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-warn] [-module <name>] [-allow <dir>]... [-system <flags>] <depfile.d>\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	var allowed multiString
+	warn := flag.Bool("warn", false, "Print violations as warnings instead of failing")
+	module := flag.String("module", "", "Name of the module being compiled, used in messages")
+	system := flag.String("system", "", "Global and toolchain flags whose include directories may always be used")
+	flag.Var(&allowed, "allow", "Directory that headers may be included from")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	depFile := flag.Arg(0)
+	f, err := os.Open(depFile)
+	if err != nil {
+		log.Fatalf("Error opening %q: %v", depFile, err)
+	}
+	defer f.Close()
+
+	inputs, err := ReadDeps(depFile, f)
+	if err != nil {
+		log.Fatalf("Failed to parse: %v", err)
+	}
+	if len(inputs) == 0 {
+		return
+	}
+
+	violations := Check(inputs, append(allowed, SystemDirs(strings.Fields(*system))...))
+	if len(violations) == 0 {
+		return
+	}
+
+	severity := "error"
+	if *warn {
+		severity = "warning"
+	}
+	for _, header := range violations {
+		fmt.Fprintf(os.Stderr, "%s: %s: header layering: %s is not in an include directory of module %q "+
+			"or exported by one of its direct dependencies\n", inputs[0], severity, header, *module)
+	}
+
+	if !*warn {
+		fmt.Fprintln(os.Stderr, "Add the library that exports these headers to header_libs, shared_libs or static_libs.")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change describes how a single member differs between two flags files.  OldFlags is nil for
+// added members and NewFlags is nil for removed members.
+type Change struct {
+	Signature string
+	OldList   string
+	NewList   string
+	OldFlags  []string
+	NewFlags  []string
+}
+
+// Kind returns a description of the change used to group changes in the report.
+func (c Change) Kind() string {
+	switch {
+	case c.OldFlags == nil:
+		return fmt.Sprintf("added to %s", listName(c.NewList))
+	case c.NewFlags == nil:
+		return fmt.Sprintf("removed from %s", listName(c.OldList))
+	case c.OldList == c.NewList:
+		return fmt.Sprintf("flags changed on %s", listName(c.NewList))
+	default:
+		return fmt.Sprintf("moved from %s to %s", listName(c.OldList), listName(c.NewList))
+	}
+}
+
+func listName(list string) string {
+	if list == "" {
+		return "no list"
+	}
+	return list
+}
+
+// Diff holds the changes between two flags files grouped by module.
+type Diff struct {
+	Modules map[string][]Change
+}
+
+// Empty returns true if there are no changes.
+func (d Diff) Empty() bool {
+	return len(d.Modules) == 0
+}
+
+// diffFlags compares the entries from two flags files.  Changes are attributed to the module from
+// the new entries, or the old entries for removed members.
+func diffFlags(oldEntries, newEntries []Entry) Diff {
+	oldBySignature := make(map[string]Entry, len(oldEntries))
+	for _, e := range oldEntries {
+		oldBySignature[e.Signature] = e
+	}
+	newBySignature := make(map[string]Entry, len(newEntries))
+	for _, e := range newEntries {
+		newBySignature[e.Signature] = e
+	}
+
+	diff := Diff{Modules: make(map[string][]Change)}
+	add := func(module string, c Change) {
+		diff.Modules[module] = append(diff.Modules[module], c)
+	}
+
+	for _, n := range newEntries {
+		o, ok := oldBySignature[n.Signature]
+		if !ok {
+			add(n.Module, Change{
+				Signature: n.Signature,
+				NewList:   n.List(),
+				NewFlags:  n.Flags,
+			})
+		} else if !sameFlags(o.Flags, n.Flags) {
+			add(n.Module, Change{
+				Signature: n.Signature,
+				OldList:   o.List(),
+				NewList:   n.List(),
+				OldFlags:  o.Flags,
+				NewFlags:  n.Flags,
+			})
+		}
+	}
+
+	for _, o := range oldEntries {
+		if _, ok := newBySignature[o.Signature]; !ok {
+			add(o.Module, Change{
+				Signature: o.Signature,
+				OldList:   o.List(),
+				OldFlags:  o.Flags,
+			})
+		}
+	}
+
+	for _, changes := range diff.Modules {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Signature < changes[j].Signature
+		})
+	}
+
+	return diff
+}
+
+// sameFlags returns true if the two lists contain the same flags, ignoring order.
+func sameFlags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String pretty-prints the changes grouped by module and then by the kind of change.
+func (d Diff) String() string {
+	buf := &bytes.Buffer{}
+
+	var modules []string
+	for module := range d.Modules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		changes := d.Modules[module]
+		fmt.Fprintf(buf, "%s: %d changed\n", module, len(changes))
+
+		byKind := make(map[string][]Change)
+		var kinds []string
+		for _, c := range changes {
+			kind := c.Kind()
+			if _, ok := byKind[kind]; !ok {
+				kinds = append(kinds, kind)
+			}
+			byKind[kind] = append(byKind[kind], c)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			fmt.Fprintf(buf, "  %s (%d):\n", kind, len(byKind[kind]))
+			for _, c := range byKind[kind] {
+				if c.OldFlags != nil && c.NewFlags != nil && c.OldList == c.NewList {
+					fmt.Fprintf(buf, "    %s [%s] -> [%s]\n", c.Signature,
+						strings.Join(c.OldFlags, ","), strings.Join(c.NewFlags, ","))
+				} else {
+					fmt.Fprintf(buf, "    %s\n", c.Signature)
+				}
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// Summary returns the number of changes per module and kind as CSV lines of module,kind,count.
+func (d Diff) Summary() string {
+	counts := make(map[[2]string]int)
+	for module, changes := range d.Modules {
+		for _, c := range changes {
+			counts[[2]string{module, c.Kind()}]++
+		}
+	}
+
+	var keys [][2]string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	buf := &bytes.Buffer{}
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s,%s,%d\n", k[0], k[1], counts[k])
+	}
+	return buf.String()
+}
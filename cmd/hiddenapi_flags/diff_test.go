@@ -0,0 +1,85 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffFlags(t *testing.T) {
+	oldEntries, err := readFlags(strings.NewReader(strings.Join([]string{
+		"framework,Landroid/A;->a()V,whitelist",
+		"framework,Landroid/A;->b()V,greylist",
+		"framework,Landroid/A;->c()V,blacklist",
+		"framework,Landroid/A;->d()V,greylist,system-api",
+		"core-oj,Ljava/B;->e()V,greylist-max-o",
+		"core-oj,Ljava/B;->same()V,whitelist,public-api",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newEntries, err := readFlags(strings.NewReader(strings.Join([]string{
+		"framework,Landroid/A;->a()V,whitelist",
+		"framework,Landroid/A;->b()V,blacklist",
+		"framework,Landroid/A;->d()V,greylist",
+		"framework,Landroid/A;->f()V,blacklist",
+		"core-oj,Ljava/B;->e()V,greylist-max-p",
+		"core-oj,Ljava/B;->same()V,public-api,whitelist",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := diffFlags(oldEntries, newEntries)
+
+	expected := strings.Join([]string{
+		"core-oj: 1 changed",
+		"  moved from greylist-max-o to greylist-max-p (1):",
+		"    Ljava/B;->e()V",
+		"framework: 4 changed",
+		"  added to blacklist (1):",
+		"    Landroid/A;->f()V",
+		"  flags changed on greylist (1):",
+		"    Landroid/A;->d()V [greylist,system-api] -> [greylist]",
+		"  moved from greylist to blacklist (1):",
+		"    Landroid/A;->b()V",
+		"  removed from blacklist (1):",
+		"    Landroid/A;->c()V",
+		"",
+	}, "\n")
+
+	if got := diff.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	expectedSummary := strings.Join([]string{
+		"core-oj,moved from greylist-max-o to greylist-max-p,1",
+		"framework,added to blacklist,1",
+		"framework,flags changed on greylist,1",
+		"framework,moved from greylist to blacklist,1",
+		"framework,removed from blacklist,1",
+		"",
+	}, "\n")
+
+	if got := diff.Summary(); got != expectedSummary {
+		t.Errorf("expected summary:\n%s\ngot:\n%s", expectedSummary, got)
+	}
+
+	if diffFlags(newEntries, newEntries).Empty() != true {
+		t.Errorf("expected no changes when comparing a file with itself")
+	}
+}
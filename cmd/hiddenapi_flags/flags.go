@@ -0,0 +1,154 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// unknownModule is used for members whose class was not found in any of the module jars.
+const unknownModule = "<unknown>"
+
+// The lists a member can be on, in order of increasing restriction.  Every member in
+// hiddenapi-flags.csv is on exactly one of them.
+var apiLists = []string{
+	"whitelist",
+	"greylist",
+	"greylist-max-p",
+	"greylist-max-o",
+	"blacklist",
+}
+
+// Entry is a single line of a hiddenapi flags CSV file, attributed to the module that contains the
+// class of the member.
+type Entry struct {
+	Module    string
+	Signature string
+	Flags     []string
+}
+
+// List returns the hiddenapi list that the member is on, or "" if none of its flags is a list.
+func (e Entry) List() string {
+	for _, flag := range e.Flags {
+		for _, list := range apiLists {
+			if flag == list {
+				return list
+			}
+		}
+	}
+	return ""
+}
+
+// Class returns the descriptor of the class that contains the member.
+func (e Entry) Class() string {
+	if i := strings.Index(e.Signature, "->"); i != -1 {
+		return e.Signature[:i]
+	}
+	return e.Signature
+}
+
+// isSignature returns true if s looks like a member signature, for example "Lfoo/Bar;->baz()V",
+// as opposed to a module name.
+func isSignature(s string) bool {
+	return strings.HasPrefix(s, "L") && strings.Contains(s, "->")
+}
+
+// readFlags reads a hiddenapi-flags.csv file, or a hiddenapi-flags-by-module.csv file written by
+// the attribute command which has the name of the module prepended to every line.  Entries read
+// from a file without module information are attributed to unknownModule.
+func readFlags(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		entry := Entry{Module: unknownModule}
+		if !isSignature(fields[0]) {
+			entry.Module = fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || !isSignature(fields[0]) {
+			return nil, fmt.Errorf("line %d: expected a member signature in %q", lineNum, line)
+		}
+		entry.Signature = fields[0]
+		entry.Flags = fields[1:]
+
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// writeFlags writes the entries in the format of hiddenapi-flags-by-module.csv.
+func writeFlags(w io.Writer, entries []Entry) error {
+	out := bufio.NewWriter(w)
+	for _, e := range entries {
+		fields := append([]string{e.Module, e.Signature}, e.Flags...)
+		fmt.Fprintln(out, strings.Join(fields, ","))
+	}
+	return out.Flush()
+}
+
+// classIndex maps class descriptors to the name of the module whose jar contains the class.
+type classIndex map[string]string
+
+// addJar adds the classes in a jar to the index.  Classes that are already in the index keep the
+// module they were first added with.
+func (index classIndex) addJar(module string, r *zip.Reader) {
+	for _, f := range r.File {
+		if !strings.HasSuffix(f.Name, ".class") || strings.HasPrefix(f.Name, "META-INF/") {
+			continue
+		}
+		class := "L" + strings.TrimSuffix(f.Name, ".class") + ";"
+		if _, exists := index[class]; !exists {
+			index[class] = module
+		}
+	}
+}
+
+// attribute sets the module of each entry to the module that contains its class, and sorts the
+// entries by module and signature.
+func attribute(entries []Entry, index classIndex) {
+	for i := range entries {
+		if module, ok := index[entries[i].Class()]; ok {
+			entries[i].Module = module
+		} else {
+			entries[i].Module = unknownModule
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Module != entries[j].Module {
+			return entries[i].Module < entries[j].Module
+		}
+		return entries[i].Signature < entries[j].Signature
+	})
+}
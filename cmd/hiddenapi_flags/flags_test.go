@@ -0,0 +1,107 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testJar(t *testing.T, files ...string) *zip.Reader {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for _, f := range files {
+		if _, err := w.Create(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReadFlags(t *testing.T) {
+	in := strings.Join([]string{
+		"Landroid/Foo;->bar()V,public-api,whitelist",
+		"",
+		"framework,Landroid/Foo;->baz:I,greylist-max-o",
+		"Landroid/Foo;-><init>()V",
+	}, "\n")
+
+	expected := []Entry{
+		{Module: unknownModule, Signature: "Landroid/Foo;->bar()V", Flags: []string{"public-api", "whitelist"}},
+		{Module: "framework", Signature: "Landroid/Foo;->baz:I", Flags: []string{"greylist-max-o"}},
+		{Module: unknownModule, Signature: "Landroid/Foo;-><init>()V", Flags: []string{}},
+	}
+
+	got, err := readFlags(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	if list := got[0].List(); list != "whitelist" {
+		t.Errorf("expected whitelist, got %q", list)
+	}
+	if list := got[1].List(); list != "greylist-max-o" {
+		t.Errorf("expected greylist-max-o, got %q", list)
+	}
+
+	if _, err := readFlags(strings.NewReader("framework,not a signature\n")); err == nil {
+		t.Errorf("expected error for invalid line")
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	index := make(classIndex)
+	index.addJar("framework", testJar(t, "META-INF/MANIFEST.MF", "android/Foo.class", "android/Foo$Inner.class"))
+	index.addJar("core-oj", testJar(t, "java/lang/Object.class", "android/Foo.class"))
+
+	entries := []Entry{
+		{Signature: "Ljava/lang/Object;->hashCode()I", Flags: []string{"whitelist"}},
+		{Signature: "Landroid/Foo$Inner;->x:I", Flags: []string{"blacklist"}},
+		{Signature: "Landroid/Foo;->bar()V", Flags: []string{"greylist"}},
+		{Signature: "Lcom/example/Missing;->m()V", Flags: []string{"blacklist"}},
+	}
+
+	attribute(entries, index)
+
+	buf := &bytes.Buffer{}
+	if err := writeFlags(buf, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"<unknown>,Lcom/example/Missing;->m()V,blacklist",
+		"core-oj,Ljava/lang/Object;->hashCode()I,whitelist",
+		"framework,Landroid/Foo$Inner;->x:I,blacklist",
+		"framework,Landroid/Foo;->bar()V,greylist",
+		"",
+	}, "\n")
+
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// hiddenapi_flags attributes the members in hiddenapi-flags.csv to the boot jar modules that
+// define them, and compares the flags files from two builds grouped by module and list.
+//
+//	hiddenapi_flags attribute --flags hiddenapi-flags.csv --module <name>=<jar>... -o <out.csv>
+//	hiddenapi_flags diff [--summary] <old.csv> <new.csv>
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: hiddenapi_flags attribute --flags <flags.csv> --module <name>=<jar>... -o <out.csv>")
+	fmt.Fprintln(os.Stderr, "       hiddenapi_flags diff [--summary] [--exit-code] <old.csv> <new.csv>")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "attribute":
+		err = attributeMain(os.Args[2:])
+	case "diff":
+		err = diffMain(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func attributeMain(args []string) error {
+	flags := flag.NewFlagSet("attribute", flag.ExitOnError)
+	flagsFile := flags.String("flags", "", "hiddenapi-flags.csv file to attribute")
+	out := flags.String("o", "", "output file")
+	var modules multiString
+	flags.Var(&modules, "module", "module and its classes jar in the form <name>=<jar>, may be repeated")
+	flags.Parse(args)
+
+	if *flagsFile == "" || *out == "" || flags.NArg() != 0 {
+		usage()
+	}
+
+	index := make(classIndex)
+	for _, m := range modules {
+		i := strings.Index(m, "=")
+		if i == -1 {
+			return fmt.Errorf("--module argument %q is not of the form <name>=<jar>", m)
+		}
+		if err := addJarToIndex(index, m[:i], m[i+1:]); err != nil {
+			return err
+		}
+	}
+
+	entries, err := readFlagsFile(*flagsFile)
+	if err != nil {
+		return err
+	}
+
+	attribute(entries, index)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := writeFlags(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func diffMain(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	summary := flags.Bool("summary", false, "only print the number of changes per module and list")
+	exitCode := flags.Bool("exit-code", false, "exit with status 3 if there are any changes")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		usage()
+	}
+
+	oldEntries, err := readFlagsFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	newEntries, err := readFlagsFile(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diff := diffFlags(oldEntries, newEntries)
+
+	if *summary {
+		fmt.Print(diff.Summary())
+	} else {
+		fmt.Print(diff.String())
+	}
+
+	if *exitCode && !diff.Empty() {
+		os.Exit(3)
+	}
+	return nil
+}
+
+func addJarToIndex(index classIndex, module, jar string) error {
+	r, err := zip.OpenReader(jar)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", jar, err)
+	}
+	defer r.Close()
+
+	index.addJar(module, &r.Reader)
+	return nil
+}
+
+func readFlagsFile(file string) ([]Entry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := readFlags(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	return entries, nil
+}
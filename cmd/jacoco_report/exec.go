@@ -0,0 +1,331 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Reader and writer for the jacoco execution data format (.ec/.exec files), as implemented by
+// org.jacoco.core.data.ExecutionDataReader and ExecutionDataWriter.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	execBlockHeader        = 0x01
+	execBlockSessionInfo   = 0x10
+	execBlockExecutionData = 0x11
+
+	execMagicNumber   = 0xC0C0
+	execFormatVersion = 0x1007
+)
+
+type SessionInfo struct {
+	Id    string
+	Start int64
+	Dump  int64
+}
+
+type ExecutionData struct {
+	Id     uint64
+	Name   string
+	Probes []bool
+}
+
+// ExecData holds the merged contents of one or more execution data files.
+type ExecData struct {
+	Sessions []SessionInfo
+	classes  map[uint64]*ExecutionData
+}
+
+func NewExecData() *ExecData {
+	return &ExecData{
+		classes: make(map[uint64]*ExecutionData),
+	}
+}
+
+// Classes returns the execution data for all classes, sorted by class name and id.
+func (e *ExecData) Classes() []*ExecutionData {
+	ret := make([]*ExecutionData, 0, len(e.classes))
+	for _, c := range e.classes {
+		ret = append(ret, c)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Name != ret[j].Name {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].Id < ret[j].Id
+	})
+	return ret
+}
+
+// Merge adds the probes of a class to the execution data, or'ing them with the probes from any
+// previously added data for the same class id.
+func (e *ExecData) Merge(data ExecutionData) error {
+	existing := e.classes[data.Id]
+	if existing == nil {
+		e.classes[data.Id] = &ExecutionData{
+			Id:     data.Id,
+			Name:   data.Name,
+			Probes: append([]bool(nil), data.Probes...),
+		}
+		return nil
+	}
+
+	if existing.Name != data.Name || len(existing.Probes) != len(data.Probes) {
+		return fmt.Errorf("incompatible execution data for class %s with id %016x", data.Name, data.Id)
+	}
+
+	for i, p := range data.Probes {
+		existing.Probes[i] = existing.Probes[i] || p
+	}
+	return nil
+}
+
+// Read parses an execution data stream and merges its contents.
+func (e *ExecData) Read(r io.Reader) error {
+	in := &execReader{r: bufio.NewReader(r)}
+
+	first := true
+	for {
+		block, err := in.r.ReadByte()
+		if err == io.EOF {
+			if first {
+				return fmt.Errorf("empty execution data file")
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if first && block != execBlockHeader {
+			return fmt.Errorf("invalid execution data file, expected header block")
+		}
+		first = false
+
+		switch block {
+		case execBlockHeader:
+			magic := in.readChar()
+			version := in.readChar()
+			if in.err != nil {
+				return in.err
+			}
+			if magic != execMagicNumber {
+				return fmt.Errorf("invalid execution data file, bad magic number 0x%04x", magic)
+			}
+			if version != execFormatVersion {
+				return fmt.Errorf("incompatible execution data version 0x%04x", version)
+			}
+		case execBlockSessionInfo:
+			info := SessionInfo{
+				Id:    in.readUTF(),
+				Start: in.readLong(),
+				Dump:  in.readLong(),
+			}
+			if in.err != nil {
+				return in.err
+			}
+			e.Sessions = append(e.Sessions, info)
+		case execBlockExecutionData:
+			data := ExecutionData{
+				Id:     uint64(in.readLong()),
+				Name:   in.readUTF(),
+				Probes: in.readBooleanArray(),
+			}
+			if in.err != nil {
+				return in.err
+			}
+			if err := e.Merge(data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown block type 0x%02x in execution data", block)
+		}
+	}
+}
+
+// Write writes the merged execution data as a single execution data stream.
+func (e *ExecData) Write(w io.Writer) error {
+	out := &execWriter{w: bufio.NewWriter(w)}
+
+	out.writeByte(execBlockHeader)
+	out.writeChar(execMagicNumber)
+	out.writeChar(execFormatVersion)
+
+	for _, info := range e.Sessions {
+		out.writeByte(execBlockSessionInfo)
+		out.writeUTF(info.Id)
+		out.writeLong(info.Start)
+		out.writeLong(info.Dump)
+	}
+
+	for _, data := range e.Classes() {
+		out.writeByte(execBlockExecutionData)
+		out.writeLong(int64(data.Id))
+		out.writeUTF(data.Name)
+		out.writeBooleanArray(data.Probes)
+	}
+
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// execReader implements the subset of java.io.DataInput and org.jacoco.core.internal.data.CompactDataInput
+// used by the execution data format.  The first error is sticky and returned through err.
+type execReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (r *execReader) read(buf []byte) {
+	if r.err != nil {
+		return
+	}
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = err
+	}
+}
+
+func (r *execReader) readByte() byte {
+	var buf [1]byte
+	r.read(buf[:])
+	return buf[0]
+}
+
+func (r *execReader) readChar() uint16 {
+	var buf [2]byte
+	r.read(buf[:])
+	return binary.BigEndian.Uint16(buf[:])
+}
+
+func (r *execReader) readLong() int64 {
+	var buf [8]byte
+	r.read(buf[:])
+	return int64(binary.BigEndian.Uint64(buf[:]))
+}
+
+// readUTF reads a string written by DataOutput.writeUTF.  Class names and session ids are plain
+// ASCII in practice, so the modified UTF-8 encoding is treated as UTF-8.
+func (r *execReader) readUTF() string {
+	buf := make([]byte, r.readChar())
+	r.read(buf)
+	return string(buf)
+}
+
+func (r *execReader) readVarInt() int {
+	value := 0
+	for shift := uint(0); ; shift += 7 {
+		if shift > 28 {
+			if r.err == nil {
+				r.err = fmt.Errorf("invalid variable length integer in execution data")
+			}
+			return 0
+		}
+		b := r.readByte()
+		value |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value
+		}
+	}
+}
+
+func (r *execReader) readBooleanArray() []bool {
+	length := r.readVarInt()
+	if r.err != nil {
+		return nil
+	}
+	probes := make([]bool, length)
+	var buffer byte
+	for i := range probes {
+		if i%8 == 0 {
+			buffer = r.readByte()
+		}
+		probes[i] = buffer&(1<<uint(i%8)) != 0
+	}
+	return probes
+}
+
+// execWriter implements the subset of java.io.DataOutput and
+// org.jacoco.core.internal.data.CompactDataOutput used by the execution data format.
+type execWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *execWriter) write(buf []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(buf)
+}
+
+func (w *execWriter) writeByte(b byte) {
+	w.write([]byte{b})
+}
+
+func (w *execWriter) writeChar(c uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], c)
+	w.write(buf[:])
+}
+
+func (w *execWriter) writeLong(l int64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(l))
+	w.write(buf[:])
+}
+
+func (w *execWriter) writeUTF(s string) {
+	if len(s) > 0xffff {
+		if w.err == nil {
+			w.err = fmt.Errorf("string too long for execution data: %q", s)
+		}
+		return
+	}
+	w.writeChar(uint16(len(s)))
+	w.write([]byte(s))
+}
+
+func (w *execWriter) writeVarInt(v int) {
+	for v&^0x7f != 0 {
+		w.writeByte(byte(0x80 | v&0x7f))
+		v >>= 7
+	}
+	w.writeByte(byte(v))
+}
+
+func (w *execWriter) writeBooleanArray(probes []bool) {
+	w.writeVarInt(len(probes))
+	var buffer byte
+	for i, p := range probes {
+		if p {
+			buffer |= 1 << uint(i%8)
+		}
+		if i%8 == 7 {
+			w.writeByte(buffer)
+			buffer = 0
+		}
+	}
+	if len(probes)%8 != 0 {
+		w.writeByte(buffer)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// An execution data file as written by jacoco's ExecutionDataWriter, containing one session and a
+// single class com/android/Foo with id 0x0102030405060708 and 10 probes of which 0, 3 and 9 were hit.
+var testExecFile = []byte{
+	0x01, 0xc0, 0xc0, 0x10, 0x07,
+	0x10, 0x00, 0x04, 'h', 'o', 's', 't',
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+	0x11, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+	0x00, 0x0f, 'c', 'o', 'm', '/', 'a', 'n', 'd', 'r', 'o', 'i', 'd', '/', 'F', 'o', 'o',
+	0x0a, 0x09, 0x02,
+}
+
+func probes(length int, hit ...int) []bool {
+	ret := make([]bool, length)
+	for _, h := range hit {
+		ret[h] = true
+	}
+	return ret
+}
+
+func TestExecRead(t *testing.T) {
+	e := NewExecData()
+	if err := e.Read(bytes.NewReader(testExecFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedSessions := []SessionInfo{{Id: "host", Start: 1, Dump: 2}}
+	if !reflect.DeepEqual(e.Sessions, expectedSessions) {
+		t.Errorf("expected sessions %v, got %v", expectedSessions, e.Sessions)
+	}
+
+	expectedClasses := []*ExecutionData{
+		{Id: 0x0102030405060708, Name: "com/android/Foo", Probes: probes(10, 0, 3, 9)},
+	}
+	if !reflect.DeepEqual(e.Classes(), expectedClasses) {
+		t.Errorf("expected classes %v, got %v", expectedClasses, e.Classes())
+	}
+}
+
+func TestExecRoundTrip(t *testing.T) {
+	e := NewExecData()
+	if err := e.Read(bytes.NewReader(testExecFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := e.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), testExecFile) {
+		t.Errorf("expected:\n%x\ngot:\n%x", testExecFile, buf.Bytes())
+	}
+}
+
+func TestExecMerge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       []ExecutionData
+		expected []*ExecutionData
+		err      bool
+	}{
+		{
+			name: "or probes",
+			in: []ExecutionData{
+				{Id: 1, Name: "a/A", Probes: probes(200, 1, 150)},
+				{Id: 1, Name: "a/A", Probes: probes(200, 2, 150, 199)},
+			},
+			expected: []*ExecutionData{
+				{Id: 1, Name: "a/A", Probes: probes(200, 1, 2, 150, 199)},
+			},
+		},
+		{
+			name: "different classes",
+			in: []ExecutionData{
+				{Id: 2, Name: "b/B", Probes: probes(1)},
+				{Id: 1, Name: "a/A", Probes: probes(3, 1)},
+			},
+			expected: []*ExecutionData{
+				{Id: 1, Name: "a/A", Probes: probes(3, 1)},
+				{Id: 2, Name: "b/B", Probes: probes(1)},
+			},
+		},
+		{
+			name: "incompatible probes",
+			in: []ExecutionData{
+				{Id: 1, Name: "a/A", Probes: probes(3)},
+				{Id: 1, Name: "a/A", Probes: probes(4)},
+			},
+			err: true,
+		},
+		{
+			name: "incompatible names",
+			in: []ExecutionData{
+				{Id: 1, Name: "a/A", Probes: probes(3)},
+				{Id: 1, Name: "a/B", Probes: probes(3)},
+			},
+			err: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Round trip each input through the file format to test the encoding of larger probe arrays.
+			e := NewExecData()
+			var err error
+			for _, in := range testCase.in {
+				single := NewExecData()
+				single.Merge(in)
+				buf := &bytes.Buffer{}
+				if err := single.Write(buf); err != nil {
+					t.Fatal(err)
+				}
+				if err = e.Read(buf); err != nil {
+					break
+				}
+			}
+
+			if testCase.err {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(e.Classes(), testCase.expected) {
+				t.Errorf("expected %v, got %v", testCase.expected, e.Classes())
+			}
+		})
+	}
+}
+
+func TestExecReadErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   []byte
+	}{
+		{"empty", nil},
+		{"no header", testExecFile[5:]},
+		{"bad magic", []byte{0x01, 0xc0, 0xc1, 0x10, 0x07}},
+		{"bad version", []byte{0x01, 0xc0, 0xc0, 0x10, 0x06}},
+		{"truncated", testExecFile[:len(testExecFile)-1]},
+		{"unknown block", append(append([]byte(nil), testExecFile...), 0x42)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if err := NewExecData().Read(bytes.NewReader(testCase.in)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
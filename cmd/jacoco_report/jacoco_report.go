@@ -0,0 +1,321 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// jacoco_report merges jacoco execution data files (.ec) collected from a device and renders
+// coverage reports for the instrumented modules listed in the jacoco-report-manifest.json file
+// written by soong when building with EMMA_INSTRUMENT=true.  It only uses the build outputs and
+// the prebuilt jacoco-cli.jar, so it can run without access to the build system.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type multiString []string
+
+func (m *multiString) String() string     { return strings.Join(*m, ",") }
+func (m *multiString) Set(s string) error { *m = append(*m, s); return nil }
+
+var (
+	manifestFile = flag.String("manifest", "", "jacoco-report-manifest.json file written by soong")
+	outDir       = flag.String("o", "", "output directory for the reports")
+	mergedEc     = flag.String("merged", "", "optional output file for the merged execution data")
+	formats      = flag.String("format", "html,xml,lcov", "comma separated list of report formats")
+	tree         = flag.Bool("tree", false, "generate a single report for all modules instead of one report per module")
+	topDir       = flag.String("top", "", "root of the source tree that paths in the manifest are relative to")
+	jacocoCli    = flag.String("jacoco_cli", "", "path to jacoco-cli.jar, defaults to $ANDROID_HOST_OUT/framework/jacoco-cli.jar")
+	javaCmd      = flag.String("java", "java", "java binary used to run jacoco-cli.jar")
+
+	modules multiString
+)
+
+func init() {
+	flag.Var(&modules, "m", "module to generate a report for, may be repeated.  Defaults to all modules in the manifest")
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: jacoco_report -manifest <manifest> -o <out dir> [-m <module>]... [-tree] <file.ec>...")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *manifestFile == "" || *outDir == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *jacocoCli == "" {
+		*jacocoCli = filepath.Join(os.Getenv("ANDROID_HOST_OUT"), "framework", "jacoco-cli.jar")
+	}
+
+	reportFormats, err := parseFormats(*formats)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Open(*manifestFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	manifest, err := readManifest(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	selected, err := manifest.selectModules(modules)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	execData := NewExecData()
+	for _, ec := range flag.Args() {
+		if err := readExecFile(execData, ec); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "jacoco_report")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	merged := *mergedEc
+	if merged == "" {
+		merged = filepath.Join(tmpDir, "merged.ec")
+	}
+	if err := writeExecFile(execData, merged); err != nil {
+		os.RemoveAll(tmpDir)
+		log.Fatal(err)
+	}
+
+	r := &reporter{
+		execFile: merged,
+		formats:  reportFormats,
+		tmpDir:   tmpDir,
+	}
+
+	if *tree {
+		err = r.report("tree", selected, filepath.Join(*outDir, "tree"))
+	} else {
+		for _, m := range selected {
+			name := m.Name
+			if countVariants(selected, m.Name) > 1 {
+				name = m.Name + "_" + m.Variant
+			}
+			if err = r.report(name, []Module{m}, filepath.Join(*outDir, name)); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		// log.Fatal doesn't run deferred functions, clean up tmpDir first.
+		os.RemoveAll(tmpDir)
+		log.Fatal(err)
+	}
+}
+
+type reportFormats struct {
+	html, xml, lcov bool
+}
+
+func parseFormats(s string) (reportFormats, error) {
+	var ret reportFormats
+	for _, f := range strings.Split(s, ",") {
+		switch strings.TrimSpace(f) {
+		case "html":
+			ret.html = true
+		case "xml":
+			ret.xml = true
+		case "lcov":
+			ret.lcov = true
+		case "":
+		default:
+			return ret, fmt.Errorf("unknown report format %q, expected html, xml or lcov", f)
+		}
+	}
+	if !ret.html && !ret.xml && !ret.lcov {
+		return ret, fmt.Errorf("no report formats selected")
+	}
+	return ret, nil
+}
+
+func countVariants(modules []Module, name string) int {
+	count := 0
+	for _, m := range modules {
+		if m.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+func readExecFile(execData *ExecData, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := execData.Read(f); err != nil {
+		return fmt.Errorf("failed to read %s: %v", file, err)
+	}
+	return nil
+}
+
+func writeExecFile(execData *ExecData, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := execData.Write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %v", file, err)
+	}
+	return f.Close()
+}
+
+type reporter struct {
+	execFile string
+	formats  reportFormats
+	tmpDir   string
+}
+
+// report runs jacoco-cli.jar to generate the html and xml reports for a set of modules, and
+// converts the xml report to LCOV if requested.
+func (r *reporter) report(name string, modules []Module, dir string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	var classFiles, srcDirs []string
+	for _, m := range modules {
+		classFiles = append(classFiles, topPath(m.ClassesJar))
+		for _, srcDir := range m.SrcDirs {
+			srcDirs = append(srcDirs, topPath(srcDir))
+		}
+	}
+	classFiles = firstUnique(classFiles)
+
+	roots := sourceRoots(firstUnique(srcDirs))
+	for i, m := range modules {
+		for j, srcJar := range m.SrcJars {
+			srcJarDir := filepath.Join(r.tmpDir, "srcjars", name, fmt.Sprintf("%d_%d", i, j))
+			if err := extractSrcJar(topPath(srcJar), srcJarDir); err != nil {
+				return err
+			}
+			roots = append(roots, srcJarDir)
+		}
+	}
+
+	xmlFile := filepath.Join(dir, "report.xml")
+	if !r.formats.xml {
+		xmlFile = filepath.Join(r.tmpDir, name+".xml")
+	}
+
+	args := []string{"-jar", *jacocoCli, "report", r.execFile, "--quiet", "--name", name}
+	for _, classFile := range classFiles {
+		args = append(args, "--classfiles", classFile)
+	}
+	for _, root := range roots {
+		args = append(args, "--sourcefiles", root)
+	}
+	if r.formats.html {
+		args = append(args, "--html", filepath.Join(dir, "html"))
+	}
+	if r.formats.xml || r.formats.lcov {
+		args = append(args, "--xml", xmlFile)
+	}
+
+	cmd := exec.Command(*javaCmd, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("jacoco report for %s failed: %v", name, err)
+	}
+
+	if r.formats.lcov {
+		if err := writeLCOVFile(xmlFile, filepath.Join(dir, "report.lcov"), roots); err != nil {
+			return fmt.Errorf("failed to write LCOV report for %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+func writeLCOVFile(xmlFile, lcovFile string, roots []string) error {
+	in, err := os.Open(xmlFile)
+	if err != nil {
+		return err
+	}
+	report, err := parseXMLReport(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(lcovFile)
+	if err != nil {
+		return err
+	}
+
+	err = writeLCOV(out, report, func(rel string) string {
+		for _, root := range roots {
+			if _, err := os.Stat(filepath.Join(root, rel)); err == nil {
+				return filepath.Join(root, rel)
+			}
+		}
+		return rel
+	})
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// topPath returns the path to a file listed in the manifest, which is relative to the root of the
+// source tree.
+func topPath(path string) string {
+	if *topDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(*topDir, path)
+}
+
+func firstUnique(list []string) []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Conversion of jacoco XML reports to the LCOV tracefile format.
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+)
+
+type xmlReport struct {
+	Name     string       `xml:"name,attr"`
+	Packages []xmlPackage `xml:"package"`
+}
+
+type xmlPackage struct {
+	Name        string          `xml:"name,attr"`
+	Classes     []xmlClass      `xml:"class"`
+	SourceFiles []xmlSourceFile `xml:"sourcefile"`
+}
+
+type xmlClass struct {
+	Name           string      `xml:"name,attr"`
+	SourceFileName string      `xml:"sourcefilename,attr"`
+	Methods        []xmlMethod `xml:"method"`
+}
+
+type xmlMethod struct {
+	Name     string       `xml:"name,attr"`
+	Desc     string       `xml:"desc,attr"`
+	Line     int          `xml:"line,attr"`
+	Counters []xmlCounter `xml:"counter"`
+}
+
+type xmlSourceFile struct {
+	Name  string    `xml:"name,attr"`
+	Lines []xmlLine `xml:"line"`
+}
+
+type xmlLine struct {
+	Nr int `xml:"nr,attr"`
+	Mi int `xml:"mi,attr"`
+	Ci int `xml:"ci,attr"`
+	Mb int `xml:"mb,attr"`
+	Cb int `xml:"cb,attr"`
+}
+
+type xmlCounter struct {
+	Type    string `xml:"type,attr"`
+	Missed  int    `xml:"missed,attr"`
+	Covered int    `xml:"covered,attr"`
+}
+
+func parseXMLReport(r io.Reader) (*xmlReport, error) {
+	// The DOCTYPE referencing report.dtd is skipped by the decoder, so the dtd doesn't need to be
+	// available.
+	report := &xmlReport{}
+	if err := xml.NewDecoder(r).Decode(report); err != nil {
+		return nil, fmt.Errorf("failed to parse jacoco xml report: %v", err)
+	}
+	return report, nil
+}
+
+// writeLCOV writes the coverage in a jacoco XML report as an LCOV tracefile.  resolveSource is
+// called with the path of each source file relative to its source root (for example
+// "com/android/foo/Foo.java") and returns the path that should be written to the SF: record.
+func writeLCOV(w io.Writer, report *xmlReport, resolveSource func(string) string) error {
+	out := bufio.NewWriter(w)
+
+	for _, pkg := range report.Packages {
+		functionsBySource := make(map[string][]lcovFunction)
+		for _, class := range pkg.Classes {
+			for _, m := range class.Methods {
+				functionsBySource[class.SourceFileName] = append(functionsBySource[class.SourceFileName],
+					lcovFunction{
+						name: path.Base(class.Name) + "." + m.Name + m.Desc,
+						line: m.Line,
+						hit:  counterCovered(m.Counters, "METHOD") > 0,
+					})
+			}
+		}
+
+		for _, sf := range pkg.SourceFiles {
+			relPath := path.Join(pkg.Name, sf.Name)
+
+			fmt.Fprintf(out, "TN:%s\n", report.Name)
+			fmt.Fprintf(out, "SF:%s\n", resolveSource(relPath))
+
+			functions := functionsBySource[sf.Name]
+			functionsHit := 0
+			for _, f := range functions {
+				fmt.Fprintf(out, "FN:%d,%s\n", f.line, f.name)
+			}
+			for _, f := range functions {
+				hits := 0
+				if f.hit {
+					hits = 1
+					functionsHit++
+				}
+				fmt.Fprintf(out, "FNDA:%d,%s\n", hits, f.name)
+			}
+			fmt.Fprintf(out, "FNF:%d\n", len(functions))
+			fmt.Fprintf(out, "FNH:%d\n", functionsHit)
+
+			branches, branchesHit := 0, 0
+			for _, line := range sf.Lines {
+				for i := 0; i < line.Cb+line.Mb; i++ {
+					taken := 0
+					if i < line.Cb {
+						taken = 1
+						branchesHit++
+					}
+					branches++
+					fmt.Fprintf(out, "BRDA:%d,0,%d,%d\n", line.Nr, i, taken)
+				}
+			}
+			fmt.Fprintf(out, "BRF:%d\n", branches)
+			fmt.Fprintf(out, "BRH:%d\n", branchesHit)
+
+			linesHit := 0
+			for _, line := range sf.Lines {
+				hits := 0
+				if line.Ci > 0 {
+					hits = 1
+					linesHit++
+				}
+				fmt.Fprintf(out, "DA:%d,%d\n", line.Nr, hits)
+			}
+			fmt.Fprintf(out, "LF:%d\n", len(sf.Lines))
+			fmt.Fprintf(out, "LH:%d\n", linesHit)
+
+			fmt.Fprintln(out, "end_of_record")
+		}
+	}
+
+	return out.Flush()
+}
+
+type lcovFunction struct {
+	name string
+	line int
+	hit  bool
+}
+
+func counterCovered(counters []xmlCounter, counterType string) int {
+	for _, c := range counters {
+		if c.Type == counterType {
+			return c.Covered
+		}
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testXMLReport = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="foo">
+  <sessioninfo id="host" start="1" dump="2"/>
+  <package name="com/android/foo">
+    <class name="com/android/foo/Foo" sourcefilename="Foo.java">
+      <method name="&lt;init&gt;" desc="()V" line="3">
+        <counter type="INSTRUCTION" missed="0" covered="3"/>
+        <counter type="METHOD" missed="0" covered="1"/>
+      </method>
+      <method name="bar" desc="(I)I" line="5">
+        <counter type="INSTRUCTION" missed="6" covered="0"/>
+        <counter type="METHOD" missed="1" covered="0"/>
+      </method>
+    </class>
+    <sourcefile name="Foo.java">
+      <line nr="3" mi="0" ci="3" mb="0" cb="0"/>
+      <line nr="5" mi="4" ci="0" mb="1" cb="1"/>
+      <line nr="6" mi="2" ci="0" mb="0" cb="0"/>
+      <counter type="LINE" missed="2" covered="1"/>
+    </sourcefile>
+  </package>
+</report>
+`
+
+const expectedLCOV = `TN:foo
+SF:src/com/android/foo/Foo.java
+FN:3,Foo.<init>()V
+FN:5,Foo.bar(I)I
+FNDA:1,Foo.<init>()V
+FNDA:0,Foo.bar(I)I
+FNF:2
+FNH:1
+BRDA:5,0,0,1
+BRDA:5,0,1,0
+BRF:2
+BRH:1
+DA:3,1
+DA:5,0
+DA:6,0
+LF:3
+LH:1
+end_of_record
+`
+
+func TestWriteLCOV(t *testing.T) {
+	report, err := parseXMLReport(strings.NewReader(testXMLReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = writeLCOV(buf, report, func(rel string) string {
+		return "src/" + rel
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != expectedLCOV {
+		t.Errorf("expected:\n%s\ngot:\n%s", expectedLCOV, buf.String())
+	}
+}
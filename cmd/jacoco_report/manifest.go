@@ -0,0 +1,184 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Module is an entry in the jacoco-report-manifest.json file written by the jacoco_report_manifest
+// singleton in soong.
+type Module struct {
+	Name       string   `json:"name"`
+	Variant    string   `json:"variant"`
+	Dir        string   `json:"dir"`
+	ClassesJar string   `json:"classes_jar"`
+	SrcDirs    []string `json:"src_dirs"`
+	SrcJars    []string `json:"srcjars"`
+}
+
+type Manifest struct {
+	Modules []Module `json:"modules"`
+}
+
+func readManifest(r io.Reader) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := json.NewDecoder(r).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// selectModules returns the modules in the manifest with the given names, or all modules if names is
+// empty.  Every variant of a selected module is returned.
+func (m *Manifest) selectModules(names []string) ([]Module, error) {
+	if len(names) == 0 {
+		return m.Modules, nil
+	}
+
+	var ret []Module
+	for _, name := range names {
+		found := false
+		for _, module := range m.Modules {
+			if module.Name == name {
+				ret = append(ret, module)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("module %q is not in the manifest, was it built with EMMA_INSTRUMENT=true?", name)
+		}
+	}
+	return ret, nil
+}
+
+var packageRegexp = regexp.MustCompile(`^\s*package\s+([\w.]+)`)
+
+// javaPackage returns the package declared in a .java or .kt file, or "" if there is no package
+// declaration.
+func javaPackage(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if match := packageRegexp.FindStringSubmatch(scanner.Text()); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// sourceRoot returns the directory that a source file in dir belongs to when its package is pkg,
+// or "" if dir doesn't end with the directories for the package.
+func sourceRoot(dir, pkg string) string {
+	if pkg == "" {
+		return dir
+	}
+	pkgDir := filepath.FromSlash(strings.Replace(pkg, ".", "/", -1))
+	if dir == pkgDir {
+		return "."
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)+pkgDir) {
+		return ""
+	}
+	return strings.TrimSuffix(dir, string(filepath.Separator)+pkgDir)
+}
+
+// sourceRoots finds the source roots for the source files in dirs by reading the package
+// declarations of the files, which jacoco needs to find the sources of each class.
+func sourceRoots(dirs []string) []string {
+	roots := make(map[string]bool)
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			// Sources may have been removed since the manifest was written, skip them.
+			continue
+		}
+		for _, file := range files {
+			if ext := filepath.Ext(file.Name()); ext != ".java" && ext != ".kt" {
+				continue
+			}
+			f, err := os.Open(filepath.Join(dir, file.Name()))
+			if err != nil {
+				continue
+			}
+			pkg := javaPackage(f)
+			f.Close()
+
+			if root := sourceRoot(filepath.Clean(dir), pkg); root != "" {
+				roots[root] = true
+			}
+		}
+	}
+
+	var ret []string
+	for root := range roots {
+		ret = append(ret, root)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// extractSrcJar extracts the .java and .kt files from a srcjar into dir.
+func extractSrcJar(srcJar, dir string) error {
+	reader, err := zip.OpenReader(srcJar)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		if ext := filepath.Ext(f.Name); ext != ".java" && ext != ".kt" {
+			continue
+		}
+		name := filepath.Clean(f.Name)
+		if filepath.IsAbs(name) || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path %q in %s", f.Name, srcJar)
+		}
+		if err := extractZipFile(f, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to extract %q from %s: %v", f.Name, srcJar, err)
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+		return err
+	}
+
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJavaPackage(t *testing.T) {
+	testCases := []struct {
+		name, in, out string
+	}{
+		{"java", "// Copyright\n\npackage com.android.foo;\n\nclass Foo {}\n", "com.android.foo"},
+		{"kotlin", "package com.android.foo\n\nclass Foo\n", "com.android.foo"},
+		{"default package", "class Foo {}\n", ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := javaPackage(strings.NewReader(testCase.in)); got != testCase.out {
+				t.Errorf("expected %q, got %q", testCase.out, got)
+			}
+		})
+	}
+}
+
+func TestSourceRoot(t *testing.T) {
+	testCases := []struct {
+		dir, pkg, out string
+	}{
+		{"frameworks/foo/src/com/android/foo", "com.android.foo", "frameworks/foo/src"},
+		{"com/android/foo", "com.android.foo", "."},
+		{"frameworks/foo/src", "", "frameworks/foo/src"},
+		{"frameworks/foo/src/com/android/bar", "com.android.foo", ""},
+		{"frameworks/foo/src/xcom/android/foo", "com.android.foo", ""},
+	}
+
+	for _, testCase := range testCases {
+		if got := sourceRoot(testCase.dir, testCase.pkg); got != testCase.out {
+			t.Errorf("sourceRoot(%q, %q): expected %q, got %q", testCase.dir, testCase.pkg, testCase.out, got)
+		}
+	}
+}
+
+func TestSelectModules(t *testing.T) {
+	manifest, err := readManifest(strings.NewReader(`{
+		"modules": [
+			{"name": "bar", "variant": "android_common", "classes_jar": "bar.jar"},
+			{"name": "foo", "variant": "android_common", "classes_jar": "foo.jar"},
+			{"name": "foo", "variant": "linux_glibc_common", "classes_jar": "foo-host.jar"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := manifest.selectModules(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected all 3 modules, got %v", all)
+	}
+
+	foo, err := manifest.selectModules([]string{"foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jars []string
+	for _, m := range foo {
+		jars = append(jars, m.ClassesJar)
+	}
+	if expected := []string{"foo.jar", "foo-host.jar"}; !reflect.DeepEqual(jars, expected) {
+		t.Errorf("expected %v, got %v", expected, jars)
+	}
+
+	if _, err := manifest.selectModules([]string{"baz"}); err == nil {
+		t.Errorf("expected error for missing module")
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resource is a resource in resources.arsc, merged across all of its configurations.
+type Resource struct {
+	ID   uint32
+	Type string
+	Name string
+
+	// Refs are the ids of the resources referenced from the values of the resource.
+	Refs []uint32
+	// Files are the paths in the APK of file based values of the resource, for example
+	// res/drawable-hdpi/icon.png.
+	Files []string
+}
+
+// JavaName returns the name of the field for the resource in the R class.
+func (r *Resource) JavaName() string {
+	return javaResourceName(r.Name)
+}
+
+func javaResourceName(name string) string {
+	return strings.NewReplacer(".", "_", "-", "_", ":", "_").Replace(name)
+}
+
+// ResourceTable holds the resources from resources.arsc by id.
+type ResourceTable map[uint32]*Resource
+
+// readResourceTable parses a resources.arsc file.
+func readResourceTable(buf []byte) (ResourceTable, error) {
+	root, err := readChunk(buf)
+	if err != nil {
+		return nil, err
+	}
+	if root.typ != resTableType {
+		return nil, fmt.Errorf("expected resource table, got chunk type 0x%04x", root.typ)
+	}
+
+	table := make(ResourceTable)
+	var globalStrings []string
+	err = root.children(func(c chunk) error {
+		switch c.typ {
+		case resStringPoolType:
+			globalStrings, err = readStringPool(c)
+			return err
+		case resTablePackageType:
+			return table.readPackage(c, globalStrings)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func (table ResourceTable) readPackage(pkg chunk, globalStrings []string) error {
+	if pkg.headerSize < 0x11c {
+		return fmt.Errorf("truncated package header")
+	}
+	pkgID := le.Uint32(pkg.data[8:])
+	typeStringsOffset := int(le.Uint32(pkg.data[0x10c:]))
+	keyStringsOffset := int(le.Uint32(pkg.data[0x114:]))
+
+	var typeStrings, keyStrings []string
+	return pkg.children(func(c chunk) error {
+		var err error
+		switch c.typ {
+		case resStringPoolType:
+			start := chunkOffset(pkg, c)
+			if start == typeStringsOffset {
+				typeStrings, err = readStringPool(c)
+			} else if start == keyStringsOffset {
+				keyStrings, err = readStringPool(c)
+			}
+			return err
+		case resTableTypeType:
+			return table.readType(c, pkgID, typeStrings, keyStrings, globalStrings)
+		}
+		return nil
+	})
+}
+
+// chunkOffset returns the offset of child from the start of parent.  Both slice the same buffer,
+// so the difference in their capacities is the distance between their starts.
+func chunkOffset(parent, child chunk) int {
+	return cap(parent.data) - cap(child.data)
+}
+
+func (table ResourceTable) readType(c chunk, pkgID uint32, typeStrings, keyStrings, globalStrings []string) error {
+	if c.headerSize < 20 {
+		return fmt.Errorf("truncated type header")
+	}
+	typeID := uint32(c.data[8])
+	flags := c.data[9]
+	entryCount := int(le.Uint32(c.data[12:]))
+	entriesStart := int(le.Uint32(c.data[16:]))
+	if int(typeID) < 1 || int(typeID) > len(typeStrings) {
+		return fmt.Errorf("invalid type id %d", typeID)
+	}
+	typeName := typeStrings[typeID-1]
+
+	readEntry := func(index uint32, offset int) error {
+		start := entriesStart + offset
+		if start+8 > len(c.data) {
+			return fmt.Errorf("entry %d of type %s out of range", index, typeName)
+		}
+		size := int(le.Uint16(c.data[start:]))
+		entryFlags := le.Uint16(c.data[start+2:])
+		key := int(le.Uint32(c.data[start+4:]))
+		if key >= len(keyStrings) {
+			return fmt.Errorf("invalid key %d for entry %d of type %s", key, index, typeName)
+		}
+
+		id := pkgID<<24 | typeID<<16 | index
+		r := table[id]
+		if r == nil {
+			r = &Resource{ID: id, Type: typeName, Name: keyStrings[key]}
+			table[id] = r
+		}
+
+		if entryFlags&tableEntryFlagComplex != 0 {
+			if start+16 > len(c.data) {
+				return fmt.Errorf("truncated map entry %s/%s", typeName, r.Name)
+			}
+			parent := le.Uint32(c.data[start+8:])
+			count := int(le.Uint32(c.data[start+12:]))
+			if parent != 0 {
+				r.Refs = append(r.Refs, parent)
+			}
+			mapStart := start + size
+			if mapStart+12*count > len(c.data) {
+				return fmt.Errorf("truncated map entry %s/%s", typeName, r.Name)
+			}
+			for i := 0; i < count; i++ {
+				m := c.data[mapStart+12*i:]
+				if name := le.Uint32(m); name>>24 != 0 {
+					r.Refs = append(r.Refs, name)
+				}
+				r.addValue(readResValue(m[4:]), globalStrings)
+			}
+		} else {
+			if start+size+8 > len(c.data) {
+				return fmt.Errorf("truncated entry %s/%s", typeName, r.Name)
+			}
+			r.addValue(readResValue(c.data[start+size:]), globalStrings)
+		}
+		return nil
+	}
+
+	offsets := c.data[c.headerSize:]
+	if flags&tableTypeFlagSparse != 0 {
+		if 4*entryCount > len(offsets) {
+			return fmt.Errorf("truncated sparse entries of type %s", typeName)
+		}
+		for i := 0; i < entryCount; i++ {
+			index := uint32(le.Uint16(offsets[4*i:]))
+			offset := int(le.Uint16(offsets[4*i+2:])) * 4
+			if err := readEntry(index, offset); err != nil {
+				return err
+			}
+		}
+	} else {
+		if 4*entryCount > len(offsets) {
+			return fmt.Errorf("truncated entries of type %s", typeName)
+		}
+		for i := 0; i < entryCount; i++ {
+			offset := le.Uint32(offsets[4*i:])
+			if offset == noEntry {
+				continue
+			}
+			if err := readEntry(uint32(i), int(offset)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Resource) addValue(v resValue, globalStrings []string) {
+	if ref := v.ref(); ref != 0 {
+		r.Refs = append(r.Refs, ref)
+	} else if v.dataType == typeString && int(v.data) < len(globalStrings) {
+		if s := globalStrings[v.data]; strings.HasPrefix(s, "res/") {
+			r.Files = append(r.Files, s)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// xmlRefs returns the ids of the resources referenced from the attributes of a compiled XML file,
+// for example the @drawable/icon in android:src="@drawable/icon".
+func xmlRefs(buf []byte) ([]uint32, error) {
+	root, err := readChunk(buf)
+	if err != nil {
+		return nil, err
+	}
+	if root.typ != resXMLType {
+		return nil, fmt.Errorf("expected compiled xml, got chunk type 0x%04x", root.typ)
+	}
+
+	var refs []uint32
+	err = root.children(func(c chunk) error {
+		if c.typ != resXMLStartElementType {
+			return nil
+		}
+		// ResXMLTree_attrExt follows the ResXMLTree_node header.
+		ext := c.data[c.headerSize:]
+		if len(ext) < 20 {
+			return fmt.Errorf("truncated start element")
+		}
+		attributeStart := int(le.Uint16(ext[8:]))
+		attributeSize := int(le.Uint16(ext[10:]))
+		attributeCount := int(le.Uint16(ext[12:]))
+		if attributeSize < 20 || attributeStart+attributeSize*attributeCount > len(ext) {
+			return fmt.Errorf("truncated attributes")
+		}
+		for i := 0; i < attributeCount; i++ {
+			attr := ext[attributeStart+attributeSize*i:]
+			if ref := readResValue(attr[12:]).ref(); ref != 0 {
+				refs = append(refs, ref)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// tinyXML returns a compiled XML file containing a single empty <x/> element, which is used to
+// replace the contents of unused XML resources.
+func tinyXML() []byte {
+	body := &bytes.Buffer{}
+	w := func(v interface{}) { binary.Write(body, le, v) }
+
+	// String pool containing "x" in UTF-8.
+	strs := []byte{1, 1, 'x', 0}
+	w(uint16(resStringPoolType))
+	w(uint16(28))
+	w(uint32(28 + 4 + len(strs)))
+	w(uint32(1))                  // stringCount
+	w(uint32(0))                  // styleCount
+	w(uint32(stringPoolUTF8Flag)) // flags
+	w(uint32(28 + 4))             // stringsStart
+	w(uint32(0))                  // stylesStart
+	w(uint32(0))                  // offset of string 0
+	body.Write(strs)
+
+	// <x>
+	w(uint16(resXMLStartElementType))
+	w(uint16(16))
+	w(uint32(16 + 20))
+	w(uint32(1))          // lineNumber
+	w(uint32(0xffffffff)) // comment
+	w(uint32(0xffffffff)) // ns
+	w(uint32(0))          // name
+	w(uint16(20))         // attributeStart
+	w(uint16(20))         // attributeSize
+	w(uint16(0))          // attributeCount
+	w(uint16(0))          // idIndex
+	w(uint16(0))          // classIndex
+	w(uint16(0))          // styleIndex
+
+	// </x>
+	w(uint16(resXMLEndElementType))
+	w(uint16(16))
+	w(uint32(16 + 8))
+	w(uint32(1))          // lineNumber
+	w(uint32(0xffffffff)) // comment
+	w(uint32(0xffffffff)) // ns
+	w(uint32(0))          // name
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, le, uint16(resXMLType))
+	binary.Write(buf, le, uint16(8))
+	binary.Write(buf, le, uint32(8+body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// tinyPNG is a 1x1 transparent PNG image, which is used to replace the contents of unused PNG
+// resources.
+var tinyPNG = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+	0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4, 0x89, 0x00, 0x00, 0x00,
+	0x0b, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x60, 0x00, 0x02, 0x00,
+	0x00, 0x05, 0x00, 0x01, 0x7a, 0x5e, 0xab, 0x3f, 0x00, 0x00, 0x00, 0x00,
+	0x49, 0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
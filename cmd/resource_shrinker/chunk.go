@@ -0,0 +1,182 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Chunk types from frameworks/base/libs/androidfw/include/androidfw/ResourceTypes.h
+const (
+	resStringPoolType      = 0x0001
+	resTableType           = 0x0002
+	resXMLType             = 0x0003
+	resXMLStartElementType = 0x0102
+	resXMLEndElementType   = 0x0103
+	resTablePackageType    = 0x0200
+	resTableTypeType       = 0x0201
+)
+
+// Res_value data types.
+const (
+	typeReference        = 0x01
+	typeAttribute        = 0x02
+	typeString           = 0x03
+	typeDynamicReference = 0x07
+	typeDynamicAttribute = 0x08
+)
+
+const (
+	stringPoolUTF8Flag = 1 << 8
+
+	tableEntryFlagComplex = 0x0001
+	tableTypeFlagSparse   = 0x01
+
+	noEntry = 0xFFFFFFFF
+)
+
+var le = binary.LittleEndian
+
+// chunk is a ResChunk_header and the bytes it covers.
+type chunk struct {
+	typ        uint16
+	headerSize int
+	data       []byte
+}
+
+// readChunk reads the chunk at the start of buf.
+func readChunk(buf []byte) (chunk, error) {
+	if len(buf) < 8 {
+		return chunk{}, fmt.Errorf("truncated chunk header")
+	}
+	c := chunk{
+		typ:        le.Uint16(buf),
+		headerSize: int(le.Uint16(buf[2:])),
+	}
+	size := int(le.Uint32(buf[4:]))
+	if c.headerSize < 8 || size < c.headerSize || size > len(buf) {
+		return chunk{}, fmt.Errorf("invalid chunk type 0x%04x with header size %d and size %d in %d bytes",
+			c.typ, c.headerSize, size, len(buf))
+	}
+	c.data = buf[:size]
+	return c, nil
+}
+
+// children calls f for each chunk that follows the header of c.
+func (c chunk) children(f func(chunk) error) error {
+	for buf := c.data[c.headerSize:]; len(buf) > 0; {
+		child, err := readChunk(buf)
+		if err != nil {
+			return err
+		}
+		if err := f(child); err != nil {
+			return err
+		}
+		buf = buf[len(child.data):]
+	}
+	return nil
+}
+
+// resValue is a Res_value.
+type resValue struct {
+	dataType uint8
+	data     uint32
+}
+
+func readResValue(buf []byte) resValue {
+	return resValue{dataType: buf[3], data: le.Uint32(buf[4:])}
+}
+
+// ref returns the resource id the value refers to, or 0 if it isn't a reference.
+func (v resValue) ref() uint32 {
+	switch v.dataType {
+	case typeReference, typeAttribute, typeDynamicReference, typeDynamicAttribute:
+		return v.data
+	}
+	return 0
+}
+
+// readStringPool decodes a ResStringPool chunk.
+func readStringPool(c chunk) ([]string, error) {
+	if c.typ != resStringPoolType || c.headerSize < 28 {
+		return nil, fmt.Errorf("expected string pool, got chunk type 0x%04x", c.typ)
+	}
+	count := int(le.Uint32(c.data[8:]))
+	flags := le.Uint32(c.data[16:])
+	stringsStart := int(le.Uint32(c.data[20:]))
+	if c.headerSize+4*count > len(c.data) || stringsStart > len(c.data) {
+		return nil, fmt.Errorf("truncated string pool")
+	}
+
+	ret := make([]string, count)
+	for i := range ret {
+		offset := stringsStart + int(le.Uint32(c.data[c.headerSize+4*i:]))
+		if offset >= len(c.data) {
+			return nil, fmt.Errorf("string %d offset %d out of range", i, offset)
+		}
+		var err error
+		if flags&stringPoolUTF8Flag != 0 {
+			ret[i], err = decodeUTF8String(c.data[offset:])
+		} else {
+			ret[i], err = decodeUTF16String(c.data[offset:])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("string %d: %v", i, err)
+		}
+	}
+	return ret, nil
+}
+
+func decodeUTF8String(buf []byte) (string, error) {
+	// Skip the length in UTF-16 code units, then read the length in bytes.
+	_, buf = decodeUTF8Length(buf)
+	n, buf := decodeUTF8Length(buf)
+	if n > len(buf) {
+		return "", fmt.Errorf("truncated string")
+	}
+	return string(buf[:n]), nil
+}
+
+func decodeUTF8Length(buf []byte) (int, []byte) {
+	if len(buf) == 0 {
+		return 0, buf
+	}
+	if buf[0]&0x80 != 0 && len(buf) > 1 {
+		return int(buf[0]&0x7f)<<8 | int(buf[1]), buf[2:]
+	}
+	return int(buf[0]), buf[1:]
+}
+
+func decodeUTF16String(buf []byte) (string, error) {
+	if len(buf) < 2 {
+		return "", fmt.Errorf("truncated string")
+	}
+	n := int(le.Uint16(buf))
+	buf = buf[2:]
+	if n&0x8000 != 0 && len(buf) >= 2 {
+		n = (n&0x7fff)<<16 | int(le.Uint16(buf))
+		buf = buf[2:]
+	}
+	if 2*n > len(buf) {
+		return "", fmt.Errorf("truncated string")
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = le.Uint16(buf[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// resource_shrinker replaces the contents of resources in an APK that are not referenced from
+// code or from other kept resources with minimal placeholders.  Code references are determined
+// from the R classes in the jar passed to R8 and the -printusage output of R8.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+type multiString []string
+
+func (m *multiString) String() string     { return strings.Join(*m, ",") }
+func (m *multiString) Set(s string) error { *m = append(*m, s); return nil }
+
+var (
+	usageFile   = flag.String("usage", "", "output of R8's -printusage option")
+	classesFile = flag.String("classes", "", "jar containing the R classes passed to R8")
+	outFile     = flag.String("o", "", "output APK")
+	reportFile  = flag.String("report", "", "optional file to write a report of the replaced files to")
+
+	keeps multiString
+)
+
+func init() {
+	flag.Var(&keeps, "keep", "resource to keep in <type>/<name> form, may be repeated")
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: resource_shrinker -usage <usage.txt> -classes <classes.jar> -o <out.apk> [-report <report.txt>] [-keep <type>/<name>]... <in.apk>")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *usageFile == "" || *classesFile == "" || *outFile == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	usage := newCodeUsage()
+
+	classes, err := zip.OpenReader(*classesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	usage.addRClasses(&classes.Reader)
+	classes.Close()
+
+	f, err := os.Open(*usageFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = usage.readUsage(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *usageFile, err)
+	}
+
+	in, err := zip.OpenReader(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+
+	out := &bytes.Buffer{}
+	stripped, err := shrink(&in.Reader, out, usage, keeps)
+	if err != nil {
+		log.Fatalf("%s: %v", flag.Arg(0), err)
+	}
+
+	if err := ioutil.WriteFile(*outFile, out.Bytes(), 0666); err != nil {
+		log.Fatal(err)
+	}
+
+	if *reportFile != "" {
+		report := &bytes.Buffer{}
+		if err := writeReport(report, stripped); err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(*reportFile, report.Bytes(), 0666); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
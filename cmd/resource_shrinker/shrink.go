@@ -0,0 +1,251 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// alwaysKeptTypes are resource types that are referenced in ways that can't be seen from the
+// resource table, R classes or compiled XML, so they are never removed.
+var alwaysKeptTypes = map[string]bool{
+	"attr": true,
+	"id":   true,
+}
+
+// reachable returns the set of resources that are reachable from the roots, following references
+// from the values of resources and from the compiled XML files of resources.
+func reachable(table ResourceTable, roots []uint32, fileRefs func(string) []uint32) map[uint32]bool {
+	seen := make(map[uint32]bool)
+	queue := append([]uint32(nil), roots...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		r := table[id]
+		if r == nil {
+			continue
+		}
+		queue = append(queue, r.Refs...)
+		for _, file := range r.Files {
+			queue = append(queue, fileRefs(file)...)
+		}
+	}
+	return seen
+}
+
+// roots returns the resources that are kept regardless of references from other resources: those
+// referenced from code, those of the always kept types and those requested with -keep.
+func roots(table ResourceTable, usage *CodeUsage, keeps []string) ([]uint32, error) {
+	keep := make(map[string]bool)
+	for _, k := range keeps {
+		if !strings.Contains(k, "/") {
+			return nil, fmt.Errorf("invalid -keep %q, expected <type>/<name>", k)
+		}
+		keep[k] = true
+	}
+
+	var ret []uint32
+	for id, r := range table {
+		if alwaysKeptTypes[r.Type] || keep[r.Type+"/"+r.Name] || usage.referenced(r) {
+			ret = append(ret, id)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret, nil
+}
+
+// placeholder returns the contents that replace an unused file, or nil if the file should be left
+// alone.  aapt2 has already assigned ids to the resources and written them into resources.arsc and
+// the dex files, so unused files can't be removed, only replaced with a minimal valid file.
+func placeholder(name string, size int) []byte {
+	var ret []byte
+	switch {
+	case strings.HasSuffix(name, ".xml"):
+		ret = tinyXML()
+	case strings.HasSuffix(name, ".png"):
+		ret = tinyPNG
+	default:
+		ret = []byte{}
+	}
+	if len(ret) >= size {
+		return nil
+	}
+	return ret
+}
+
+// Stripped is a file whose contents were replaced.
+type Stripped struct {
+	Name    string
+	OldSize int
+	NewSize int
+}
+
+// shrink copies the APK in r to w, replacing the contents of the files of resources that aren't
+// reachable with placeholders.  It returns the files that were replaced.
+func shrink(r *zip.Reader, w io.Writer, usage *CodeUsage, keeps []string) ([]Stripped, error) {
+	contents := make(map[string][]byte)
+	read := func(name string) ([]byte, error) {
+		if buf, ok := contents[name]; ok {
+			return buf, nil
+		}
+		for _, f := range r.File {
+			if f.Name == name {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				buf, err := ioutil.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read %s: %v", name, err)
+				}
+				contents[name] = buf
+				return buf, nil
+			}
+		}
+		return nil, nil
+	}
+
+	arsc, err := read("resources.arsc")
+	if err != nil {
+		return nil, err
+	}
+	if arsc == nil {
+		return nil, fmt.Errorf("missing resources.arsc")
+	}
+	table, err := readResourceTable(arsc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resources.arsc: %v", err)
+	}
+
+	var fileErr error
+	fileRefs := func(name string) []uint32 {
+		if !strings.HasSuffix(name, ".xml") {
+			return nil
+		}
+		buf, err := read(name)
+		if err != nil {
+			fileErr = err
+			return nil
+		}
+		if buf == nil {
+			return nil
+		}
+		refs, err := xmlRefs(buf)
+		if err != nil {
+			fileErr = fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		return refs
+	}
+
+	rootIDs, err := roots(table, usage, keeps)
+	if err != nil {
+		return nil, err
+	}
+	rootIDs = append(rootIDs, fileRefs("AndroidManifest.xml")...)
+	kept := reachable(table, rootIDs, fileRefs)
+	if fileErr != nil {
+		return nil, fileErr
+	}
+
+	// A file can be shared by multiple resources, only replace it if none of them are kept.
+	keptFiles := make(map[string]bool)
+	unusedFiles := make(map[string]bool)
+	for id, r := range table {
+		for _, file := range r.Files {
+			if kept[id] {
+				keptFiles[file] = true
+			} else {
+				unusedFiles[file] = true
+			}
+		}
+	}
+
+	var stripped []Stripped
+	zw := zip.NewWriter(w)
+	for _, f := range r.File {
+		if !unusedFiles[f.Name] || keptFiles[f.Name] {
+			if err := copyFile(zw, f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		replacement := placeholder(f.Name, int(f.UncompressedSize64))
+		if replacement == nil {
+			if err := copyFile(zw, f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fh := f.FileHeader
+		fw, err := zw.CreateHeader(&fh)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(replacement); err != nil {
+			return nil, err
+		}
+		stripped = append(stripped, Stripped{
+			Name:    f.Name,
+			OldSize: int(f.UncompressedSize64),
+			NewSize: len(replacement),
+		})
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return stripped, nil
+}
+
+func copyFile(zw *zip.Writer, f *zip.File) error {
+	fh := f.FileHeader
+	fw, err := zw.CreateHeader(&fh)
+	if err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(fw, rc)
+	return err
+}
+
+// writeReport writes a line for each replaced file followed by a summary line.
+func writeReport(w io.Writer, stripped []Stripped) error {
+	saved := 0
+	for _, s := range stripped {
+		saved += s.OldSize - s.NewSize
+		if _, err := fmt.Fprintf(w, "%s: %d -> %d bytes\n", s.Name, s.OldSize, s.NewSize); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "Replaced %d unused resource files, saved %d uncompressed bytes\n",
+		len(stripped), saved)
+	return err
+}
@@ -0,0 +1,342 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testChunk returns a chunk with the given type, header fields and body.
+func testChunk(typ uint16, header []byte, body []byte) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, le, typ)
+	binary.Write(buf, le, uint16(8+len(header)))
+	binary.Write(buf, le, uint32(8+len(header)+len(body)))
+	buf.Write(header)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func testFields(fields ...interface{}) []byte {
+	buf := &bytes.Buffer{}
+	for _, f := range fields {
+		binary.Write(buf, le, f)
+	}
+	return buf.Bytes()
+}
+
+// testStringPool returns a UTF-8 string pool.
+func testStringPool(strs ...string) []byte {
+	offsets := &bytes.Buffer{}
+	data := &bytes.Buffer{}
+	for _, s := range strs {
+		binary.Write(offsets, le, uint32(data.Len()))
+		data.WriteByte(byte(len(s)))
+		data.WriteByte(byte(len(s)))
+		data.WriteString(s)
+		data.WriteByte(0)
+	}
+	for data.Len()%4 != 0 {
+		data.WriteByte(0)
+	}
+	header := testFields(uint32(len(strs)), uint32(0), uint32(stringPoolUTF8Flag),
+		uint32(28+offsets.Len()), uint32(0))
+	return testChunk(resStringPoolType, header, append(offsets.Bytes(), data.Bytes()...))
+}
+
+// testEntry is an entry in a type chunk built by testTable.  If refs is not empty it is written as
+// a map entry with one item per ref, otherwise as a simple entry with the given value.
+type testEntry struct {
+	key   uint32
+	value resValue
+	refs  []uint32
+}
+
+func testType(id uint8, entries []testEntry) []byte {
+	offsets := &bytes.Buffer{}
+	data := &bytes.Buffer{}
+	for _, e := range entries {
+		binary.Write(offsets, le, uint32(data.Len()))
+		if len(e.refs) > 0 {
+			data.Write(testFields(uint16(16), uint16(tableEntryFlagComplex), e.key, uint32(0), uint32(len(e.refs))))
+			for i, ref := range e.refs {
+				data.Write(testFields(uint32(0x01010000+i), uint16(8), uint8(0), uint8(typeReference), ref))
+			}
+		} else {
+			data.Write(testFields(uint16(8), uint16(0), e.key))
+			data.Write(testFields(uint16(8), uint8(0), e.value.dataType, e.value.data))
+		}
+	}
+	// The header is followed by a ResTable_config, which only contains its size here.
+	header := testFields(id, uint8(0), uint16(0), uint32(len(entries)),
+		uint32(24+offsets.Len()), uint32(4))
+	return testChunk(resTableTypeType, header, append(offsets.Bytes(), data.Bytes()...))
+}
+
+// testTable returns a resources.arsc with a single package with id 0x7f.
+func testTable(globalStrings, typeStrings, keyStrings []string, types ...[]byte) []byte {
+	typePool := testStringPool(typeStrings...)
+	keyPool := testStringPool(keyStrings...)
+
+	name := make([]byte, 256)
+	pkgHeader := testFields(uint32(0x7f))
+	pkgHeader = append(pkgHeader, name...)
+	pkgHeader = append(pkgHeader, testFields(
+		uint32(0x11c), uint32(len(typeStrings)),
+		uint32(0x11c+len(typePool)), uint32(len(keyStrings)))...)
+
+	pkgBody := append(append([]byte(nil), typePool...), keyPool...)
+	for _, typ := range types {
+		pkgBody = append(pkgBody, typ...)
+	}
+
+	body := append(testStringPool(globalStrings...), testChunk(resTablePackageType, pkgHeader, pkgBody)...)
+	return testChunk(resTableType, testFields(uint32(1)), body)
+}
+
+// testXML returns a compiled XML file with a single element that has an attribute referencing
+// each of refs.
+func testXML(refs ...uint32) []byte {
+	attrs := &bytes.Buffer{}
+	for _, ref := range refs {
+		attrs.Write(testFields(uint32(0xffffffff), uint32(0), uint32(0xffffffff),
+			uint16(8), uint8(0), uint8(typeReference), ref))
+	}
+	ext := testFields(uint32(0xffffffff), uint32(0), uint16(20), uint16(20), uint16(len(refs)),
+		uint16(0), uint16(0), uint16(0))
+	start := testChunk(resXMLStartElementType, testFields(uint32(1), uint32(0xffffffff)),
+		append(ext, attrs.Bytes()...))
+	body := append(testStringPool("x"), start...)
+	return testChunk(resXMLType, nil, body)
+}
+
+const (
+	drawableIcon   = 0x7f010000
+	drawableUnused = 0x7f010001
+	drawableShared = 0x7f010002
+	layoutMain     = 0x7f020000
+	layoutUnused   = 0x7f020001
+	styleTheme     = 0x7f030000
+	idButton       = 0x7f040000
+)
+
+func testResources() []byte {
+	return testTable(
+		[]string{
+			"res/drawable/icon.png",
+			"res/drawable/unused.png",
+			"res/layout/main.xml",
+			"res/layout/unused.xml",
+			"res/drawable/shared.png",
+		},
+		[]string{"drawable", "layout", "style", "id"},
+		[]string{"icon", "unused", "shared", "main", "unused_layout", "Theme", "button"},
+		testType(1, []testEntry{
+			{key: 0, value: resValue{typeString, 0}},
+			{key: 1, value: resValue{typeString, 1}},
+			{key: 2, value: resValue{typeString, 4}},
+		}),
+		testType(2, []testEntry{
+			{key: 3, value: resValue{typeString, 2}},
+			{key: 4, value: resValue{typeString, 3}},
+		}),
+		testType(3, []testEntry{
+			{key: 5, refs: []uint32{drawableShared}},
+		}),
+		testType(4, []testEntry{
+			{key: 6, value: resValue{0x12, 0}},
+		}),
+	)
+}
+
+func TestReadResourceTable(t *testing.T) {
+	table, err := readResourceTable(testResources())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ResourceTable{
+		drawableIcon:   {ID: drawableIcon, Type: "drawable", Name: "icon", Files: []string{"res/drawable/icon.png"}},
+		drawableUnused: {ID: drawableUnused, Type: "drawable", Name: "unused", Files: []string{"res/drawable/unused.png"}},
+		drawableShared: {ID: drawableShared, Type: "drawable", Name: "shared", Files: []string{"res/drawable/shared.png"}},
+		layoutMain:     {ID: layoutMain, Type: "layout", Name: "main", Files: []string{"res/layout/main.xml"}},
+		layoutUnused:   {ID: layoutUnused, Type: "layout", Name: "unused_layout", Files: []string{"res/layout/unused.xml"}},
+		styleTheme:     {ID: styleTheme, Type: "style", Name: "Theme", Refs: []uint32{0x01010000, drawableShared}},
+		idButton:       {ID: idButton, Type: "id", Name: "button"},
+	}
+
+	if !reflect.DeepEqual(table, want) {
+		for id, r := range table {
+			t.Errorf("got  0x%08x: %#v", id, *r)
+		}
+		for id, r := range want {
+			t.Errorf("want 0x%08x: %#v", id, *r)
+		}
+	}
+}
+
+func TestXMLRefs(t *testing.T) {
+	refs, err := xmlRefs(testXML(drawableIcon, styleTheme))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint32{drawableIcon, styleTheme}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("want %x, got %x", want, refs)
+	}
+
+	refs, err = xmlRefs(tinyXML())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 0 {
+		t.Errorf("expected no refs in tinyXML, got %x", refs)
+	}
+}
+
+func TestShrink(t *testing.T) {
+	unusedPNG := bytes.Repeat([]byte{0xff}, 1000)
+	unusedXML := append(testXML(drawableUnused), make([]byte, 100)...)
+	files := []struct {
+		name     string
+		contents []byte
+	}{
+		{"AndroidManifest.xml", testXML(styleTheme)},
+		{"resources.arsc", testResources()},
+		{"classes.dex", []byte("dex")},
+		{"res/drawable/icon.png", bytes.Repeat([]byte{0xee}, 1000)},
+		{"res/drawable/unused.png", unusedPNG},
+		{"res/drawable/shared.png", bytes.Repeat([]byte{0xdd}, 1000)},
+		{"res/layout/main.xml", testXML(drawableIcon)},
+		{"res/layout/unused.xml", unusedXML},
+	}
+
+	in := &bytes.Buffer{}
+	zw := zip.NewWriter(in)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(f.contents)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := newCodeUsage()
+	usage.rClasses = map[string]string{
+		"com.example.R$drawable": "drawable",
+		"com.example.R$layout":   "layout",
+		"com.example.R$style":    "style",
+		"com.example.R$id":       "id",
+	}
+	usage.removedClasses["com.example.R$style"] = true
+	usage.removedClasses["com.example.R$id"] = true
+	usage.removedClasses["com.example.R$drawable"] = true
+	usage.removedFields["com.example.R$layout"] = map[string]bool{"unused_layout": true}
+
+	r, err := zip.NewReader(bytes.NewReader(in.Bytes()), int64(in.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	stripped, err := shrink(r, out, usage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantStripped := []Stripped{
+		{"res/drawable/unused.png", len(unusedPNG), len(tinyPNG)},
+		{"res/layout/unused.xml", len(unusedXML), len(tinyXML())},
+	}
+	if !reflect.DeepEqual(stripped, wantStripped) {
+		t.Errorf("want stripped %v, got %v", wantStripped, stripped)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != len(files) {
+		t.Fatalf("want %d files, got %d", len(files), len(zr.File))
+	}
+	for i, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := files[i].contents
+		switch f.Name {
+		case "res/drawable/unused.png":
+			want = tinyPNG
+		case "res/layout/unused.xml":
+			want = tinyXML()
+		}
+		if f.Name != files[i].name || !bytes.Equal(got, want) {
+			t.Errorf("unexpected contents for %s", f.Name)
+		}
+		if f.Method != zip.Deflate {
+			t.Errorf("%s: expected compression method to be preserved", f.Name)
+		}
+	}
+
+	report := &bytes.Buffer{}
+	if err := writeReport(report, stripped); err != nil {
+		t.Fatal(err)
+	}
+	saved := len(unusedPNG) - len(tinyPNG) + len(unusedXML) - len(tinyXML())
+	if !strings.Contains(report.String(), "saved "+strconv.Itoa(saved)+" uncompressed bytes") {
+		t.Errorf("unexpected report:\n%s", report.String())
+	}
+}
+
+func TestShrinkKeep(t *testing.T) {
+	table, err := readResourceTable(testResources())
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := newCodeUsage()
+	usage.rClasses = map[string]string{"com.example.R$drawable": "drawable"}
+	usage.removedClasses["com.example.R$drawable"] = true
+
+	ids, err := roots(table, usage, []string{"drawable/unused"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := reachable(table, ids, func(string) []uint32 { return nil })
+	if !kept[drawableUnused] {
+		t.Errorf("expected drawable/unused to be kept")
+	}
+	if kept[drawableIcon] {
+		t.Errorf("expected drawable/icon to be removed")
+	}
+
+	if _, err := roots(table, usage, []string{"unused"}); err == nil {
+		t.Errorf("expected error for -keep without a type")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// rClassRe matches the name of an R class for a resource type, for example com.example.R$drawable,
+// and captures the resource type.
+var rClassRe = regexp.MustCompile(`^(?:.*[./])?R\$([a-z]+)$`)
+
+// CodeUsage records which resources are referenced from code, based on the R classes in the jar
+// that was passed to R8 and the list of classes and members that R8 removed.
+type CodeUsage struct {
+	// rClasses maps each R class in the program to its resource type.
+	rClasses map[string]string
+	// removedClasses contains the R classes that R8 removed completely.
+	removedClasses map[string]bool
+	// removedFields contains the fields that R8 removed from each R class.
+	removedFields map[string]map[string]bool
+}
+
+func newCodeUsage() *CodeUsage {
+	return &CodeUsage{
+		rClasses:       make(map[string]string),
+		removedClasses: make(map[string]bool),
+		removedFields:  make(map[string]map[string]bool),
+	}
+}
+
+// addRClasses adds the R classes in a jar.
+func (u *CodeUsage) addRClasses(r *zip.Reader) {
+	for _, f := range r.File {
+		if !strings.HasSuffix(f.Name, ".class") {
+			continue
+		}
+		class := strings.Replace(strings.TrimSuffix(f.Name, ".class"), "/", ".", -1)
+		if m := rClassRe.FindStringSubmatch(class); m != nil {
+			u.rClasses[class] = m[1]
+		}
+	}
+}
+
+// readUsage reads the output of R8's -printusage option.  Classes that were removed completely are
+// listed on their own line, classes that had members removed are followed by a ':' and the removed
+// members indented on the following lines:
+//
+//	com.example.R$layout
+//	com.example.R$drawable:
+//	    public static int unused_icon
+func (u *CodeUsage) readUsage(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	class := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			class = strings.TrimSpace(line)
+			if !strings.HasSuffix(class, ":") {
+				u.removedClasses[class] = true
+			}
+			class = strings.TrimSuffix(class, ":")
+			continue
+		}
+
+		if !rClassRe.MatchString(class) {
+			continue
+		}
+		member := strings.Fields(line)
+		if strings.Contains(line, "(") {
+			// Methods aren't interesting, R classes only have a constructor.
+			continue
+		}
+		field := member[len(member)-1]
+		if u.removedFields[class] == nil {
+			u.removedFields[class] = make(map[string]bool)
+		}
+		u.removedFields[class][field] = true
+	}
+	return scanner.Err()
+}
+
+// referenced returns true if the resource is referenced from code.  A resource is referenced
+// unless the field for it was removed from every R class for its type.  Resources whose type has
+// no R class in the program are conservatively treated as referenced.
+func (u *CodeUsage) referenced(r *Resource) bool {
+	found := false
+	for class, typ := range u.rClasses {
+		if typ != r.Type {
+			continue
+		}
+		found = true
+		if !u.removedClasses[class] && !u.removedFields[class][r.JavaName()] {
+			return true
+		}
+	}
+	return !found
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeUsage(t *testing.T) {
+	usage := newCodeUsage()
+	usage.rClasses = map[string]string{
+		"com.example.R$drawable": "drawable",
+		"com.example.R$layout":   "layout",
+		"com.example.R$string":   "string",
+		"com.lib.R$string":       "string",
+	}
+
+	err := usage.readUsage(strings.NewReader(`com.example.Unused
+com.example.R$layout
+com.example.R$drawable:
+    public static int unused_icon
+    public static final int other_unused
+    R$drawable()
+com.example.R$string:
+    public static int shared
+com.example.Foo:
+    public static int unused_icon
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		typ, name string
+		want      bool
+	}{
+		{"drawable", "icon", true},
+		{"drawable", "unused_icon", false},
+		{"drawable", "other.unused", false},
+		{"layout", "main", false},
+		// Still referenced through com.lib.R$string.
+		{"string", "shared", true},
+		// No R class for the type, assume it is referenced.
+		{"xml", "config", true},
+	}
+
+	for _, test := range testCases {
+		r := &Resource{Type: test.typ, Name: test.name}
+		if got := usage.referenced(r); got != test.want {
+			t.Errorf("referenced(%s/%s) = %v, want %v", test.typ, test.name, got, test.want)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -34,6 +35,7 @@ var (
 	keepOutDir    bool
 	copyAllOutput bool
 	depfileOut    string
+	sandboxInputs string
 )
 
 func init() {
@@ -50,6 +52,8 @@ func init() {
 
 	flag.StringVar(&depfileOut, "depfile-out", "",
 		"file path of the depfile to generate. This value will replace '__SBOX_DEPFILE__' in the command and will be treated as an output but won't be added to __SBOX_OUT_FILES__")
+	flag.StringVar(&sandboxInputs, "sandbox-inputs", "",
+		"file listing the declared inputs of the command. If set, the command runs in a temporary tree that only contains symlinks to these inputs, so that reading an undeclared input fails")
 
 }
 
@@ -59,7 +63,7 @@ func usageViolation(violation string) {
 	}
 
 	fmt.Fprintf(os.Stderr,
-		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> --overwrite [--depfile-out depFile] <outputFile> [<outputFile>...]\n"+
+		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> --overwrite [--depfile-out depFile] [--sandbox-inputs inputsFile] <outputFile> [<outputFile>...]\n"+
 			"\n"+
 			"Deletes <outputRoot>,"+
 			"runs <commandToRun>,"+
@@ -139,6 +143,10 @@ func run() error {
 	}
 
 	tempDir, err := ioutil.TempDir(sandboxesRoot, "sbox")
+	if err == nil && sandboxInputs != "" {
+		// The command runs in the input tree, so it needs an absolute path to the output directory.
+		tempDir, err = filepath.Abs(tempDir)
+	}
 
 	for i, filePath := range outputsVarEntries {
 		if !strings.HasPrefix(filePath, "__SBOX_OUT_DIR__/") {
@@ -205,9 +213,39 @@ func run() error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+
+	inputDir := ""
+	if sandboxInputs != "" {
+		inputs, err := readInputsList(sandboxInputs)
+		if err != nil {
+			return err
+		}
+		inputDir, err = ioutil.TempDir(sandboxesRoot, "sbox_in")
+		if err != nil {
+			return fmt.Errorf("Failed to create temp dir: %s", err)
+		}
+		defer func() {
+			if !keepOutDir {
+				os.RemoveAll(inputDir)
+			}
+		}()
+		if err := createInputTree(inputDir, inputs); err != nil {
+			return err
+		}
+		cmd.Dir = inputDir
+	}
+
 	err = cmd.Run()
 
 	if exit, ok := err.(*exec.ExitError); ok && !exit.Success() {
+		if inputDir != "" {
+			// Keep the input tree around so that the user can see which files were available to the command.
+			keepOutDir = true
+			return fmt.Errorf("sbox command (%s) failed with err %#v\n"+
+				"The command ran in %s, which only contains its declared inputs. If it failed to read a file,\n"+
+				"the file needs to be declared as an input of the command.\n",
+				commandDescription, err.Error(), inputDir)
+		}
 		return fmt.Errorf("sbox command (%s) failed with err %#v\n", commandDescription, err.Error())
 	} else if err != nil {
 		return err
@@ -293,3 +331,71 @@ func run() error {
 	// TODO(jeffrygaston) if a process creates more output files than it declares, should there be a warning?
 	return nil
 }
+
+// readInputsList returns the whitespace separated paths listed in the file passed to --sandbox-inputs.
+func readInputsList(file string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// createInputTree creates a symlink in inputDir to each of the inputs, which are relative to the
+// current directory, so that commands run in inputDir can read the declared inputs at the same
+// relative paths and nothing else.  Absolute inputs are reachable from anywhere and are skipped.
+func createInputTree(inputDir string, inputs []string) error {
+	linked := make(map[string]bool)
+
+	var relInputs []string
+	for _, input := range inputs {
+		if filepath.IsAbs(input) {
+			continue
+		}
+		input = filepath.Clean(input)
+		if input == "." || input == ".." || strings.HasPrefix(input, "../") {
+			return fmt.Errorf("sandbox input %q is outside of the current directory", input)
+		}
+		relInputs = append(relInputs, input)
+	}
+
+	// Sort the inputs so that directories are linked before the inputs inside them.
+	sort.Strings(relInputs)
+
+	for _, input := range relInputs {
+
+		// An input inside a directory that is already an input is reachable through the symlink to the
+		// directory, and creating a symlink to it would write into the real directory.
+		if linked[input] || hasLinkedParent(linked, input) {
+			continue
+		}
+
+		target, err := filepath.Abs(input)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(target); err != nil {
+			return fmt.Errorf("sandbox input %q: %s", input, err)
+		}
+
+		link := filepath.Join(inputDir, input)
+		if err := os.MkdirAll(filepath.Dir(link), 0777); err != nil {
+			return err
+		}
+		if err := os.Symlink(target, link); err != nil {
+			return err
+		}
+		linked[input] = true
+	}
+
+	return nil
+}
+
+func hasLinkedParent(linked map[string]bool, input string) bool {
+	for dir := filepath.Dir(input); dir != "."; dir = filepath.Dir(dir) {
+		if linked[dir] {
+			return true
+		}
+	}
+	return false
+}
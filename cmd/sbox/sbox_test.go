@@ -0,0 +1,102 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateInputTree(t *testing.T) {
+	srcDir, err := ioutil.TempDir("", "sbox_test_src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(srcDir)
+
+	for _, file := range []string{"a/in1", "a/in2", "b/in3", "c/d/in4", "undeclared"} {
+		path := filepath.Join(srcDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(file), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(srcDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	inputDir, err := ioutil.TempDir("", "sbox_test_in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(inputDir)
+
+	err = createInputTree(inputDir, []string{"c/d/in4", "a/in1", "./b/in3", "c", "a/in1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"a/in1", "b/in3", "c/d/in4"} {
+		if data, err := ioutil.ReadFile(filepath.Join(inputDir, file)); err != nil {
+			t.Errorf("expected %q to be readable: %s", file, err)
+		} else if string(data) != file {
+			t.Errorf("expected %q to contain %q, got %q", file, file, string(data))
+		}
+	}
+
+	for _, file := range []string{"a/in2", "undeclared"} {
+		if _, err := os.Stat(filepath.Join(inputDir, file)); !os.IsNotExist(err) {
+			t.Errorf("expected undeclared input %q to be missing, got %v", file, err)
+		}
+	}
+
+	// The input inside the linked directory must not have been written into the real directory.
+	if fi, err := os.Lstat(filepath.Join(srcDir, "c/d/in4")); err != nil || fi.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("expected c/d/in4 to be a regular file, got %v, %v", fi, err)
+	}
+}
+
+func TestCreateInputTreeErrors(t *testing.T) {
+	inputDir, err := ioutil.TempDir("", "sbox_test_in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(inputDir)
+
+	testCases := []struct {
+		input string
+		err   string
+	}{
+		{"../foo", `sandbox input "../foo" is outside of the current directory`},
+		{"a/../..", `sandbox input ".." is outside of the current directory`},
+	}
+
+	for _, tc := range testCases {
+		err := createInputTree(inputDir, []string{tc.input})
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%q: expected error %q, got %v", tc.input, tc.err, err)
+		}
+	}
+}
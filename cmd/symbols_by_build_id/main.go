@@ -0,0 +1,108 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool publishes unstripped ELF files by the build ID recorded in their NT_GNU_BUILD_ID note,
+// into a .build-id/xx/yyyy.debug tree and a compressed zip file with the same layout, so that
+// crash tooling can find the symbols for a build ID without knowing where the file was installed.
+// Files without a build ID are skipped.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"android/soong/symbol_inject"
+)
+
+var (
+	outputZip = flag.String("o", "", "Output zip file")
+	outputDir = flag.String("d", "", "Output directory for the .build-id tree")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-o <zip>] [-d <dir>] <elf>|@<list>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *outputZip == "" && *outputDir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var files []string
+	for _, arg := range flag.Args() {
+		if strings.HasPrefix(arg, "@") {
+			data, err := ioutil.ReadFile(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			files = append(files, strings.Fields(string(data))...)
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	store := NewStore()
+	for _, file := range files {
+		id, err := buildId(file)
+		if err != nil {
+			log.Fatalf("%s: %s", file, err)
+		}
+		if id == "" {
+			continue
+		}
+		if existing := store.Add(id, file); existing != "" && existing != file {
+			fmt.Fprintf(os.Stderr, "warning: %s has the same build id %s as %s, skipping\n", file, id, existing)
+		}
+	}
+
+	if *outputDir != "" {
+		if err := store.WriteDir(*outputDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *outputZip != "" {
+		f, err := os.Create(*outputZip)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := bufio.NewWriter(f)
+		if err := store.WriteZip(w); err != nil {
+			log.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func buildId(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return symbol_inject.ElfBuildId(f)
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"android/soong/jar"
+)
+
+// Store maps build IDs to the unstripped ELF files that have them.
+type Store struct {
+	files map[string]string
+}
+
+func NewStore() *Store {
+	return &Store{files: make(map[string]string)}
+}
+
+// Add records file as the symbols for id.  If another file was already recorded for id it is kept,
+// and its name is returned.
+func (s *Store) Add(id, file string) (existing string) {
+	if existing, ok := s.files[id]; ok {
+		return existing
+	}
+	s.files[id] = file
+	return ""
+}
+
+// Len returns the number of build IDs in the store.
+func (s *Store) Len() int {
+	return len(s.files)
+}
+
+func (s *Store) ids() []string {
+	ids := make([]string, 0, len(s.files))
+	for id := range s.files {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// BuildIdPath returns the path of the symbols for a build ID relative to the root of the store,
+// using the same .build-id/xx/yyyy.debug layout that gdb and lldb search.
+func BuildIdPath(id string) (string, error) {
+	if len(id) < 3 {
+		return "", fmt.Errorf("build id %q is too short", id)
+	}
+	return filepath.Join(".build-id", id[:2], id[2:]+".debug"), nil
+}
+
+// WriteZip writes a compressed zip file holding the files under their build ID paths.
+func (s *Store) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, id := range s.ids() {
+		name, err := BuildIdPath(id)
+		if err != nil {
+			return err
+		}
+		fh := &zip.FileHeader{
+			Name:   filepath.ToSlash(name),
+			Method: zip.Deflate,
+		}
+		fh.SetModTime(jar.DefaultTime)
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		if err := copyFrom(fw, s.files[id]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteDir writes the files under their build ID paths in dir.  The files are hard linked if
+// possible, as they are often large.
+func (s *Store) WriteDir(dir string) error {
+	for _, id := range s.ids() {
+		name, err := BuildIdPath(id)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+			return err
+		}
+		os.Remove(dst)
+		if err := os.Link(s.files[id], dst); err == nil {
+			continue
+		}
+		f, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		err = copyFrom(f, s.files[id])
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFrom(w io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildIdPath(t *testing.T) {
+	path, err := BuildIdPath("deadbeef01")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if w := filepath.Join(".build-id", "de", "adbeef01.debug"); path != w {
+		t.Errorf("want %q, got %q", w, path)
+	}
+
+	if _, err := BuildIdPath("de"); err == nil {
+		t.Errorf("expected an error for a short build id")
+	}
+}
+
+func TestStore(t *testing.T) {
+	buildDir, err := ioutil.TempDir("", "symbols_by_build_id_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	write := func(name, contents string) string {
+		file := filepath.Join(buildDir, name)
+		if err := ioutil.WriteFile(file, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	foo := write("foo", "foo symbols")
+	bar := write("bar", "bar symbols")
+	baz := write("baz", "baz symbols")
+
+	store := NewStore()
+	if existing := store.Add("1234abcd", foo); existing != "" {
+		t.Errorf("unexpected existing file %q", existing)
+	}
+	if existing := store.Add("ab01", bar); existing != "" {
+		t.Errorf("unexpected existing file %q", existing)
+	}
+	if existing := store.Add("1234abcd", baz); existing != foo {
+		t.Errorf("expected existing file %q, got %q", foo, existing)
+	}
+	if store.Len() != 2 {
+		t.Errorf("expected 2 build ids, got %d", store.Len())
+	}
+
+	var buf bytes.Buffer
+	if err := store.WriteZip(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Method != zip.Deflate {
+			t.Errorf("expected %s to be compressed", f.Name)
+		}
+	}
+	if w := []string{".build-id/12/34abcd.debug", ".build-id/ab/01.debug"}; !reflect.DeepEqual(names, w) {
+		t.Errorf("want zip entries %q, got %q", w, names)
+	}
+
+	outDir := filepath.Join(buildDir, "out")
+	if err := store.WriteDir(outDir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(outDir, ".build-id", "12", "34abcd.debug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(data), "foo symbols"; g != w {
+		t.Errorf("want %q, got %q", w, g)
+	}
+}
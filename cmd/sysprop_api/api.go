@@ -0,0 +1,150 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteApi writes the API dump of the properties, one line per property sorted by name, in the form
+// "<module>.<api_name> type:<type> access:<access> scope:<scope> [prop_name:<name>] [enum_values:<values>]".
+func WriteApi(w io.Writer, props []Property) error {
+	sorted := append([]Property(nil), props...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+	for i, p := range sorted {
+		if i > 0 && sorted[i-1].Name() == p.Name() {
+			return fmt.Errorf("duplicate property %q", p.Name())
+		}
+		line := fmt.Sprintf("%s type:%s access:%s scope:%s", p.Name(), p.Type, p.Access, p.Scope)
+		if p.PropName != "" {
+			line += " prop_name:" + p.PropName
+		}
+		if p.EnumValues != "" {
+			line += " enum_values:" + p.EnumValues
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadApi reads an API dump written by WriteApi.  Blank lines and lines starting with # are skipped.
+func ReadApi(r io.Reader) (map[string]Property, error) {
+	props := make(map[string]Property)
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		dot := strings.LastIndexByte(fields[0], '.')
+		if dot == -1 {
+			return nil, fmt.Errorf("line %d: invalid property name %q", lineNum, fields[0])
+		}
+		p := Property{Module: fields[0][:dot], ApiName: fields[0][dot+1:]}
+		for _, f := range fields[1:] {
+			colon := strings.IndexByte(f, ':')
+			if colon == -1 {
+				return nil, fmt.Errorf("line %d: expected <key>:<value>, got %q", lineNum, f)
+			}
+			key, value := f[:colon], f[colon+1:]
+			switch key {
+			case "type":
+				p.Type = value
+			case "access":
+				p.Access = value
+			case "scope":
+				p.Scope = value
+			case "prop_name":
+				p.PropName = value
+			case "enum_values":
+				p.EnumValues = value
+			default:
+				return nil, fmt.Errorf("line %d: unknown key %q", lineNum, key)
+			}
+		}
+
+		if _, exists := props[p.Name()]; exists {
+			return nil, fmt.Errorf("line %d: duplicate property %q", lineNum, p.Name())
+		}
+		props[p.Name()] = p
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return props, nil
+}
+
+// CheckCompatible returns the changes from the latest frozen API to the current API that break
+// backward compatibility: removed properties and changes of type or access.  Enum properties may
+// add values but not remove them.
+func CheckCompatible(latest, current map[string]Property) []string {
+	var names []string
+	for name := range latest {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []string
+	for _, name := range names {
+		old := latest[name]
+		cur, ok := current[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: property was removed", name))
+			continue
+		}
+		if old.Type != cur.Type {
+			errs = append(errs, fmt.Sprintf("%s: type changed from %s to %s", name, old.Type, cur.Type))
+		} else if removed := removedEnumValues(old.EnumValues, cur.EnumValues); len(removed) > 0 {
+			errs = append(errs, fmt.Sprintf("%s: enum values %s were removed", name,
+				strings.Join(removed, ", ")))
+		}
+		if old.Access != cur.Access {
+			errs = append(errs, fmt.Sprintf("%s: access changed from %s to %s", name, old.Access, cur.Access))
+		}
+	}
+	return errs
+}
+
+// removedEnumValues returns the values of the | separated list old that are not in cur.
+func removedEnumValues(old, cur string) []string {
+	if old == "" {
+		return nil
+	}
+
+	curValues := make(map[string]bool)
+	for _, v := range strings.Split(cur, "|") {
+		curValues[v] = true
+	}
+
+	var removed []string
+	for _, v := range strings.Split(old, "|") {
+		if !curValues[v] {
+			removed = append(removed, v)
+		}
+	}
+	return removed
+}
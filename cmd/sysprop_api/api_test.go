@@ -0,0 +1,118 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteAndReadApi(t *testing.T) {
+	props := []Property{
+		{Module: "m", ApiName: "b", Type: "Enum", Access: "ReadWrite", Scope: "Public", EnumValues: "x|y"},
+		{Module: "m", ApiName: "a", Type: "Integer", Access: "Readonly", Scope: "Internal", PropName: "ro.a"},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteApi(buf, props); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "m.a type:Integer access:Readonly scope:Internal prop_name:ro.a\n" +
+		"m.b type:Enum access:ReadWrite scope:Public enum_values:x|y\n"
+	if g := buf.String(); g != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, g)
+	}
+
+	api, err := ReadApi(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantApi := map[string]Property{
+		"m.a": props[1],
+		"m.b": props[0],
+	}
+	if !reflect.DeepEqual(api, wantApi) {
+		t.Errorf("expected %#v, got %#v", wantApi, api)
+	}
+
+	err = WriteApi(&bytes.Buffer{}, append(props, props[0]))
+	if g, w := errString(err), `duplicate property "m.b"`; g != w {
+		t.Errorf("expected error %q, got %q", w, g)
+	}
+}
+
+func TestCheckCompatible(t *testing.T) {
+	latest := `
+m.removed type:Integer access:Readonly scope:Public
+m.retyped type:Integer access:Readonly scope:Public
+m.access type:String access:Readonly scope:Public
+m.enum type:Enum access:Readonly scope:Public enum_values:a|b|c
+m.enum_added type:Enum access:Readonly scope:Public enum_values:a
+m.same type:Boolean access:ReadWrite scope:Public
+`
+	current := `
+m.retyped type:Long access:Readonly scope:Public
+m.access type:String access:ReadWrite scope:Public
+m.enum type:Enum access:Readonly scope:Public enum_values:b
+m.enum_added type:Enum access:Readonly scope:Public enum_values:a|b
+m.same type:Boolean access:ReadWrite scope:Internal prop_name:same
+m.new type:Integer access:Readonly scope:Public
+`
+
+	latestApi, err := ReadApi(strings.NewReader(latest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentApi, err := ReadApi(strings.NewReader(current))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"m.access: access changed from Readonly to ReadWrite",
+		"m.enum: enum values a, c were removed",
+		"m.removed: property was removed",
+		"m.retyped: type changed from Integer to Long",
+	}
+	if g := CheckCompatible(latestApi, currentApi); !reflect.DeepEqual(g, want) {
+		t.Errorf("expected %q, got %q", want, g)
+	}
+
+	if g := CheckCompatible(currentApi, currentApi); len(g) != 0 {
+		t.Errorf("expected no errors, got %q", g)
+	}
+}
+
+func TestReadApiErrors(t *testing.T) {
+	testCases := []struct {
+		api string
+		err string
+	}{
+		{"noname type:Integer", `line 1: invalid property name "noname"`},
+		{"m.a type:Integer\nm.a type:Long", `line 2: duplicate property "m.a"`},
+		{"m.a type=Integer", `line 1: expected <key>:<value>, got "type=Integer"`},
+		{"m.a kind:Integer", `line 1: unknown key "kind"`},
+	}
+
+	for _, tc := range testCases {
+		_, err := ReadApi(strings.NewReader(tc.api))
+		if g := errString(err); g != tc.err {
+			t.Errorf("%q: expected error %q, got %q", tc.api, tc.err, g)
+		}
+	}
+}
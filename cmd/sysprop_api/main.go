@@ -0,0 +1,122 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool dumps the API of .sysprop files, and checks that an API dump is backward compatible
+// with the latest frozen API dump.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+var (
+	output = flag.String("o", "", "Output API dump of the .sysprop files")
+	latest = flag.String("latest", "", "Latest frozen API dump to check the API dump against")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -o <api dump> <.sysprop files>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -latest <latest api dump> <current api dump>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *latest != "" {
+		if *output != "" || flag.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		errs, err := check(*latest, flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "%s is not backward compatible with %s:\n", flag.Arg(0), *latest)
+			for _, e := range errs {
+				fmt.Fprintln(os.Stderr, "  "+e)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *output == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := dump(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dump(files []string) error {
+	var props []Property
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		fileProps, err := ParseSysprop(bufio.NewReader(f))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		props = append(props, fileProps...)
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	if err := WriteApi(w, props); err != nil {
+		out.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func check(latestFile, currentFile string) ([]string, error) {
+	latestApi, err := readApiFile(latestFile)
+	if err != nil {
+		return nil, err
+	}
+	currentApi, err := readApiFile(currentFile)
+	if err != nil {
+		return nil, err
+	}
+	return CheckCompatible(latestApi, currentApi), nil
+}
+
+func readApiFile(file string) (map[string]Property, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	api, err := ReadApi(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return api, nil
+}
@@ -0,0 +1,308 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Property is a property declared in a .sysprop file.
+type Property struct {
+	Module     string
+	ApiName    string
+	Type       string
+	Access     string
+	Scope      string
+	PropName   string
+	EnumValues string
+}
+
+// Name returns the name that identifies the property in the API, <module>.<api_name>.
+func (p Property) Name() string {
+	return p.Module + "." + p.ApiName
+}
+
+// field is a field of a text format protocol buffer message, either a scalar value or a nested
+// message.
+type field struct {
+	name   string
+	value  string
+	fields []field
+	line   int
+}
+
+// ParseSysprop parses the properties out of a .sysprop file, which is a text format
+// sysprop.Properties message.  Access and scope default to Readonly and Public like in the
+// sysprop_cpp and sysprop_java generators.
+func ParseSysprop(r io.Reader) ([]Property, error) {
+	fields, err := parseTextProto(r)
+	if err != nil {
+		return nil, err
+	}
+
+	module := ""
+	var props []Property
+	for _, f := range fields {
+		switch f.name {
+		case "owner":
+		case "module":
+			module = f.value
+		case "prop":
+			if f.fields == nil {
+				return nil, fmt.Errorf("line %d: prop must be a message", f.line)
+			}
+			prop := Property{Access: "Readonly", Scope: "Public"}
+			for _, pf := range f.fields {
+				switch pf.name {
+				case "api_name":
+					prop.ApiName = pf.value
+				case "type":
+					prop.Type = pf.value
+				case "access":
+					prop.Access = pf.value
+				case "scope":
+					prop.Scope = pf.value
+				case "prop_name":
+					prop.PropName = pf.value
+				case "enum_values":
+					prop.EnumValues = pf.value
+				case "integer_as_bool", "legacy_prop_name":
+				default:
+					return nil, fmt.Errorf("line %d: unknown prop field %q", pf.line, pf.name)
+				}
+			}
+			if prop.ApiName == "" {
+				return nil, fmt.Errorf("line %d: prop is missing api_name", f.line)
+			}
+			if prop.Type == "" {
+				return nil, fmt.Errorf("line %d: prop %q is missing type", f.line, prop.ApiName)
+			}
+			props = append(props, prop)
+		default:
+			return nil, fmt.Errorf("line %d: unknown field %q", f.line, f.name)
+		}
+	}
+
+	if module == "" {
+		return nil, fmt.Errorf("missing module")
+	}
+	for i := range props {
+		props[i].Module = module
+	}
+
+	return props, nil
+}
+
+type tokenizer struct {
+	r    *bufio.Reader
+	line int
+
+	// a token that was read and pushed back
+	peeked    string
+	hasPeeked bool
+}
+
+// parseTextProto parses the subset of the protocol buffer text format that is used by .sysprop
+// files: scalar fields, nested messages and comments.
+func parseTextProto(r io.Reader) ([]field, error) {
+	t := &tokenizer{r: bufio.NewReader(r), line: 1}
+	fields, err := t.parseMessage(false)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %v", t.line, err)
+	}
+	return fields, nil
+}
+
+func (t *tokenizer) parseMessage(nested bool) ([]field, error) {
+	fields := []field{}
+	for {
+		tok, err := t.next()
+		if err == io.EOF {
+			if nested {
+				return nil, fmt.Errorf("unexpected end of file, expected }")
+			}
+			return fields, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if tok == "}" {
+			if !nested {
+				return nil, fmt.Errorf("unexpected }")
+			}
+			return fields, nil
+		}
+		if !isIdent(tok) {
+			return nil, fmt.Errorf("expected field name, got %q", tok)
+		}
+
+		f := field{name: tok, line: t.line}
+
+		tok, err = t.nextNoEOF()
+		if err != nil {
+			return nil, err
+		}
+		if tok == ":" {
+			if tok, err = t.nextNoEOF(); err != nil {
+				return nil, err
+			}
+		}
+		if tok == "{" {
+			if f.fields, err = t.parseMessage(true); err != nil {
+				return nil, err
+			}
+		} else if tok == "}" || tok == ":" || tok == ";" || tok == "," {
+			return nil, fmt.Errorf("expected value of %q, got %q", f.name, tok)
+		} else {
+			f.value = unquote(tok)
+		}
+		fields = append(fields, f)
+
+		// Fields may be separated by ; or ,
+		if tok, err = t.next(); err == nil && tok != ";" && tok != "," {
+			t.unread(tok)
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+}
+
+func (t *tokenizer) unread(tok string) {
+	t.peeked = tok
+	t.hasPeeked = true
+}
+
+func (t *tokenizer) nextNoEOF() (string, error) {
+	tok, err := t.next()
+	if err == io.EOF {
+		return "", fmt.Errorf("unexpected end of file")
+	}
+	return tok, err
+}
+
+// next returns the next token, which is a punctuation character, a quoted string or a bare word.
+func (t *tokenizer) next() (string, error) {
+	if t.hasPeeked {
+		t.hasPeeked = false
+		return t.peeked, nil
+	}
+
+	for {
+		c, err := t.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case c == '\n':
+			t.line++
+		case unicode.IsSpace(rune(c)):
+		case c == '#':
+			if _, err := t.r.ReadString('\n'); err != nil {
+				return "", err
+			}
+			t.line++
+		case c == '{' || c == '}' || c == ':' || c == ';' || c == ',':
+			return string(c), nil
+		case c == '"' || c == '\'':
+			return t.readString(c)
+		default:
+			word := []byte{c}
+			for {
+				c, err := t.r.ReadByte()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return "", err
+				}
+				if unicode.IsSpace(rune(c)) || strings.IndexByte("{}:;,#\"'", c) != -1 {
+					t.r.UnreadByte()
+					break
+				}
+				word = append(word, c)
+			}
+			return string(word), nil
+		}
+	}
+}
+
+// readString reads a quoted string whose opening quote has been read, returning it with its quotes.
+func (t *tokenizer) readString(quote byte) (string, error) {
+	s := []byte{quote}
+	for {
+		c, err := t.r.ReadByte()
+		if err == io.EOF {
+			return "", fmt.Errorf("unterminated string")
+		} else if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\n':
+			return "", fmt.Errorf("unterminated string")
+		case '\\':
+			next, err := t.r.ReadByte()
+			if err != nil {
+				return "", fmt.Errorf("unterminated string")
+			}
+			s = append(s, c, next)
+		default:
+			s = append(s, c)
+			if c == quote {
+				return string(s), nil
+			}
+		}
+	}
+}
+
+func isIdent(tok string) bool {
+	for i, c := range tok {
+		if !(c == '_' || unicode.IsLetter(c) || (i > 0 && unicode.IsDigit(c))) {
+			return false
+		}
+	}
+	return tok != ""
+}
+
+// unquote removes the quotes and escapes from a quoted string, and returns other tokens unchanged.
+func unquote(tok string) string {
+	if len(tok) < 2 || (tok[0] != '"' && tok[0] != '\'') {
+		return tok
+	}
+	tok = tok[1 : len(tok)-1]
+	if strings.IndexByte(tok, '\\') == -1 {
+		return tok
+	}
+	var s []byte
+	for i := 0; i < len(tok); i++ {
+		if tok[i] == '\\' && i+1 < len(tok) {
+			i++
+			switch tok[i] {
+			case 'n':
+				s = append(s, '\n')
+			case 't':
+				s = append(s, '\t')
+			default:
+				s = append(s, tok[i])
+			}
+		} else {
+			s = append(s, tok[i])
+		}
+	}
+	return string(s)
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSysprop(t *testing.T) {
+	sysprop := `
+# Comments are skipped.
+owner: Platform
+module: "android.sysprop.PlatformProperties"
+
+prop {
+    api_name: "build_id"
+    type: String
+    prop_name: "ro.build.id"
+    scope: Internal
+}
+prop: {
+    api_name: 'mode'; type: Enum; enum_values: "a|b"; access: ReadWrite
+}
+`
+
+	props, err := ParseSysprop(strings.NewReader(sysprop))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Property{
+		{
+			Module:   "android.sysprop.PlatformProperties",
+			ApiName:  "build_id",
+			Type:     "String",
+			Access:   "Readonly",
+			Scope:    "Internal",
+			PropName: "ro.build.id",
+		},
+		{
+			Module:     "android.sysprop.PlatformProperties",
+			ApiName:    "mode",
+			Type:       "Enum",
+			Access:     "ReadWrite",
+			Scope:      "Public",
+			EnumValues: "a|b",
+		},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("expected %#v, got %#v", want, props)
+	}
+}
+
+func TestParseSyspropErrors(t *testing.T) {
+	testCases := []struct {
+		sysprop string
+		err     string
+	}{
+		{`prop { api_name: "a" type: Integer }`, `missing module`},
+		{"module: \"m\"\nprop { type: Integer }", `line 2: prop is missing api_name`},
+		{"module: \"m\"\nprop { api_name: \"a\" }", `line 2: prop "a" is missing type`},
+		{"module: \"m\"\nprop: Integer", `line 2: prop must be a message`},
+		{"module: \"m\"\nprop {\n  api_name: \"a\"\n  kind: Integer\n}", `line 4: unknown prop field "kind"`},
+		{"modules: \"m\"", `line 1: unknown field "modules"`},
+		{"module: \"m\"\nprop {\n  api_name: \"a\"\n", `line 4: unexpected end of file, expected }`},
+		{"module: \"m\n\"", `line 1: unterminated string`},
+		{"module: }", `line 1: expected value of "module", got "}"`},
+	}
+
+	for _, tc := range testCases {
+		_, err := ParseSysprop(strings.NewReader(tc.sysprop))
+		if g := errString(err); g != tc.err {
+			t.Errorf("%q: expected error %q, got %q", tc.sysprop, tc.err, g)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// SoongModule is a test module defined in an Android.bp file, as written to test_modules.json by
+// the test_mapping singleton.
+type SoongModule struct {
+	Dir        string   `json:"dir"`
+	TestConfig string   `json:"test_config"`
+	TestSuites []string `json:"test_suites"`
+}
+
+// IndexEntry is a test that covers a directory.
+type IndexEntry struct {
+	Name         string   `json:"name"`
+	Group        string   `json:"group"`
+	Host         bool     `json:"host,omitempty"`
+	TestConfig   string   `json:"test_config,omitempty"`
+	TestSuites   []string `json:"test_suites,omitempty"`
+	FilePatterns []string `json:"file_patterns,omitempty"`
+
+	// Mapping is the TEST_MAPPING file that lists the test, which is in a different directory
+	// for imported tests.
+	Mapping string `json:"mapping"`
+}
+
+// Check validates the TEST_MAPPING files, keyed by the directory that contains them, and returns
+// an index from each of the directories to the tests that cover it.  Every test must either be a
+// Soong test module with a test config, or a module for which isMakeModule returns true.
+func Check(mappings map[string]*TestMapping, modules map[string]SoongModule,
+	isMakeModule func(name string) bool) (map[string][]IndexEntry, []string) {
+
+	var errs []string
+	for _, dir := range sortedDirs(mappings) {
+		file := filepath.Join(dir, "TEST_MAPPING")
+		m := mappings[dir]
+		for _, group := range m.GroupNames() {
+			for _, test := range m.Groups[group] {
+				if module, ok := modules[test.Name]; ok {
+					if module.TestConfig == "" {
+						errs = append(errs, fmt.Sprintf("%s: %s: test %q defined in %s has no test config",
+							file, group, test.Name, module.Dir))
+					}
+				} else if !isMakeModule(test.Name) {
+					errs = append(errs, fmt.Sprintf("%s: %s: unknown test module %q",
+						file, group, test.Name))
+				}
+			}
+		}
+		for _, i := range m.Imports {
+			if _, ok := mappings[filepath.Clean(i)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: imports: %q does not contain a TEST_MAPPING file",
+					file, i))
+			}
+		}
+	}
+
+	index := make(map[string][]IndexEntry)
+	for dir := range mappings {
+		var entries []IndexEntry
+		visited := make(map[string]bool)
+		var visit func(dir string)
+		visit = func(dir string) {
+			m, ok := mappings[dir]
+			if !ok || visited[dir] {
+				return
+			}
+			visited[dir] = true
+			for group, tests := range m.Groups {
+				for _, test := range tests {
+					module := modules[test.Name]
+					entries = append(entries, IndexEntry{
+						Name:         test.Name,
+						Group:        group,
+						Host:         test.Host,
+						TestConfig:   module.TestConfig,
+						TestSuites:   module.TestSuites,
+						FilePatterns: test.FilePatterns,
+						Mapping:      filepath.Join(dir, "TEST_MAPPING"),
+					})
+				}
+			}
+			for _, i := range m.Imports {
+				visit(filepath.Clean(i))
+			}
+		}
+		visit(dir)
+
+		sort.Slice(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			if a.Group != b.Group {
+				return a.Group < b.Group
+			}
+			return a.Mapping < b.Mapping
+		})
+		index[dir] = entries
+	}
+
+	return index, errs
+}
+
+func sortedDirs(mappings map[string]*TestMapping) []string {
+	var dirs []string
+	for dir := range mappings {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
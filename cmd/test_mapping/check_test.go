@@ -0,0 +1,119 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var checkTestModules = map[string]SoongModule{
+	"foo_test": {
+		Dir:        "a",
+		TestConfig: "out/soong/.intermediates/a/foo_test/android_arm64_armv8-a_core/foo_test.config",
+		TestSuites: []string{"device-tests"},
+	},
+	"bar_test": {
+		Dir:        "b",
+		TestConfig: "b/AndroidTest.xml",
+	},
+	"no_config_test": {
+		Dir: "c",
+	},
+}
+
+func checkTestIsMakeModule(name string) bool {
+	return name == "CtsMakeTestCases"
+}
+
+func TestCheck(t *testing.T) {
+	mappings := map[string]*TestMapping{
+		"a": {
+			Groups: map[string][]Test{
+				"presubmit":  {{Name: "foo_test"}, {Name: "CtsMakeTestCases"}},
+				"postsubmit": {{Name: "foo_test", FilePatterns: []string{".*\\.cpp"}}},
+			},
+			Imports: []string{"b/"},
+		},
+		"b": {
+			Groups:  map[string][]Test{"presubmit": {{Name: "bar_test", Host: true}}},
+			Imports: []string{"a"},
+		},
+	}
+
+	index, errs := Check(mappings, checkTestModules, checkTestIsMakeModule)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %q", errs)
+	}
+
+	fooConfig := checkTestModules["foo_test"].TestConfig
+	expectedA := []IndexEntry{
+		{Name: "CtsMakeTestCases", Group: "presubmit", Mapping: "a/TEST_MAPPING"},
+		{Name: "bar_test", Group: "presubmit", Host: true, TestConfig: "b/AndroidTest.xml", Mapping: "b/TEST_MAPPING"},
+		{Name: "foo_test", Group: "postsubmit", TestConfig: fooConfig, TestSuites: []string{"device-tests"},
+			FilePatterns: []string{".*\\.cpp"}, Mapping: "a/TEST_MAPPING"},
+		{Name: "foo_test", Group: "presubmit", TestConfig: fooConfig, TestSuites: []string{"device-tests"},
+			Mapping: "a/TEST_MAPPING"},
+	}
+	if !reflect.DeepEqual(index["a"], expectedA) {
+		t.Errorf("incorrect index for a:\nwant: %#v\n got: %#v", expectedA, index["a"])
+	}
+
+	// The imports of b form a cycle back to b, which must not duplicate its tests.
+	if len(index["b"]) != len(expectedA) {
+		t.Errorf("expected %d tests for b, got %#v", len(expectedA), index["b"])
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	mappings := map[string]*TestMapping{
+		"a": {
+			Groups: map[string][]Test{
+				"presubmit": {{Name: "foo_tset"}, {Name: "no_config_test"}},
+			},
+			Imports: []string{"missing"},
+		},
+	}
+
+	_, errs := Check(mappings, checkTestModules, checkTestIsMakeModule)
+	expected := []string{
+		`a/TEST_MAPPING: presubmit: unknown test module "foo_tset"`,
+		`a/TEST_MAPPING: presubmit: test "no_config_test" defined in c has no test config`,
+		`a/TEST_MAPPING: imports: "missing" does not contain a TEST_MAPPING file`,
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("incorrect errors:\nwant: %q\n got: %q", expected, errs)
+	}
+}
+
+func TestParseMakeModules(t *testing.T) {
+	modules := make(map[string]bool)
+	err := parseMakeModules(strings.NewReader(`
+LOCAL_PATH := $(call my-dir)
+include $(CLEAR_VARS)
+LOCAL_MODULE := CtsFooTestCases
+include $(CLEAR_VARS)
+  LOCAL_PACKAGE_NAME:=CtsBarTestCases # comment
+LOCAL_MODULE := $(my_test)_host
+`), modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"CtsFooTestCases": true, "CtsBarTestCases": true}
+	if !reflect.DeepEqual(modules, expected) {
+		t.Errorf("incorrect modules:\nwant: %v\n got: %v", expected, modules)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool checks that every test listed in the TEST_MAPPING files of the source tree is a test
+// module with a test config, and writes an index from the directories with a TEST_MAPPING file to
+// the tests that cover them.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	modulesFile     = flag.String("modules", "", "JSON file of the Soong test modules")
+	testMappingList = flag.String("test_mappings", "", "File listing the TEST_MAPPING files")
+	androidMkList   = flag.String("android_mks", "", "File listing the Android.mk files")
+	output          = flag.String("o", "", "Output JSON index of the tests that cover each directory")
+	depFile         = flag.String("d", "", "Output depfile listing the files that were read")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -modules <json> -test_mappings <list> [-android_mks <list>] -o <json> [-d <depfile>]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *modulesFile == "" || *testMappingList == "" || *output == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	deps := []string{*modulesFile}
+
+	var modules map[string]SoongModule
+	data, err := ioutil.ReadFile(*modulesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &modules); err != nil {
+		log.Fatalf("%s: %v", *modulesFile, err)
+	}
+
+	mappingFiles, err := readList(*testMappingList, &deps)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mappings := make(map[string]*TestMapping)
+	var errs []string
+	for _, file := range mappingFiles {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		deps = append(deps, file)
+		m, err := ParseTestMapping(data)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		mappings[filepath.Dir(file)] = m
+	}
+
+	// Only read the Android.mk files if a test is not a Soong module.
+	var makeModules map[string]bool
+	isMakeModule := func(name string) bool {
+		if makeModules == nil {
+			makeModules = make(map[string]bool)
+			if *androidMkList != "" {
+				if err := readMakeModules(*androidMkList, makeModules, &deps); err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
+		return makeModules[name]
+	}
+
+	index, checkErrs := Check(mappings, modules, isMakeModule)
+	errs = append(errs, checkErrs...)
+	if len(errs) > 0 {
+		fmt.Fprintln(os.Stderr, "Invalid TEST_MAPPING files:")
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, "  "+e)
+		}
+		os.Exit(1)
+	}
+
+	data, err = json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*output, append(data, '\n'), 0666); err != nil {
+		log.Fatal(err)
+	}
+
+	if *depFile != "" {
+		if err := writeDepFile(*depFile, *output, deps); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// readList returns the files listed in a file list written by soong_ui, one per line.  A missing
+// list is treated as empty, otherwise it is added to deps.
+func readList(list string, deps *[]string) ([]string, error) {
+	data, err := ioutil.ReadFile(list)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	*deps = append(*deps, list)
+
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+var makeModuleRe = regexp.MustCompile(`^\s*(?:LOCAL_MODULE|LOCAL_PACKAGE_NAME)\s*:?=\s*([^\s$#]+)\s*(?:#.*)?$`)
+
+// readMakeModules adds the names of the modules defined in the Android.mk files listed in list to
+// modules.  Names that are computed from make variables are not supported.
+func readMakeModules(list string, modules map[string]bool, deps *[]string) error {
+	files, err := readList(list, deps)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		*deps = append(*deps, file)
+		err = parseMakeModules(f, modules)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+func parseMakeModules(r io.Reader, modules map[string]bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if match := makeModuleRe.FindStringSubmatch(scanner.Text()); match != nil {
+			modules[match[1]] = true
+		}
+	}
+	return scanner.Err()
+}
+
+func writeDepFile(depFile, output string, deps []string) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s:", escapeDep(output))
+	for _, dep := range deps {
+		fmt.Fprintf(buf, " \\\n  %s", escapeDep(dep))
+	}
+	buf.WriteString("\n")
+	return ioutil.WriteFile(depFile, buf.Bytes(), 0666)
+}
+
+func escapeDep(s string) string {
+	return strings.Replace(s, " ", `\ `, -1)
+}
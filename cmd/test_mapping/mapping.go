@@ -0,0 +1,130 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// TestMapping is the contents of a TEST_MAPPING file.
+type TestMapping struct {
+	// Groups maps the test group names (for example "presubmit") to the tests in the group.
+	Groups map[string][]Test
+
+	// Imports are the directories of the TEST_MAPPING files whose tests are imported.
+	Imports []string
+}
+
+// Test is a test listed in a TEST_MAPPING file.
+type Test struct {
+	Name         string              `json:"name"`
+	Host         bool                `json:"host,omitempty"`
+	Options      []map[string]string `json:"options,omitempty"`
+	FilePatterns []string            `json:"file_patterns,omitempty"`
+	Keywords     []string            `json:"keywords,omitempty"`
+}
+
+type testMappingImport struct {
+	Path string `json:"path"`
+}
+
+// GroupNames returns the sorted names of the test groups.
+func (m *TestMapping) GroupNames() []string {
+	var names []string
+	for name := range m.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTestMapping parses the contents of a TEST_MAPPING file.  TEST_MAPPING files are JSON files
+// that may contain comments starting with // or # outside of strings.
+func ParseTestMapping(data []byte) (*TestMapping, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(stripComments(data), &raw); err != nil {
+		return nil, err
+	}
+
+	m := &TestMapping{Groups: make(map[string][]Test)}
+	for key, value := range raw {
+		if key == "imports" {
+			var imports []testMappingImport
+			if err := json.Unmarshal(value, &imports); err != nil {
+				return nil, fmt.Errorf("imports: %v", err)
+			}
+			for _, i := range imports {
+				if i.Path == "" {
+					return nil, fmt.Errorf("imports: missing path")
+				}
+				m.Imports = append(m.Imports, i.Path)
+			}
+			continue
+		}
+
+		var tests []Test
+		if err := json.Unmarshal(value, &tests); err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		for i, test := range tests {
+			if test.Name == "" {
+				return nil, fmt.Errorf("%s: test %d is missing a name", key, i)
+			}
+		}
+		m.Groups[key] = tests
+	}
+
+	return m, nil
+}
+
+// stripComments replaces the comments in data with spaces, keeping the newlines so that the line
+// numbers in JSON errors are unchanged.
+func stripComments(data []byte) []byte {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	inString, inComment, escaped := false, false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inComment:
+			if c == '\n' {
+				inComment = false
+				out.WriteByte(c)
+			} else {
+				out.WriteByte(' ')
+			}
+		case inString:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+			out.WriteByte(c)
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '#', c == '/' && i+1 < len(data) && data[i+1] == '/':
+			inComment = true
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
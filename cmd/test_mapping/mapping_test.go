@@ -0,0 +1,108 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTestMapping(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		out  *TestMapping
+		err  string
+	}{
+		{
+			name: "groups and imports",
+			in: `
+				// Comments are allowed.
+				{
+				  "presubmit": [
+				    {
+				      "name": "CtsFooTestCases", # here too
+				      "options": [{"include-filter": "android.foo.FooTest"}],
+				      "file_patterns": ["Foo.*\\.java"]
+				    }
+				  ],
+				  "postsubmit": [{"name": "foo_host_test", "host": true}],
+				  "imports": [{"path": "frameworks/base/foo"}]
+				}`,
+			out: &TestMapping{
+				Groups: map[string][]Test{
+					"presubmit": {{
+						Name:         "CtsFooTestCases",
+						Options:      []map[string]string{{"include-filter": "android.foo.FooTest"}},
+						FilePatterns: []string{`Foo.*\.java`},
+					}},
+					"postsubmit": {{Name: "foo_host_test", Host: true}},
+				},
+				Imports: []string{"frameworks/base/foo"},
+			},
+		},
+		{
+			name: "comment markers in strings",
+			in:   `{"presubmit": [{"name": "foo", "keywords": ["http://a#b", "\"//"]}]}`,
+			out: &TestMapping{
+				Groups: map[string][]Test{
+					"presubmit": {{Name: "foo", Keywords: []string{"http://a#b", `"//`}}},
+				},
+			},
+		},
+		{
+			name: "missing name",
+			in:   `{"presubmit": [{"host": true}]}`,
+			err:  "presubmit: test 0 is missing a name",
+		},
+		{
+			name: "missing import path",
+			in:   `{"imports": [{}]}`,
+			err:  "imports: missing path",
+		},
+		{
+			name: "invalid json",
+			in:   `{"presubmit": [{"name": "foo",}]}`,
+			err:  "invalid character",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			out, err := ParseTestMapping([]byte(testCase.in))
+			if testCase.err != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.err) {
+					t.Fatalf("expected error containing %q, got %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(out, testCase.out) {
+				t.Errorf("incorrect test mapping:\nwant: %#v\n got: %#v", testCase.out, out)
+			}
+		})
+	}
+}
+
+func TestStripCommentsKeepsLines(t *testing.T) {
+	in := "{\n// a\n\"b\": 1 # c\n}"
+	out := string(stripComments([]byte(in)))
+	if strings.Count(out, "\n") != strings.Count(in, "\n") || len(out) != len(in) {
+		t.Errorf("stripComments changed the layout of %q to %q", in, out)
+	}
+}
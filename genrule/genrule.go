@@ -96,6 +96,10 @@ type generatorProperties struct {
 
 	// input files to exclude
 	Exclude_srcs []string `android:"path,arch_variant"`
+
+	// Run the command in a temporary directory that only contains the srcs, tool_files and tools, so
+	// that reading any other file fails.  Defaults to false.
+	Sandbox_inputs *bool
 }
 
 type Module struct {
@@ -116,8 +120,9 @@ type Module struct {
 
 	exportedIncludeDirs android.Paths
 
-	outputFiles android.Paths
-	outputDeps  android.Paths
+	outputFiles  android.Paths
+	outputDeps   android.Paths
+	outputGroups map[string]android.Paths
 
 	subName string
 }
@@ -129,6 +134,9 @@ type generateTask struct {
 	out         android.WritablePaths
 	sandboxOuts []string
 	cmd         string
+
+	// named groups of the output files, referenced by other modules with ":module{name}"
+	outGroups map[string]android.Paths
 }
 
 func (g *Module) GeneratedSourceFiles() android.Paths {
@@ -139,6 +147,25 @@ func (g *Module) Srcs() android.Paths {
 	return append(android.Paths{}, g.outputFiles...)
 }
 
+// OutputFiles returns the output files in the output group named by tag, or the output file whose path
+// relative to the generated directory is tag.  An empty tag returns all the output files.
+func (g *Module) OutputFiles(tag string) (android.Paths, error) {
+	if tag == "" {
+		return g.Srcs(), nil
+	}
+	if paths, ok := g.outputGroups[tag]; ok {
+		return append(android.Paths{}, paths...), nil
+	}
+	for _, outputFile := range g.outputFiles {
+		if outputFile.Rel() == tag {
+			return android.Paths{outputFile}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported output tag %q", tag)
+}
+
+var _ android.OutputFileProducer = (*Module)(nil)
+
 func (g *Module) GeneratedHeaderDirs() android.Paths {
 	return g.exportedIncludeDirs
 }
@@ -370,8 +397,12 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	// Escape the command for the shell
 	rawCommand = "'" + strings.Replace(rawCommand, "'", `'\''`, -1) + "'"
 	g.rawCommand = rawCommand
-	sandboxCommand := fmt.Sprintf("$sboxCmd --sandbox-path %s --output-root %s -c %s %s $allouts",
-		sandboxPath, genDir, rawCommand, depfilePlaceholder)
+	sandboxInputsPlaceholder := ""
+	if Bool(g.properties.Sandbox_inputs) {
+		sandboxInputsPlaceholder = "--sandbox-inputs $sandboxInputsRsp "
+	}
+	sandboxCommand := fmt.Sprintf("$sboxCmd --sandbox-path %s --output-root %s %s-c %s %s $allouts",
+		sandboxPath, genDir, sandboxInputsPlaceholder, rawCommand, depfilePlaceholder)
 
 	ruleParams := blueprint.RuleParams{
 		Command:     sandboxCommand,
@@ -382,6 +413,12 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		ruleParams.Deps = blueprint.DepsGCC
 		args = append(args, "depfileArgs")
 	}
+	if Bool(g.properties.Sandbox_inputs) {
+		// the list of inputs is passed through a response file as it can be too long for the command line
+		ruleParams.Rspfile = "$sandboxInputsRsp"
+		ruleParams.RspfileContent = "$sandboxInputs"
+		args = append(args, "sandboxInputsRsp", "sandboxInputs")
+	}
 	g.rule = ctx.Rule(pctx, "generator", ruleParams, args...)
 
 	g.generateSourceFile(ctx, task)
@@ -418,12 +455,17 @@ func (g *Module) generateSourceFile(ctx android.ModuleContext, task generateTask
 		params.Depfile = android.PathForModuleGen(ctx, task.out[0].Rel()+".d")
 		params.Args["depfileArgs"] = "--depfile-out " + depFile.String()
 	}
+	if Bool(g.properties.Sandbox_inputs) {
+		params.Args["sandboxInputsRsp"] = android.PathForModuleOut(ctx, "sandbox_inputs.rsp").String()
+		params.Args["sandboxInputs"] = strings.Join(append(task.in.Strings(), g.deps.Strings()...), " ")
+	}
 
 	ctx.Build(pctx, params)
 
 	for _, outputFile := range task.out {
 		g.outputFiles = append(g.outputFiles, outputFile)
 	}
+	g.outputGroups = task.outGroups
 	g.outputDeps = append(g.outputDeps, task.out[0])
 }
 
@@ -553,6 +595,7 @@ func NewGenRule() *Module {
 			out:         outs,
 			sandboxOuts: sandboxOuts,
 			cmd:         rawCommand,
+			outGroups:   genRuleOutputGroups(ctx, properties.Output_groups, outs),
 		}
 	}
 
@@ -569,6 +612,53 @@ func GenRuleFactory() android.Module {
 type genRuleProperties struct {
 	// names of the output files that will be generated
 	Out []string `android:"arch_variant"`
+
+	// named groups of output files that other modules can reference with ":module{name}", for
+	// example "headers: foo.h bar.h".  Every file in a group must also be listed in out.
+	Output_groups []string
+}
+
+// genRuleOutputGroups parses the output_groups property of a genrule into the paths of the output
+// files in each group.
+func genRuleOutputGroups(ctx android.ModuleContext, groups []string, outs android.WritablePaths) map[string]android.Paths {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	outsByName := make(map[string]android.Path, len(outs))
+	for _, out := range outs {
+		outsByName[out.Rel()] = out
+	}
+
+	ret := make(map[string]android.Paths, len(groups))
+	for _, group := range groups {
+		colon := strings.IndexByte(group, ':')
+		if colon == -1 {
+			ctx.PropertyErrorf("output_groups", "expected \"<name>: <out> [<out>...]\", got %q", group)
+			continue
+		}
+		name := strings.TrimSpace(group[:colon])
+		files := strings.Fields(group[colon+1:])
+		if name == "" || len(files) == 0 {
+			ctx.PropertyErrorf("output_groups", "expected \"<name>: <out> [<out>...]\", got %q", group)
+			continue
+		}
+		if _, exists := ret[name]; exists {
+			ctx.PropertyErrorf("output_groups", "duplicate output group %q", name)
+			continue
+		}
+
+		var paths android.Paths
+		for _, file := range files {
+			if out, ok := outsByName[file]; ok {
+				paths = append(paths, out)
+			} else {
+				ctx.PropertyErrorf("output_groups", "output group %q file %q is not listed in out", name, file)
+			}
+		}
+		ret[name] = paths
+	}
+	return ret
 }
 
 var Bool = proptools.Bool
@@ -17,6 +17,7 @@ package genrule
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -539,6 +540,208 @@ func TestGenruleDefaults(t *testing.T) {
 	}
 }
 
+func TestGenruleSandboxInputs(t *testing.T) {
+	bp := `
+		genrule {
+			name: "gen",
+			tools: ["tool"],
+			tool_files: ["tool_file1"],
+			srcs: [":ins"],
+			out: ["out"],
+			sandbox_inputs: true,
+			cmd: "$(location tool) $(in) > $(out)",
+		}
+
+		genrule {
+			name: "unsandboxed",
+			srcs: ["in1"],
+			out: ["out"],
+			cmd: "cat $(in) > $(out)",
+		}
+	`
+
+	config := android.TestArchConfig(buildDir, nil)
+	ctx := testContext(config, bp, nil)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if errs == nil {
+		_, errs = ctx.PrepareBuildActions(config)
+	}
+	android.FailIfErrored(t, errs)
+
+	gen := ctx.ModuleForTests("gen", "").Rule("generator")
+	if !strings.Contains(gen.RuleParams.Command, "--sandbox-inputs $sandboxInputsRsp ") {
+		t.Errorf("expected --sandbox-inputs in command, got %q", gen.RuleParams.Command)
+	}
+	if g, w := gen.RuleParams.RspfileContent, "$sandboxInputs"; g != w {
+		t.Errorf("want rspfile content %q, got %q", w, g)
+	}
+	if g, w := gen.Args["sandboxInputs"], "in1 in2 out/tool tool_file1"; g != w {
+		t.Errorf("want sandbox inputs %q, got %q", w, g)
+	}
+	if g, w := gen.Args["sandboxInputsRsp"], filepath.Join(buildDir, ".intermediates/gen/sandbox_inputs.rsp"); g != w {
+		t.Errorf("want sandbox inputs rsp file %q, got %q", w, g)
+	}
+
+	unsandboxed := ctx.ModuleForTests("unsandboxed", "").Rule("generator")
+	if strings.Contains(unsandboxed.RuleParams.Command, "--sandbox-inputs") {
+		t.Errorf("unexpected --sandbox-inputs in command %q", unsandboxed.RuleParams.Command)
+	}
+}
+
+func TestGenruleOutputGroups(t *testing.T) {
+	bp := `
+		genrule {
+			name: "gen",
+			out: ["foo.h", "bar.h", "foo.c"],
+			output_groups: [
+				"headers: foo.h bar.h",
+				"srcs: foo.c",
+			],
+			cmd: "touch $(out)",
+		}
+
+		genrule {
+			name: "headers",
+			srcs: [":gen{headers}"],
+			out: ["out"],
+			cmd: "cat $(in) > $(out)",
+		}
+
+		genrule {
+			name: "single",
+			srcs: [
+				":gen{srcs}",
+				":gen{bar.h}",
+			],
+			out: ["out"],
+			cmd: "cat $(locations :gen{srcs}) $(location :gen{bar.h}) > $(out)",
+		}
+	`
+
+	config := android.TestArchConfig(buildDir, nil)
+	ctx := testContext(config, bp, nil)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if errs == nil {
+		_, errs = ctx.PrepareBuildActions(config)
+	}
+	android.FailIfErrored(t, errs)
+
+	rels := func(paths android.Paths) []string {
+		var ret []string
+		for _, p := range paths {
+			ret = append(ret, p.Rel())
+		}
+		return ret
+	}
+
+	headers := ctx.ModuleForTests("headers", "").Rule("generator")
+	if g, w := rels(headers.Inputs), []string{"foo.h", "bar.h"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want headers inputs %q, got %q", w, g)
+	}
+
+	single := ctx.ModuleForTests("single", "")
+	if g, w := rels(single.Rule("generator").Inputs), []string{"foo.c", "bar.h"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want single inputs %q, got %q", w, g)
+	}
+	genDir := filepath.Join(buildDir, ".intermediates", "gen", "gen")
+	expectedCmd := "'cat " + filepath.Join(genDir, "foo.c") + " " + filepath.Join(genDir, "bar.h") +
+		" > __SBOX_OUT_FILES__'"
+	if g := single.Module().(*Module).rawCommand; g != expectedCmd {
+		t.Errorf("want cmd %q, got %q", expectedCmd, g)
+	}
+}
+
+func TestGenruleOutputGroupsErrors(t *testing.T) {
+	testcases := []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "invalid group",
+			bp: `
+				genrule {
+					name: "gen",
+					out: ["foo.h"],
+					output_groups: ["headers"],
+					cmd: "touch $(out)",
+				}`,
+			err: `expected "<name>: <out> [<out>...]", got "headers"`,
+		},
+		{
+			name: "duplicate group",
+			bp: `
+				genrule {
+					name: "gen",
+					out: ["foo.h"],
+					output_groups: ["headers: foo.h", "headers: foo.h"],
+					cmd: "touch $(out)",
+				}`,
+			err: `duplicate output group "headers"`,
+		},
+		{
+			name: "file not in out",
+			bp: `
+				genrule {
+					name: "gen",
+					out: ["foo.h"],
+					output_groups: ["headers: bar.h"],
+					cmd: "touch $(out)",
+				}`,
+			err: `output group "headers" file "bar.h" is not listed in out`,
+		},
+		{
+			name: "unsupported tag",
+			bp: `
+				genrule {
+					name: "gen",
+					out: ["foo.h"],
+					cmd: "touch $(out)",
+				}
+
+				genrule {
+					name: "use",
+					srcs: [":gen{headers}"],
+					out: ["out"],
+					cmd: "cat $(in) > $(out)",
+				}`,
+			err: `path dependency "gen": unsupported output tag "headers"`,
+		},
+		{
+			name: "tag on source file producer",
+			bp: `
+				genrule {
+					name: "use",
+					srcs: [":ins{headers}"],
+					out: ["out"],
+					cmd: "cat $(in) > $(out)",
+				}`,
+			err: `"ins" is not an output file producing module`,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			config := android.TestArchConfig(buildDir, nil)
+			ctx := testContext(config, test.bp, nil)
+			_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+			if errs == nil {
+				_, errs = ctx.PrepareBuildActions(config)
+			}
+			if len(errs) != 1 {
+				t.Errorf("want 1 error, got %d errors:", len(errs))
+				for _, err := range errs {
+					t.Errorf("   %s", err.Error())
+				}
+				t.FailNow()
+			}
+			if !strings.Contains(errs[0].Error(), test.err) {
+				t.Fatalf("want %q, got %q", test.err, errs[0].Error())
+			}
+		})
+	}
+}
+
 type testTool struct {
 	android.ModuleBase
 	outputFile android.Path
@@ -230,3 +230,35 @@ func aapt2Convert(ctx android.ModuleContext, out android.WritablePath, in androi
 		Description: "convert to proto",
 	})
 }
+
+var shrinkResourcesRule = pctx.AndroidStaticRule("shrinkResources",
+	blueprint.RuleParams{
+		Command: `rm -f $out $out.tmp $report && ` +
+			`${config.ResourceShrinkerCmd} -usage $usage -classes $classes -report $report $keepFlags -o $out.tmp $in && ` +
+			`${config.Aapt2Cmd} optimize -o $out $out.tmp && rm -f $out.tmp && ` +
+			`echo "$in: $$(wc -c < $in) -> $$(wc -c < $out) bytes" >> $report`,
+		CommandDeps: []string{"${config.ResourceShrinkerCmd}", "${config.Aapt2Cmd}"},
+	},
+	"usage", "classes", "report", "keepFlags")
+
+// shrinkResources replaces the contents of the resources in the resource package in that are not
+// referenced according to the output of R8's -printusage option, and then runs aapt2 optimize on
+// the result.  A report of the replaced files and the bytes saved is written to report.
+func shrinkResources(ctx android.ModuleContext, out, report android.WritablePath, in, usage, classes android.Path,
+	keeps []string) {
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:           shrinkResourcesRule,
+		Description:    "shrink resources",
+		Input:          in,
+		Implicits:      android.Paths{usage, classes},
+		Output:         out,
+		ImplicitOutput: report,
+		Args: map[string]string{
+			"usage":     usage.String(),
+			"classes":   classes.String(),
+			"report":    report.String(),
+			"keepFlags": android.JoinWithPrefix(keeps, "-keep "),
+		},
+	})
+}
@@ -270,6 +270,10 @@ func (app *AndroidApp) AndroidMk() android.AndroidMkData {
 				if app.proguardDictionary != nil {
 					fmt.Fprintln(w, "LOCAL_SOONG_PROGUARD_DICT :=", app.proguardDictionary.String())
 				}
+				if app.shrinkResourcesReport != nil {
+					fmt.Fprintln(w, "$(call dist-for-goals,droidcore,"+
+						app.shrinkResourcesReport.String()+":shrink_resources/"+app.Name()+".txt)")
+				}
 
 				if app.Name() == "framework-res" {
 					fmt.Fprintln(w, "LOCAL_MODULE_PATH := $(TARGET_OUT_JAVA_LIBRARIES)")
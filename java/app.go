@@ -111,6 +111,9 @@ type AndroidApp struct {
 
 	bundleFile android.Path
 
+	// report of the resources replaced by optimize.shrink_resources
+	shrinkResourcesReport android.Path
+
 	// the install APK name is normally the same as the module name, but can be overridden with PRODUCT_PACKAGE_NAME_OVERRIDES.
 	installApkName string
 
@@ -246,6 +249,12 @@ func (a *AndroidApp) aaptBuildActions(ctx android.ModuleContext) {
 		aaptLinkFlags = append(aaptLinkFlags, "--rename-manifest-package "+manifestPackageName)
 	}
 
+	if Bool(a.deviceProperties.Optimize.Shrink_resources) {
+		// Keep references to R fields in the bytecode instead of inlining the ids, so that R8 can
+		// report which resources are unused.  Code that switches on resource ids won't compile.
+		aaptLinkFlags = append(aaptLinkFlags, "--non-final-ids")
+	}
+
 	aaptLinkFlags = append(aaptLinkFlags, a.additionalAaptFlags...)
 
 	a.aapt.splitNames = a.appProperties.Package_splits
@@ -293,6 +302,33 @@ func (a *AndroidApp) dexBuildActions(ctx android.ModuleContext) android.Path {
 	return a.maybeStrippedDexJarFile
 }
 
+// shrinkResourcesBuildActions returns the resource package to use in the APK, which has the
+// contents of unused resources replaced if optimize.shrink_resources is set.
+func (a *AndroidApp) shrinkResourcesBuildActions(ctx android.ModuleContext) android.Path {
+	opt := &a.deviceProperties.Optimize
+	if !Bool(opt.Shrink_resources) {
+		return a.exportPackage
+	}
+
+	if !a.deviceProperties.EffectiveOptimizeEnabled() || !Bool(opt.Shrink) {
+		ctx.PropertyErrorf("optimize.shrink_resources", "requires optimize.enabled and optimize.shrink")
+		return a.exportPackage
+	}
+	if a.proguardUsage == nil {
+		// The usage report of R8 is only written when the app's code is dexed.
+		ctx.PropertyErrorf("optimize.shrink_resources", "requires the app's code to be compiled to dex and shrunk by R8")
+		return a.exportPackage
+	}
+
+	shrunkPackage := android.PathForModuleOut(ctx, "shrunk", "package-res.apk")
+	report := android.PathForModuleOut(ctx, "shrink_resources.txt")
+	shrinkResources(ctx, shrunkPackage, report, a.exportPackage, a.proguardUsage, a.proguardInputJar,
+		opt.Keep_resources)
+	a.shrinkResourcesReport = report
+
+	return shrunkPackage
+}
+
 func (a *AndroidApp) jniBuildActions(jniLibs []jniLib, ctx android.ModuleContext) android.WritablePath {
 	var jniJarFile android.WritablePath
 	if len(jniLibs) > 0 {
@@ -416,6 +452,8 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 
 	dexJarFile := a.dexBuildActions(ctx)
 
+	resPackage := a.shrinkResourcesBuildActions(ctx)
+
 	jniLibs, certificateDeps := a.collectAppDeps(ctx)
 	jniJarFile := a.jniBuildActions(jniLibs, ctx)
 
@@ -428,7 +466,7 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	// Build a final signed app package.
 	// TODO(jungjw): Consider changing this to installApkName.
 	packageFile := android.PathForModuleOut(ctx, ctx.ModuleName()+".apk")
-	CreateAppPackage(ctx, packageFile, a.exportPackage, jniJarFile, dexJarFile, certificates)
+	CreateAppPackage(ctx, packageFile, resPackage, jniJarFile, dexJarFile, certificates)
 	a.outputFile = packageFile
 
 	for _, split := range a.aapt.splits {
@@ -440,7 +478,7 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 
 	// Build an app bundle.
 	bundleFile := android.PathForModuleOut(ctx, "base.zip")
-	BuildBundleModule(ctx, bundleFile, a.exportPackage, jniJarFile, dexJarFile)
+	BuildBundleModule(ctx, bundleFile, resPackage, jniJarFile, dexJarFile)
 	a.bundleFile = bundleFile
 
 	// Install the app package.
@@ -553,6 +591,13 @@ func (a *AndroidTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	a.data = android.PathsForModuleSrc(ctx, a.testProperties.Data)
 }
 
+func (a *AndroidTest) TestModuleInfo() *android.TestModuleInfo {
+	return &android.TestModuleInfo{
+		TestSuites: a.testProperties.Test_suites,
+		TestConfig: a.testConfig,
+	}
+}
+
 func (a *AndroidTest) DepsMutator(ctx android.BottomUpMutatorContext) {
 	a.AndroidApp.DepsMutator(ctx)
 	if a.appTestProperties.Instrumentation_for != nil {
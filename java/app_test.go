@@ -17,7 +17,9 @@ package java
 import (
 	"android/soong/android"
 	"android/soong/cc"
+	"android/soong/dexpreopt"
 
+	"bytes"
 	"fmt"
 	"path/filepath"
 	"reflect"
@@ -134,6 +136,102 @@ func TestAppSplits(t *testing.T) {
 	}
 }
 
+func TestShrinkResources(t *testing.T) {
+	ctx := testApp(t, `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			optimize: {
+				shrink_resources: true,
+				keep_resources: ["drawable/dynamic"],
+			},
+		}
+
+		android_app {
+			name: "bar",
+			srcs: ["a.java"],
+		}
+	`)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+
+	link := foo.Output("package-res.apk")
+	if !strings.Contains(link.Args["flags"], "--non-final-ids") {
+		t.Errorf("expected aapt2 link flags to contain --non-final-ids, got %q", link.Args["flags"])
+	}
+
+	r8 := foo.Rule("r8")
+	usage := foo.Output("proguard_usage.txt")
+	if usage.Rule != r8.Rule {
+		t.Errorf("expected proguard_usage.txt to be an output of r8")
+	}
+	if !strings.Contains(r8.Args["r8Flags"], "-printusage "+usage.Output.String()) {
+		t.Errorf("expected r8 flags to contain -printusage, got %q", r8.Args["r8Flags"])
+	}
+
+	shrink := foo.Output("shrunk/package-res.apk")
+	if g, w := shrink.Input.String(), link.Output.String(); g != w {
+		t.Errorf("want shrink resources input %q, got %q", w, g)
+	}
+	if !android.InList(usage.Output.String(), shrink.Implicits.Strings()) {
+		t.Errorf("expected shrink resources implicits to contain %q, got %q", usage.Output.String(),
+			shrink.Implicits.Strings())
+	}
+	if g, w := shrink.Args["keepFlags"], "-keep drawable/dynamic"; g != w {
+		t.Errorf("want keepFlags %q, got %q", w, g)
+	}
+	report := foo.Output("shrink_resources.txt")
+
+	// Test that the report of the bytes saved is dist'ed
+	app := foo.Module().(*AndroidApp)
+	data := app.AndroidMk()
+	androidMk := &bytes.Buffer{}
+	for _, extra := range data.Extra {
+		extra(androidMk, data.OutputFile.Path())
+	}
+	expectedDist := "$(call dist-for-goals,droidcore," + report.Output.String() + ":shrink_resources/foo.txt)"
+	if !strings.Contains(androidMk.String(), expectedDist) {
+		t.Errorf("expected %q in the Android.mk of foo, got:\n%s", expectedDist, androidMk.String())
+	}
+
+	combine := foo.Output("foo-unsigned.apk")
+	if !android.InList(shrink.Output.String(), combine.Inputs.Strings()) {
+		t.Errorf("expected foo-unsigned.apk inputs to contain %q, got %q", shrink.Output.String(),
+			combine.Inputs.Strings())
+	}
+
+	bar := ctx.ModuleForTests("bar", "android_common")
+	if shrink := bar.MaybeOutput("shrunk/package-res.apk"); shrink.Rule != nil {
+		t.Errorf("expected no resource shrinking without shrink_resources")
+	}
+	if strings.Contains(bar.Output("package-res.apk").Args["flags"], "--non-final-ids") {
+		t.Errorf("expected no --non-final-ids without shrink_resources")
+	}
+}
+
+func TestShrinkResourcesWithoutDex(t *testing.T) {
+	config := testConfig(nil)
+	ctx := testContext(config, `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			installable: false,
+			optimize: {
+				shrink_resources: true,
+			},
+		}
+	`, nil)
+
+	pathCtx := android.PathContextForTesting(config, nil)
+	setDexpreoptTestGlobalConfig(config, dexpreopt.GlobalConfigForTests(pathCtx))
+
+	ctx.Register()
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp", "prebuilts/sdk/Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfNoMatchingErrors(t, `optimize.shrink_resources: requires the app's code to be compiled to dex`, errs)
+}
+
 func TestResourceDirs(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -1034,13 +1132,13 @@ func TestEmbedNotice(t *testing.T) {
 	if len(mergeNotices.Inputs) != 3 {
 		t.Errorf("number of input notice files: expected = 3, actual = %q", noticeInputs)
 	}
-	if !inList("APP_NOTICE", noticeInputs) {
+	if !android.InList("APP_NOTICE", noticeInputs) {
 		t.Errorf("APP_NOTICE is missing from notice files, %q", noticeInputs)
 	}
-	if !inList("LIB_NOTICE", noticeInputs) {
+	if !android.InList("LIB_NOTICE", noticeInputs) {
 		t.Errorf("LIB_NOTICE is missing from notice files, %q", noticeInputs)
 	}
-	if !inList("GENRULE_NOTICE", noticeInputs) {
+	if !android.InList("GENRULE_NOTICE", noticeInputs) {
 		t.Errorf("GENRULE_NOTICE is missing from notice files, %q", noticeInputs)
 	}
 	// aapt2 flags should include -A <NOTICE dir> so that its contents are put in the APK's /assets.
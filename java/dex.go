@@ -185,14 +185,23 @@ func (j *Module) compileDex(ctx android.ModuleContext, flags javaBuilderFlags,
 	if useR8 {
 		proguardDictionary := android.PathForModuleOut(ctx, "proguard_dictionary")
 		j.proguardDictionary = proguardDictionary
+		implicitOutputs := android.WritablePaths{proguardDictionary}
 		r8Flags, r8Deps := j.r8Flags(ctx, flags)
+		if Bool(j.deviceProperties.Optimize.Shrink_resources) {
+			// The list of removed R class fields is used to find unused resources.
+			proguardUsage := android.PathForModuleOut(ctx, "proguard_usage.txt")
+			j.proguardUsage = proguardUsage
+			j.proguardInputJar = classesJar
+			r8Flags = append(r8Flags, "-printusage "+proguardUsage.String())
+			implicitOutputs = append(implicitOutputs, proguardUsage)
+		}
 		ctx.Build(pctx, android.BuildParams{
-			Rule:           r8,
-			Description:    "r8",
-			Output:         javalibJar,
-			ImplicitOutput: proguardDictionary,
-			Input:          classesJar,
-			Implicits:      r8Deps,
+			Rule:            r8,
+			Description:     "r8",
+			Output:          javalibJar,
+			ImplicitOutputs: implicitOutputs,
+			Input:           classesJar,
+			Implicits:       r8Deps,
 			Args: map[string]string{
 				"r8Flags":  strings.Join(r8Flags, " "),
 				"zipFlags": zipFlags,
@@ -31,6 +31,7 @@ type hiddenAPI struct {
 	flagsCSVPath    android.Path
 	metadataCSVPath android.Path
 	bootDexJarPath  android.Path
+	classesJarPath  android.Path
 }
 
 func (h *hiddenAPI) flagsCSV() android.Path {
@@ -45,10 +46,15 @@ func (h *hiddenAPI) bootDexJar() android.Path {
 	return h.bootDexJarPath
 }
 
+func (h *hiddenAPI) classesJar() android.Path {
+	return h.classesJarPath
+}
+
 type hiddenAPIIntf interface {
 	flagsCSV() android.Path
 	metadataCSV() android.Path
 	bootDexJar() android.Path
+	classesJar() android.Path
 }
 
 var _ hiddenAPIIntf = (*hiddenAPI)(nil)
@@ -80,6 +86,7 @@ func (h *hiddenAPI) hiddenAPI(ctx android.ModuleContext, dexJar android.ModuleOu
 			hiddenAPIGenerateCSV(ctx, flagsCSV, metadataCSV, implementationJar)
 			h.flagsCSVPath = flagsCSV
 			h.metadataCSVPath = metadataCSV
+			h.classesJarPath = implementationJar
 
 			// If this module is actually on the boot jars list and not providing
 			// hiddenapi information for a module on the boot jars list then encode
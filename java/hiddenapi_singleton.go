@@ -15,6 +15,9 @@
 package java
 
 import (
+	"sort"
+	"strings"
+
 	"android/soong/android"
 )
 
@@ -23,9 +26,10 @@ func init() {
 }
 
 type hiddenAPISingletonPathsStruct struct {
-	stubFlags android.OutputPath
-	flags     android.OutputPath
-	metadata  android.OutputPath
+	stubFlags     android.OutputPath
+	flags         android.OutputPath
+	flagsByModule android.OutputPath
+	metadata      android.OutputPath
 }
 
 var hiddenAPISingletonPathsKey = android.NewOnceKey("hiddenAPISingletonPathsKey")
@@ -36,9 +40,10 @@ var hiddenAPISingletonPathsKey = android.NewOnceKey("hiddenAPISingletonPathsKey"
 func hiddenAPISingletonPaths(ctx android.PathContext) hiddenAPISingletonPathsStruct {
 	return ctx.Config().Once(hiddenAPISingletonPathsKey, func() interface{} {
 		return hiddenAPISingletonPathsStruct{
-			stubFlags: android.PathForOutput(ctx, "hiddenapi", "hiddenapi-stub-flags.txt"),
-			flags:     android.PathForOutput(ctx, "hiddenapi", "hiddenapi-flags.csv"),
-			metadata:  android.PathForOutput(ctx, "hiddenapi", "hiddenapi-greylist.csv"),
+			stubFlags:     android.PathForOutput(ctx, "hiddenapi", "hiddenapi-stub-flags.txt"),
+			flags:         android.PathForOutput(ctx, "hiddenapi", "hiddenapi-flags.csv"),
+			flagsByModule: android.PathForOutput(ctx, "hiddenapi", "hiddenapi-flags-by-module.csv"),
+			metadata:      android.PathForOutput(ctx, "hiddenapi", "hiddenapi-greylist.csv"),
 		}
 	}).(hiddenAPISingletonPathsStruct)
 }
@@ -48,7 +53,7 @@ func hiddenAPISingletonFactory() android.Singleton {
 }
 
 type hiddenAPISingleton struct {
-	flags, metadata android.Path
+	flags, flagsByModule, metadata android.Path
 }
 
 // hiddenAPI singleton rules
@@ -63,6 +68,7 @@ func (h *hiddenAPISingleton) GenerateBuildActions(ctx android.SingletonContext)
 	// These rules depend on files located in frameworks/base, skip them if running in a tree that doesn't have them.
 	if ctx.Config().FrameworksBaseDirExists(ctx) {
 		h.flags = flagsRule(ctx)
+		h.flagsByModule = flagsByModuleRule(ctx)
 		h.metadata = metadataRule(ctx)
 	} else {
 		h.flags = emptyFlagsRule(ctx)
@@ -78,6 +84,10 @@ func (h *hiddenAPISingleton) MakeVars(ctx android.MakeVarsContext) {
 
 	ctx.Strict("INTERNAL_PLATFORM_HIDDENAPI_FLAGS", h.flags.String())
 
+	if h.flagsByModule != nil {
+		ctx.Strict("INTERNAL_PLATFORM_HIDDENAPI_FLAGS_BY_MODULE", h.flagsByModule.String())
+	}
+
 	if h.metadata != nil {
 		ctx.Strict("INTERNAL_PLATFORM_HIDDENAPI_GREYLIST_METADATA", h.metadata.String())
 	}
@@ -247,6 +257,53 @@ func flagsRule(ctx android.SingletonContext) android.Path {
 	return outputPath
 }
 
+// flagsByModuleRule creates a rule to build hiddenapi-flags-by-module.csv, which contains the same entries as
+// hiddenapi-flags.csv prefixed with the name of the boot jar module that defines each member.  It can be compared
+// between builds with `hiddenapi_flags diff`.
+func flagsByModuleRule(ctx android.SingletonContext) android.Path {
+	classesJars := make(map[string]android.Paths)
+
+	ctx.VisitAllModules(func(module android.Module) {
+		if h, ok := module.(hiddenAPIIntf); ok {
+			if jar := h.classesJar(); jar != nil {
+				// Modules named <x>-hiddenapi provide hiddenapi information for the boot jar module <x>.
+				name := strings.TrimSuffix(ctx.ModuleName(module), "-hiddenapi")
+				classesJars[name] = append(classesJars[name], jar)
+			}
+		}
+	})
+
+	var names []string
+	for name := range classesJars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rule := android.NewRuleBuilder()
+
+	outputPath := hiddenAPISingletonPaths(ctx).flagsByModule
+	tempPath := android.PathForOutput(ctx, outputPath.Rel()+".tmp")
+
+	cmd := rule.Command().
+		Tool(ctx.Config().HostToolPath(ctx, "hiddenapi_flags")).
+		Text("attribute").
+		FlagWithInput("--flags ", hiddenAPISingletonPaths(ctx).flags)
+
+	for _, name := range names {
+		for _, jar := range classesJars[name] {
+			cmd.FlagWithArg("--module ", name+"="+jar.String()).Implicit(jar)
+		}
+	}
+
+	cmd.FlagWithOutput("-o ", tempPath)
+
+	commitChangeForRestat(rule, tempPath, outputPath)
+
+	rule.Build(pctx, ctx, "hiddenAPIFlagsByModuleFile", "hiddenapi flags by module")
+
+	return outputPath
+}
+
 // emptyFlagsRule creates a rule to build an empty hiddenapi-flags.csv, which is needed by master-art-host builds that
 // have a partial manifest without frameworks/base but still need to build a boot image.
 func emptyFlagsRule(ctx android.SingletonContext) android.Path {
@@ -17,8 +17,12 @@ package java
 // Rules for instrumenting classes using jacoco
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -46,6 +50,10 @@ var (
 		"strippedJar", "stripSpec", "tmpDir", "tmpJar")
 )
 
+func init() {
+	android.RegisterSingletonType("jacoco_report_manifest", jacocoReportManifestSingletonFactory)
+}
+
 // Instruments a jar using the Jacoco command line interface.  Uses stripSpec to extract a subset
 // of the classes in inputJar into strippedJar, instruments strippedJar into tmpJar, and then
 // combines the classes in tmpJar with inputJar (preferring the instrumented classes in tmpJar)
@@ -139,3 +147,149 @@ func jacocoFilterToSpec(filter string) (string, error) {
 
 	return spec, nil
 }
+
+// This singleton writes a manifest describing every module that was instrumented with jacoco.  Each
+// entry maps the module to the source directories and srcjars it was compiled from and to the
+// jacoco-report-classes jar containing the uninstrumented classes, which is everything the
+// jacoco_report host tool needs to turn .ec files collected from a device into a report without
+// access to the build system.  The manifest is written to $OUT_DIR/soong/jacoco-report-manifest.json
+// when building with EMMA_INSTRUMENT=true.
+
+const jacocoReportManifestFileName = "jacoco-report-manifest.json"
+
+type jacocoReportManifestEntry struct {
+	Name       string   `json:"name"`
+	Variant    string   `json:"variant"`
+	Dir        string   `json:"dir"`
+	ClassesJar string   `json:"classes_jar"`
+	SrcDirs    []string `json:"src_dirs,omitempty"`
+	SrcJars    []string `json:"srcjars,omitempty"`
+}
+
+type jacocoReportManifest struct {
+	Modules []jacocoReportManifestEntry `json:"modules"`
+}
+
+func jacocoReportManifestSingletonFactory() android.Singleton {
+	return &jacocoReportManifestSingleton{}
+}
+
+type jacocoReportManifestSingleton struct {
+	manifest android.Path
+}
+
+func (j *jacocoReportManifestSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !ctx.Config().IsEnvTrue("EMMA_INSTRUMENT") {
+		return
+	}
+
+	var manifest jacocoReportManifest
+	var classesJars android.Paths
+
+	ctx.VisitAllModules(func(module android.Module) {
+		if !module.Enabled() {
+			return
+		}
+
+		m, ok := module.(jacocoReportClassesProvider)
+		if !ok || m.JacocoReportClassesFile() == nil {
+			return
+		}
+
+		entry := m.jacocoReportManifestEntry()
+		entry.Name = ctx.ModuleName(module)
+		entry.Variant = ctx.ModuleSubDir(module)
+		entry.Dir = ctx.ModuleDir(module)
+		manifest.Modules = append(manifest.Modules, entry)
+
+		classesJars = append(classesJars, m.JacocoReportClassesFile())
+	})
+
+	sort.SliceStable(manifest.Modules, func(i, k int) bool {
+		if manifest.Modules[i].Name != manifest.Modules[k].Name {
+			return manifest.Modules[i].Name < manifest.Modules[k].Name
+		}
+		return manifest.Modules[i].Variant < manifest.Modules[k].Variant
+	})
+
+	manifestPath := android.PathForOutput(ctx, jacocoReportManifestFileName)
+	if err := writeJacocoReportManifest(manifest, manifestPath.String()); err != nil {
+		ctx.Errorf("%s", err.Error())
+		return
+	}
+	j.manifest = manifestPath
+
+	// Create a phony rule that builds every jacoco-report-classes jar listed in the manifest so that
+	// the report can be generated offline after the build.
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Phony,
+		Output:      android.PathForPhony(ctx, "jacoco-report-classes"),
+		Inputs:      classesJars,
+		Description: "jacoco-report-classes",
+	})
+}
+
+func (j *jacocoReportManifestSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if j.manifest != nil {
+		ctx.Strict("SOONG_JACOCO_REPORT_MANIFEST", j.manifest.String())
+	}
+}
+
+func writeJacocoReportManifest(manifest jacocoReportManifest, path string) error {
+	buf, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", jacocoReportManifestFileName, err)
+	}
+
+	// Avoid touching the manifest when it hasn't changed so that anything depending on it is not
+	// rebuilt on every soong run.
+	if old, err := ioutil.ReadFile(path); err == nil && string(old) == string(buf) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %v", jacocoReportManifestFileName, err)
+	}
+	if err := ioutil.WriteFile(path, buf, 0666); err != nil {
+		return fmt.Errorf("failed to write %s: %v", jacocoReportManifestFileName, err)
+	}
+	return nil
+}
+
+type jacocoReportClassesProvider interface {
+	JacocoReportClassesFile() android.Path
+	jacocoReportManifestEntry() jacocoReportManifestEntry
+}
+
+var _ jacocoReportClassesProvider = (*Module)(nil)
+
+func (j *Module) JacocoReportClassesFile() android.Path {
+	return j.jacocoReportClassesFile
+}
+
+// jacocoReportManifestEntry returns the partially filled manifest entry for the module, containing the
+// paths that are only known to the module itself.
+func (j *Module) jacocoReportManifestEntry() jacocoReportManifestEntry {
+	entry := jacocoReportManifestEntry{
+		SrcDirs: jacocoSrcDirs(j.expandIDEInfoCompiledSrcs),
+		SrcJars: j.compiledSrcJars.Strings(),
+	}
+	if j.jacocoReportClassesFile != nil {
+		entry.ClassesJar = j.jacocoReportClassesFile.String()
+	}
+	return entry
+}
+
+// jacocoSrcDirs returns the sorted list of unique directories that contain the .java or .kt files in
+// srcs.
+func jacocoSrcDirs(srcs []string) []string {
+	var dirs []string
+	for _, src := range srcs {
+		if ext := filepath.Ext(src); ext == ".java" || ext == ".kt" {
+			dirs = append(dirs, filepath.Dir(src))
+		}
+	}
+	dirs = android.FirstUniqueStrings(dirs)
+	sort.Strings(dirs)
+	return dirs
+}
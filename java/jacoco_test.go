@@ -14,7 +14,10 @@
 
 package java
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestJacocoFilterToSpecs(t *testing.T) {
 	testCases := []struct {
@@ -103,3 +106,22 @@ func TestJacocoFiltersToZipCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestJacocoSrcDirs(t *testing.T) {
+	srcs := []string{
+		"frameworks/foo/src/com/android/foo/Foo.java",
+		"frameworks/foo/src/com/android/foo/Bar.kt",
+		"frameworks/foo/src/com/android/bar/Bar.java",
+		"frameworks/foo/aidl/com/android/foo/IFoo.aidl",
+		"out/soong/.intermediates/frameworks/foo/foo/android_common/gen/foo.srcjar",
+	}
+
+	expected := []string{
+		"frameworks/foo/src/com/android/bar",
+		"frameworks/foo/src/com/android/foo",
+	}
+
+	if got := jacocoSrcDirs(srcs); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q got %q", expected, got)
+	}
+}
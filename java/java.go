@@ -257,6 +257,16 @@ type CompilerDeviceProperties struct {
 
 		// Specifies the locations of files containing proguard flags.
 		Proguard_flags_files []string `android:"path"`
+
+		// If true, replace the contents of resources that are not referenced from the code kept by
+		// R8 or from other kept resources with minimal placeholders.  Only supported by android_app
+		// modules, and requires shrink to be enabled.  Resources that are only looked up by name,
+		// for example with Resources.getIdentifier, must be listed in keep_resources.  Defaults to
+		// false.
+		Shrink_resources *bool
+
+		// Resources to keep when shrink_resources is set, in <type>/<name> form.
+		Keep_resources []string
 	}
 
 	// When targeting 1.9, override the modules to use with --system
@@ -306,6 +316,10 @@ type Module struct {
 	// output file containing mapping of obfuscated names
 	proguardDictionary android.Path
 
+	// output file of R8's -printusage option and the jar R8 was run on, used to shrink resources
+	proguardUsage    android.Path
+	proguardInputJar android.Path
+
 	// output file of the module, which may be a classes jar or a dex jar
 	outputFile       android.Path
 	extraOutputFiles android.Paths
@@ -446,6 +460,10 @@ func (j *Module) sdkVersion() string {
 	return String(j.deviceProperties.Sdk_version)
 }
 
+func (j *Module) SdkVersion() string {
+	return j.sdkVersion()
+}
+
 func (j *Module) minSdkVersion() string {
 	if j.deviceProperties.Min_sdk_version != nil {
 		return *j.deviceProperties.Min_sdk_version
@@ -489,7 +507,9 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 		}
 		if ctx.ModuleName() == "android_stubs_current" ||
 			ctx.ModuleName() == "android_system_stubs_current" ||
-			ctx.ModuleName() == "android_test_stubs_current" {
+			ctx.ModuleName() == "android_test_stubs_current" ||
+			ctx.ModuleName() == "android_module_lib_stubs_current" ||
+			ctx.ModuleName() == "android_system_server_stubs_current" {
 			ctx.AddVariationDependencies(nil, frameworkApkTag, "framework-res")
 		}
 	}
@@ -622,7 +642,9 @@ type linkType int
 const (
 	javaCore linkType = iota
 	javaSdk
+	javaModule
 	javaSystem
+	javaSystemServer
 	javaPlatform
 )
 
@@ -635,6 +657,10 @@ func getLinkType(m *Module, name string) (ret linkType, stubs bool) {
 		return javaCore, true
 	case ver == "core_current":
 		return javaCore, false
+	case name == "android_system_server_stubs_current":
+		return javaSystemServer, true
+	case strings.HasPrefix(ver, "system_server_"):
+		return javaSystemServer, false
 	case name == "android_system_stubs_current":
 		return javaSystem, true
 	case strings.HasPrefix(ver, "system_"):
@@ -643,6 +669,10 @@ func getLinkType(m *Module, name string) (ret linkType, stubs bool) {
 		return javaSystem, true
 	case strings.HasPrefix(ver, "test_"):
 		return javaPlatform, false
+	case name == "android_module_lib_stubs_current":
+		return javaModule, true
+	case strings.HasPrefix(ver, "module_"):
+		return javaModule, false
 	case name == "android_stubs_current":
 		return javaSdk, true
 	case ver == "current":
@@ -682,9 +712,21 @@ func checkLinkType(ctx android.ModuleContext, from *Module, to *Library, tag dep
 				ctx.OtherModuleName(to))
 		}
 		break
+	case javaModule:
+		if otherLinkType != javaCore && otherLinkType != javaSdk && otherLinkType != javaModule {
+			ctx.ModuleErrorf("compiles against module API, but dependency %q is compiling against system or private API."+commonMessage,
+				ctx.OtherModuleName(to))
+		}
+		break
 	case javaSystem:
+		if otherLinkType == javaPlatform || otherLinkType == javaSystemServer {
+			ctx.ModuleErrorf("compiles against system API, but dependency %q is compiling against system server or private API."+commonMessage,
+				ctx.OtherModuleName(to))
+		}
+		break
+	case javaSystemServer:
 		if otherLinkType == javaPlatform {
-			ctx.ModuleErrorf("compiles against system API, but dependency %q is compiling against private API."+commonMessage,
+			ctx.ModuleErrorf("compiles against system server API, but dependency %q is compiling against private API."+commonMessage,
 				ctx.OtherModuleName(to))
 		}
 		break
@@ -775,7 +817,9 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 			case frameworkApkTag:
 				if ctx.ModuleName() == "android_stubs_current" ||
 					ctx.ModuleName() == "android_system_stubs_current" ||
-					ctx.ModuleName() == "android_test_stubs_current" {
+					ctx.ModuleName() == "android_test_stubs_current" ||
+					ctx.ModuleName() == "android_module_lib_stubs_current" ||
+					ctx.ModuleName() == "android_system_server_stubs_current" {
 					// framework stubs.jar need to depend on framework-res.apk, in order to pull the
 					// resource files out of there for aapt.
 					//
@@ -1599,6 +1643,13 @@ func (j *Test) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	j.Library.GenerateAndroidBuildActions(ctx)
 }
 
+func (j *Test) TestModuleInfo() *android.TestModuleInfo {
+	return &android.TestModuleInfo{
+		TestSuites: j.testProperties.Test_suites,
+		TestConfig: j.testConfig,
+	}
+}
+
 func (j *TestHelperLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	j.Library.GenerateAndroidBuildActions(ctx)
 }
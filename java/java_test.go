@@ -137,6 +137,11 @@ func testContext(config android.Config, bp string,
 		"api/test-removed.txt":   nil,
 		"framework/aidl/a.aidl":  nil,
 
+		"api/module-lib-current.txt":    nil,
+		"api/module-lib-removed.txt":    nil,
+		"api/system-server-current.txt": nil,
+		"api/system-server-removed.txt": nil,
+
 		"prebuilts/sdk/14/public/android.jar":         nil,
 		"prebuilts/sdk/14/public/framework.aidl":      nil,
 		"prebuilts/sdk/14/system/android.jar":         nil,
@@ -797,6 +802,29 @@ func TestJavaSdkLibrary(t *testing.T) {
 		    libs: ["baz"],
 		    sdk_version: "system_current",
 		}
+		java_sdk_library {
+			name: "quux",
+			srcs: ["a.java"],
+			api_packages: ["quux"],
+			module_lib: {
+				enabled: true,
+			},
+			system_server: {
+				enabled: true,
+			},
+		}
+		java_library {
+			name: "corge",
+			srcs: ["c.java"],
+			libs: ["quux", "foo"],
+			sdk_version: "module_current",
+		}
+		java_library {
+			name: "grault",
+			srcs: ["c.java"],
+			libs: ["quux", "foo"],
+			sdk_version: "system_server_current",
+		}
 		`)
 
 	// check the existence of the internal modules
@@ -812,6 +840,41 @@ func TestJavaSdkLibrary(t *testing.T) {
 	ctx.ModuleForTests("foo.api.system.28", "")
 	ctx.ModuleForTests("foo.api.test.28", "")
 
+	// the module-lib and system-server scopes are only created when enabled
+	ctx.ModuleForTests("quux"+sdkStubsLibrarySuffix+sdkModuleLibApiSuffix, "android_common")
+	ctx.ModuleForTests("quux"+sdkStubsLibrarySuffix+sdkSystemServerApiSuffix, "android_common")
+	ctx.ModuleForTests("quux"+sdkDocsSuffix+sdkModuleLibApiSuffix, "android_common")
+	ctx.ModuleForTests("quux"+sdkDocsSuffix+sdkSystemServerApiSuffix, "android_common")
+	if variants := ctx.ModuleVariantsForTests("foo" + sdkStubsLibrarySuffix + sdkModuleLibApiSuffix); len(variants) > 0 {
+		t.Errorf("foo should not have module-lib stubs, found variants %q", variants)
+	}
+
+	corgeJavac := ctx.ModuleForTests("corge", "android_common").Rule("javac")
+	// tests if corge is linked to the module-lib stubs of quux
+	if !strings.Contains(corgeJavac.Args["classpath"], "quux.stubs.module_lib.jar") {
+		t.Errorf("corge javac classpath %v does not contain %q", corgeJavac.Args["classpath"],
+			"quux.stubs.module_lib.jar")
+	}
+	// ... and falls back to the public stubs, not the system stubs, of foo which doesn't have the
+	// module-lib scope
+	if !strings.Contains(corgeJavac.Args["classpath"], "foo.stubs.jar") {
+		t.Errorf("corge javac classpath %v does not contain %q", corgeJavac.Args["classpath"],
+			"foo.stubs.jar")
+	}
+	if strings.Contains(corgeJavac.Args["classpath"], "foo.stubs.system.jar") {
+		t.Errorf("corge javac classpath %v should not contain %q", corgeJavac.Args["classpath"],
+			"foo.stubs.system.jar")
+	}
+
+	graultJavac := ctx.ModuleForTests("grault", "android_common").Rule("javac")
+	// tests if grault is linked to the system-server stubs of quux, and falls back to the system
+	// stubs of foo which doesn't have the system-server scope
+	for _, jar := range []string{"quux.stubs.system_server.jar", "foo.stubs.system.jar"} {
+		if !strings.Contains(graultJavac.Args["classpath"], jar) {
+			t.Errorf("grault javac classpath %v does not contain %q", graultJavac.Args["classpath"], jar)
+		}
+	}
+
 	bazJavac := ctx.ModuleForTests("baz", "android_common").Rule("javac")
 	// tests if baz is actually linked to the stubs lib
 	if !strings.Contains(bazJavac.Args["classpath"], "foo.stubs.system.jar") {
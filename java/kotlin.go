@@ -46,6 +46,60 @@ var kotlinc = pctx.AndroidGomaStaticRule("kotlinc",
 	},
 	"kotlincFlags", "classpath", "srcJars", "srcJarDir", "classesDir", "kotlinJvmTarget", "kotlinBuildFile")
 
+// kotlinIncrementalCacheVersion is part of the key of the caches, and must be changed whenever the
+// layout of the caches changes.
+const kotlinIncrementalCacheVersion = "3"
+
+// kotlinCacheKeyCmd returns a command that writes a hash of all the inputs of a kotlinc or kapt rule
+// to $cacheDir/key.new: the cache version, the flags, the compiler jars, the jars in $cacheKeyDeps and
+// the contents of the sources listed in $out.rsp and of the srcjar sources extracted into $srcJarDir.
+func kotlinCacheKeyCmd(compilerJars string) string {
+	return `(echo "` + kotlinIncrementalCacheVersion + ` $kotlincFlags $kotlinJvmTarget $cacheKeyFlags" && ` +
+		`sha1sum ` + compilerJars + ` $cacheKeyDeps && ` +
+		`tr ' ' '\n' < $out.rsp | sort | xargs -r sha1sum && ` +
+		`sort $srcJarDir/list | xargs -r sha1sum) | sha1sum > $cacheDir/key.new`
+}
+
+// kotlincIncremental is used instead of kotlinc when KOTLIN_INCREMENTAL=true.  It keeps the classes
+// jar from the last compile in a per-module cache, together with the key of the inputs it was
+// compiled from.  If the key of the current inputs matches, for example after a rebuild caused by
+// timestamp-only changes or by a dependency that was rebuilt with identical contents, the cached
+// jar is used instead of running kotlinc.  On any mismatch, or if kotlinc fails, the cache is
+// cleared and the sources are compiled from scratch.  The key is touched on every run so that it
+// is never older than the inputs.
+var kotlincIncremental = pctx.AndroidGomaStaticRule("kotlinc-incremental",
+	blueprint.RuleParams{
+		Command: `rm -rf "$srcJarDir" "$kotlinBuildFile" && mkdir -p "$srcJarDir" "$cacheDir" && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+			kotlinCacheKeyCmd("${config.KotlinCompilerJar}") + ` && ` +
+			`if ! cmp -s $cacheDir/key.new $cacheDir/key || [ ! -f $cacheDir/classes.jar ]; then ` +
+			`rm -rf "$classesDir" $cacheDir/key $cacheDir/classes.jar && mkdir -p "$classesDir" && ` +
+			`${config.GenKotlinBuildFileCmd} $classpath $classesDir $out.rsp $srcJarDir/list > $kotlinBuildFile && ` +
+			`${config.KotlincCmd} ${config.JavacHeapFlags} $kotlincFlags ` +
+			`-jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile && ` +
+			`${config.SoongZipCmd} -jar -o $cacheDir/classes.jar -C $classesDir -D $classesDir && ` +
+			`mv $cacheDir/key.new $cacheDir/key; ` +
+			`fi && ` +
+			`rm -f $cacheDir/key.new && touch $cacheDir/key && cp $cacheDir/classes.jar $out && ` +
+			`rm -rf "$srcJarDir"`,
+		CommandDeps: []string{
+			"${config.KotlincCmd}",
+			"${config.KotlinCompilerJar}",
+			"${config.GenKotlinBuildFileCmd}",
+			"${config.SoongZipCmd}",
+			"${config.ZipSyncCmd}",
+		},
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+	},
+	"kotlincFlags", "classpath", "srcJars", "srcJarDir", "classesDir", "kotlinJvmTarget", "kotlinBuildFile",
+	"cacheDir", "cacheKeyDeps", "cacheKeyFlags")
+
+// kotlinIncremental returns true if the kotlinc and kapt rules should use the per-module caches.
+func kotlinIncremental(ctx android.ModuleContext) bool {
+	return ctx.Config().IsEnvTrue("KOTLIN_INCREMENTAL")
+}
+
 // kotlinCompile takes .java and .kt sources and srcJars, and compiles the .kt sources into a classes jar in outputFile.
 func kotlinCompile(ctx android.ModuleContext, outputFile android.WritablePath,
 	srcFiles, srcJars android.Paths,
@@ -55,22 +109,42 @@ func kotlinCompile(ctx android.ModuleContext, outputFile android.WritablePath,
 	deps = append(deps, flags.kotlincClasspath...)
 	deps = append(deps, srcJars...)
 
+	args := map[string]string{
+		"classpath":       flags.kotlincClasspath.FormJavaClassPath("-classpath"),
+		"kotlincFlags":    flags.kotlincFlags,
+		"srcJars":         strings.Join(srcJars.Strings(), " "),
+		"classesDir":      android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
+		"srcJarDir":       android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
+		"kotlinBuildFile": android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
+		// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
+		"kotlinJvmTarget": "1.8",
+	}
+
+	if kotlinIncremental(ctx) {
+		cacheDir := android.PathForModuleOut(ctx, "kotlinc", "incremental")
+		args["cacheDir"] = cacheDir.String()
+		args["cacheKeyDeps"] = strings.Join(flags.kotlincClasspath.Strings(), " ")
+		args["cacheKeyFlags"] = args["classpath"]
+
+		ctx.Build(pctx, android.BuildParams{
+			Rule:           kotlincIncremental,
+			Description:    "kotlinc incremental",
+			Output:         outputFile,
+			ImplicitOutput: cacheDir.Join(ctx, "key"),
+			Inputs:         srcFiles,
+			Implicits:      deps,
+			Args:           args,
+		})
+		return
+	}
+
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        kotlinc,
 		Description: "kotlinc",
 		Output:      outputFile,
 		Inputs:      srcFiles,
 		Implicits:   deps,
-		Args: map[string]string{
-			"classpath":       flags.kotlincClasspath.FormJavaClassPath("-classpath"),
-			"kotlincFlags":    flags.kotlincFlags,
-			"srcJars":         strings.Join(srcJars.Strings(), " "),
-			"classesDir":      android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
-			"srcJarDir":       android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
-			"kotlinBuildFile": android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
-			// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
-			"kotlinJvmTarget": "1.8",
-		},
+		Args:        args,
 	})
 }
 
@@ -106,6 +180,49 @@ var kapt = pctx.AndroidGomaStaticRule("kapt",
 	"kotlincFlags", "encodedJavacFlags", "kaptProcessorPath", "kaptProcessor",
 	"classpath", "srcJars", "srcJarDir", "kaptDir", "kotlinJvmTarget", "kotlinBuildFile")
 
+// kaptIncremental is used instead of kapt when KOTLIN_INCREMENTAL=true.  Like kotlincIncremental it
+// reuses the generated sources from the per-module cache if the key of the inputs, which includes
+// the annotation processors, matches the key of the cached sources, and otherwise runs kapt from
+// scratch.
+var kaptIncremental = pctx.AndroidGomaStaticRule("kapt-incremental",
+	blueprint.RuleParams{
+		Command: `rm -rf "$srcJarDir" "$kotlinBuildFile" && mkdir -p "$srcJarDir" "$cacheDir" && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+			kotlinCacheKeyCmd("${config.KotlinCompilerJar} ${config.KotlinKaptJar}") + ` && ` +
+			`if ! cmp -s $cacheDir/key.new $cacheDir/key || [ ! -f $cacheDir/sources.jar ]; then ` +
+			`rm -rf "$kaptDir" $cacheDir/key $cacheDir/sources.jar && mkdir -p "$kaptDir" && ` +
+			`${config.GenKotlinBuildFileCmd} $classpath "" $out.rsp $srcJarDir/list > $kotlinBuildFile && ` +
+			`${config.KotlincCmd} ${config.KotlincSuppressJDK9Warnings} ${config.JavacHeapFlags} $kotlincFlags ` +
+			`-Xplugin=${config.KotlinKaptJar} ` +
+			`-P plugin:org.jetbrains.kotlin.kapt3:sources=$kaptDir/sources ` +
+			`-P plugin:org.jetbrains.kotlin.kapt3:classes=$kaptDir/classes ` +
+			`-P plugin:org.jetbrains.kotlin.kapt3:stubs=$kaptDir/stubs ` +
+			`-P plugin:org.jetbrains.kotlin.kapt3:correctErrorTypes=true ` +
+			`-P plugin:org.jetbrains.kotlin.kapt3:aptMode=stubsAndApt ` +
+			`-P plugin:org.jetbrains.kotlin.kapt3:javacArguments=$encodedJavacFlags ` +
+			`$kaptProcessorPath ` +
+			`$kaptProcessor ` +
+			`-Xbuild-file=$kotlinBuildFile && ` +
+			`${config.SoongZipCmd} -jar -o $cacheDir/sources.jar -C $kaptDir/sources -D $kaptDir/sources && ` +
+			`mv $cacheDir/key.new $cacheDir/key; ` +
+			`fi && ` +
+			`rm -f $cacheDir/key.new && touch $cacheDir/key && cp $cacheDir/sources.jar $out && ` +
+			`rm -rf "$srcJarDir"`,
+		CommandDeps: []string{
+			"${config.KotlincCmd}",
+			"${config.KotlinCompilerJar}",
+			"${config.KotlinKaptJar}",
+			"${config.GenKotlinBuildFileCmd}",
+			"${config.SoongZipCmd}",
+			"${config.ZipSyncCmd}",
+		},
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+	},
+	"kotlincFlags", "encodedJavacFlags", "kaptProcessorPath", "kaptProcessor",
+	"classpath", "srcJars", "srcJarDir", "kaptDir", "kotlinJvmTarget", "kotlinBuildFile",
+	"cacheDir", "cacheKeyDeps", "cacheKeyFlags")
+
 // kotlinKapt performs Kotlin-compatible annotation processing.  It takes .kt and .java sources and srcjars, and runs
 // annotation processors over all of them, producing a srcjar of generated code in outputFile.  The srcjar should be
 // added as an additional input to kotlinc and javac rules, and the javac rule should have annotation processing
@@ -131,23 +248,47 @@ func kotlinKapt(ctx android.ModuleContext, outputFile android.WritablePath,
 		{"-target", flags.javaVersion},
 	})
 
+	args := map[string]string{
+		"classpath":         flags.kotlincClasspath.FormJavaClassPath("-classpath"),
+		"kotlincFlags":      flags.kotlincFlags,
+		"srcJars":           strings.Join(srcJars.Strings(), " "),
+		"srcJarDir":         android.PathForModuleOut(ctx, "kapt", "srcJars").String(),
+		"kotlinBuildFile":   android.PathForModuleOut(ctx, "kapt", "build.xml").String(),
+		"kaptProcessorPath": strings.Join(kaptProcessorPath, " "),
+		"kaptProcessor":     kaptProcessor,
+		"kaptDir":           android.PathForModuleOut(ctx, "kapt/gen").String(),
+		"encodedJavacFlags": encodedJavacFlags,
+	}
+
+	if kotlinIncremental(ctx) {
+		cacheDir := android.PathForModuleOut(ctx, "kapt", "incremental")
+		var keyDeps android.Paths
+		keyDeps = append(keyDeps, flags.kotlincClasspath...)
+		keyDeps = append(keyDeps, flags.processorPath...)
+		args["cacheDir"] = cacheDir.String()
+		args["cacheKeyDeps"] = strings.Join(keyDeps.Strings(), " ")
+		args["cacheKeyFlags"] = strings.Join([]string{args["classpath"], kaptProcessor,
+			args["kaptProcessorPath"], encodedJavacFlags}, " ")
+
+		ctx.Build(pctx, android.BuildParams{
+			Rule:           kaptIncremental,
+			Description:    "kapt incremental",
+			Output:         outputFile,
+			ImplicitOutput: cacheDir.Join(ctx, "key"),
+			Inputs:         srcFiles,
+			Implicits:      deps,
+			Args:           args,
+		})
+		return
+	}
+
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        kapt,
 		Description: "kapt",
 		Output:      outputFile,
 		Inputs:      srcFiles,
 		Implicits:   deps,
-		Args: map[string]string{
-			"classpath":         flags.kotlincClasspath.FormJavaClassPath("-classpath"),
-			"kotlincFlags":      flags.kotlincFlags,
-			"srcJars":           strings.Join(srcJars.Strings(), " "),
-			"srcJarDir":         android.PathForModuleOut(ctx, "kapt", "srcJars").String(),
-			"kotlinBuildFile":   android.PathForModuleOut(ctx, "kapt", "build.xml").String(),
-			"kaptProcessorPath": strings.Join(kaptProcessorPath, " "),
-			"kaptProcessor":     kaptProcessor,
-			"kaptDir":           android.PathForModuleOut(ctx, "kapt/gen").String(),
-			"encodedJavacFlags": encodedJavacFlags,
-		},
+		Args:        args,
 	})
 }
 
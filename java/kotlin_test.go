@@ -154,6 +154,63 @@ func TestKapt(t *testing.T) {
 	}
 }
 
+func TestKotlinIncremental(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			plugins: ["bar"],
+		}
+
+		java_plugin {
+			name: "bar",
+			processor_class: "com.bar",
+			srcs: ["b.java"],
+		}
+		`
+
+	config := testConfig(map[string]string{"KOTLIN_INCREMENTAL": "true"})
+	ctx := testContext(config, bp, nil)
+	run(t, ctx, config)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	kotlinc := foo.Rule("kotlinc-incremental")
+	kapt := foo.Rule("kapt-incremental")
+
+	for _, rule := range []struct {
+		name     string
+		params   android.TestingBuildParams
+		cacheDir string
+	}{
+		{"kotlinc", kotlinc, "kotlinc/incremental"},
+		{"kapt", kapt, "kapt/incremental"},
+	} {
+		if !strings.HasSuffix(rule.params.Args["cacheDir"], "/"+rule.cacheDir) {
+			t.Errorf("%s: expected cacheDir under %q, got %q", rule.name, rule.cacheDir, rule.params.Args["cacheDir"])
+		}
+		// Only the key is an output, and it is touched on every run so that it is never older than
+		// the inputs.
+		if len(rule.params.ImplicitOutputs) != 0 || rule.params.ImplicitOutput == nil ||
+			rule.params.ImplicitOutput.String() != rule.params.Args["cacheDir"]+"/key" {
+			t.Errorf("%s: expected only %q as an implicit output, got %v %q", rule.name,
+				rule.params.Args["cacheDir"]+"/key", rule.params.ImplicitOutput, rule.params.ImplicitOutputs.Strings())
+		}
+		if !strings.Contains(rule.params.RuleParams.Command, "touch $cacheDir/key") {
+			t.Errorf("%s: expected the key to be touched in command %q", rule.name, rule.params.RuleParams.Command)
+		}
+		// The cache is keyed on the contents of the sources, not only their names.
+		if !strings.Contains(rule.params.RuleParams.Command, "xargs -r sha1sum") {
+			t.Errorf("%s: expected the sources to be hashed in command %q", rule.name, rule.params.RuleParams.Command)
+		}
+	}
+
+	// Test that changing the annotation processors invalidates the kapt cache
+	bar := ctx.ModuleForTests("bar", android.BuildOs.String()+"_common").Rule("javac").Output.String()
+	if !strings.Contains(kapt.Args["cacheKeyDeps"], bar) {
+		t.Errorf("expected %q in kapt cacheKeyDeps %q", bar, kapt.Args["cacheKeyDeps"])
+	}
+}
+
 func TestKaptEncodeFlags(t *testing.T) {
 	// Compares the kaptEncodeFlags against the results of the example implementation at
 	// https://kotlinlang.org/docs/reference/kapt.html#apjavac-options-encoding
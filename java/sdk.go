@@ -48,7 +48,8 @@ type sdkContext interface {
 
 func sdkVersionOrDefault(ctx android.BaseContext, v string) string {
 	switch v {
-	case "", "current", "system_current", "test_current", "core_current":
+	case "", "current", "system_current", "test_current", "core_current",
+		"module_current", "system_server_current":
 		return ctx.Config().DefaultAppTargetSdk()
 	default:
 		return v
@@ -59,7 +60,8 @@ func sdkVersionOrDefault(ctx android.BaseContext, v string) string {
 // it returns android.FutureApiLevel (10000).
 func sdkVersionToNumber(ctx android.BaseContext, v string) (int, error) {
 	switch v {
-	case "", "current", "test_current", "system_current", "core_current":
+	case "", "current", "test_current", "system_current", "core_current",
+		"module_current", "system_server_current":
 		return ctx.Config().DefaultAppTargetSdkInt(), nil
 	default:
 		n := android.GetNumericSdkVersion(v)
@@ -79,6 +81,27 @@ func sdkVersionToNumberAsString(ctx android.BaseContext, v string) (string, erro
 	return strconv.Itoa(n), nil
 }
 
+// prebuiltSdkScopeDirs maps the prefix of an sdk_version to the name of the directory under
+// prebuilts/sdk/<version> that contains its stubs, for the prefixes where the two differ.
+var prebuiltSdkScopeDirs = map[string]string{
+	"module":        "module-lib",
+	"system_server": "system-server",
+}
+
+// splitSdkVersion splits an sdk_version like "system_28" or "system_server_current" into the name
+// of the directory under prebuilts/sdk/<version> that contains its stubs and the version.
+func splitSdkVersion(sdk string) (api, v string) {
+	i := strings.LastIndex(sdk, "_")
+	if i == -1 {
+		return "public", sdk
+	}
+	api, v = sdk[:i], sdk[i+1:]
+	if dir, ok := prebuiltSdkScopeDirs[api]; ok {
+		api = dir
+	}
+	return api, v
+}
+
 func decodeSdkDep(ctx android.BaseContext, sdkContext sdkContext) sdkDep {
 	v := sdkContext.sdkVersion()
 	// For PDK builds, use the latest SDK version instead of "current"
@@ -98,15 +121,7 @@ func decodeSdkDep(ctx android.BaseContext, sdkContext sdkContext) sdkDep {
 	}
 
 	toPrebuilt := func(sdk string) sdkDep {
-		var api, v string
-		if strings.Contains(sdk, "_") {
-			t := strings.Split(sdk, "_")
-			api = t[0]
-			v = t[1]
-		} else {
-			api = "public"
-			v = sdk
-		}
+		api, v := splitSdkVersion(sdk)
 		dir := filepath.Join("prebuilts", "sdk", v, api)
 		jar := filepath.Join(dir, "android.jar")
 		// There's no aidl for other SDKs yet.
@@ -160,7 +175,8 @@ func decodeSdkDep(ctx android.BaseContext, sdkContext sdkContext) sdkDep {
 
 	// Ensures that the specificed system SDK version is one of BOARD_SYSTEMSDK_VERSIONS (for vendor apks)
 	// or PRODUCT_SYSTEMSDK_VERSIONS (for other apks or when BOARD_SYSTEMSDK_VERSIONS is not set)
-	if strings.HasPrefix(v, "system_") && numericSdkVersion != android.FutureApiLevel {
+	if strings.HasPrefix(v, "system_") && !strings.HasPrefix(v, "system_server_") &&
+		numericSdkVersion != android.FutureApiLevel {
 		allowed_versions := ctx.DeviceConfig().PlatformSystemSdkVersions()
 		if ctx.DeviceSpecific() || ctx.SocSpecific() {
 			if len(ctx.DeviceConfig().SystemSdkVersions()) > 0 {
@@ -189,6 +205,19 @@ func decodeSdkDep(ctx android.BaseContext, sdkContext sdkContext) sdkDep {
 		return toModule("android_system_stubs_current", "framework-res", sdkFrameworkAidlPath(ctx))
 	case "test_current":
 		return toModule("android_test_stubs_current", "framework-res", sdkFrameworkAidlPath(ctx))
+	case "module_current", "system_server_current":
+		// The stubs of these scopes are only provided by framework trees that annotate their APIs with
+		// @SystemApi(client = ...), so report a clear error instead of a missing dependency.
+		m := "android_module_lib_stubs_current"
+		if v == "system_server_current" {
+			m = "android_system_server_stubs_current"
+		}
+		if mctx, ok := ctx.(interface{ OtherModuleExists(string) bool }); ok && !mctx.OtherModuleExists(m) {
+			ctx.PropertyErrorf("sdk_version", "%q is not supported by this tree: the framework does not define %q",
+				v, m)
+			return sdkDep{}
+		}
+		return toModule(m, "framework-res", sdkFrameworkAidlPath(ctx))
 	case "core_current":
 		return toModule("core.current.stubs", "", nil)
 	default:
@@ -30,11 +30,13 @@ import (
 )
 
 var (
-	sdkStubsLibrarySuffix = ".stubs"
-	sdkSystemApiSuffix    = ".system"
-	sdkTestApiSuffix      = ".test"
-	sdkDocsSuffix         = ".docs"
-	sdkXmlFileSuffix      = ".xml"
+	sdkStubsLibrarySuffix    = ".stubs"
+	sdkSystemApiSuffix       = ".system"
+	sdkTestApiSuffix         = ".test"
+	sdkModuleLibApiSuffix    = ".module_lib"
+	sdkSystemServerApiSuffix = ".system_server"
+	sdkDocsSuffix            = ".docs"
+	sdkXmlFileSuffix         = ".xml"
 )
 
 type stubsLibraryDependencyTag struct {
@@ -47,12 +49,16 @@ type syspropLibraryInterface interface {
 }
 
 var (
-	publicApiStubsTag = dependencyTag{name: "public"}
-	systemApiStubsTag = dependencyTag{name: "system"}
-	testApiStubsTag   = dependencyTag{name: "test"}
-	publicApiFileTag  = dependencyTag{name: "publicApi"}
-	systemApiFileTag  = dependencyTag{name: "systemApi"}
-	testApiFileTag    = dependencyTag{name: "testApi"}
+	publicApiStubsTag       = dependencyTag{name: "public"}
+	systemApiStubsTag       = dependencyTag{name: "system"}
+	testApiStubsTag         = dependencyTag{name: "test"}
+	moduleLibApiStubsTag    = dependencyTag{name: "module_lib"}
+	systemServerApiStubsTag = dependencyTag{name: "system_server"}
+	publicApiFileTag        = dependencyTag{name: "publicApi"}
+	systemApiFileTag        = dependencyTag{name: "systemApi"}
+	testApiFileTag          = dependencyTag{name: "testApi"}
+	moduleLibApiFileTag     = dependencyTag{name: "moduleLibApi"}
+	systemServerApiFileTag  = dependencyTag{name: "systemServerApi"}
 )
 
 type apiScope int
@@ -61,6 +67,8 @@ const (
 	apiScopePublic apiScope = iota
 	apiScopeSystem
 	apiScopeTest
+	apiScopeModuleLib
+	apiScopeSystemServer
 )
 
 var (
@@ -133,6 +141,21 @@ type sdkLibraryProperties struct {
 	// If set to true, the path of dist files is apistubs/core. Defaults to false.
 	Core_lib *bool
 
+	// Properties for the module-lib API scope, the API that is available to other mainline modules.
+	// It consists of the public API plus the APIs annotated with
+	// @SystemApi(client = MODULE_LIBRARIES), and is checked against api/module-lib-current.txt
+	// and api/module-lib-removed.txt.  Modules use it with sdk_version: "module_current".
+	// Requires a framework that supports the annotation and defines android_module_lib_stubs_current.
+	Module_lib sdkLibraryScopeProperties
+
+	// Properties for the system-server API scope, the API that is available to services running
+	// in system_server.  It consists of the public API plus the APIs annotated with
+	// @SystemApi(client = SYSTEM_SERVER), and is checked against api/system-server-current.txt
+	// and api/system-server-removed.txt.  Modules use it with sdk_version: "system_server_current".
+	// Requires a framework that supports the annotation and defines
+	// android_system_server_stubs_current.
+	System_server sdkLibraryScopeProperties
+
 	// don't create dist rules.
 	No_dist *bool `blueprint:"mutated"`
 
@@ -140,22 +163,34 @@ type sdkLibraryProperties struct {
 	//Html_doc *bool
 }
 
+type sdkLibraryScopeProperties struct {
+	// If set to true, create the stubs library, droidstubs and check-api rules for the scope.
+	// Defaults to false.
+	Enabled *bool
+}
+
 type SdkLibrary struct {
 	Library
 
 	sdkLibraryProperties sdkLibraryProperties
 
-	publicApiStubsPath android.Paths
-	systemApiStubsPath android.Paths
-	testApiStubsPath   android.Paths
-
-	publicApiStubsImplPath android.Paths
-	systemApiStubsImplPath android.Paths
-	testApiStubsImplPath   android.Paths
-
-	publicApiFilePath android.Path
-	systemApiFilePath android.Path
-	testApiFilePath   android.Path
+	publicApiStubsPath       android.Paths
+	systemApiStubsPath       android.Paths
+	testApiStubsPath         android.Paths
+	moduleLibApiStubsPath    android.Paths
+	systemServerApiStubsPath android.Paths
+
+	publicApiStubsImplPath       android.Paths
+	systemApiStubsImplPath       android.Paths
+	testApiStubsImplPath         android.Paths
+	moduleLibApiStubsImplPath    android.Paths
+	systemServerApiStubsImplPath android.Paths
+
+	publicApiFilePath       android.Path
+	systemApiFilePath       android.Path
+	testApiFilePath         android.Path
+	moduleLibApiFilePath    android.Path
+	systemServerApiFilePath android.Path
 }
 
 var _ Dependency = (*SdkLibrary)(nil)
@@ -176,6 +211,19 @@ func (module *SdkLibrary) DepsMutator(ctx android.BottomUpMutatorContext) {
 		}
 		ctx.AddVariationDependencies(nil, systemApiFileTag, module.docsName(apiScopeSystem))
 		ctx.AddVariationDependencies(nil, testApiFileTag, module.docsName(apiScopeTest))
+
+		if module.hasApiScope(apiScopeModuleLib) {
+			if useBuiltStubs {
+				ctx.AddVariationDependencies(nil, moduleLibApiStubsTag, module.stubsName(apiScopeModuleLib))
+			}
+			ctx.AddVariationDependencies(nil, moduleLibApiFileTag, module.docsName(apiScopeModuleLib))
+		}
+		if module.hasApiScope(apiScopeSystemServer) {
+			if useBuiltStubs {
+				ctx.AddVariationDependencies(nil, systemServerApiStubsTag, module.stubsName(apiScopeSystemServer))
+			}
+			ctx.AddVariationDependencies(nil, systemServerApiFileTag, module.docsName(apiScopeSystemServer))
+		}
 	}
 
 	module.Library.deps(ctx)
@@ -202,6 +250,12 @@ func (module *SdkLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext)
 			case testApiStubsTag:
 				module.testApiStubsPath = lib.HeaderJars()
 				module.testApiStubsImplPath = lib.ImplementationJars()
+			case moduleLibApiStubsTag:
+				module.moduleLibApiStubsPath = lib.HeaderJars()
+				module.moduleLibApiStubsImplPath = lib.ImplementationJars()
+			case systemServerApiStubsTag:
+				module.systemServerApiStubsPath = lib.HeaderJars()
+				module.systemServerApiStubsImplPath = lib.ImplementationJars()
 			}
 		}
 		if doc, ok := to.(ApiFilePath); ok {
@@ -212,6 +266,10 @@ func (module *SdkLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext)
 				module.systemApiFilePath = doc.ApiFilePath()
 			case testApiFileTag:
 				module.testApiFilePath = doc.ApiFilePath()
+			case moduleLibApiFileTag:
+				module.moduleLibApiFilePath = doc.ApiFilePath()
+			case systemServerApiFileTag:
+				module.systemServerApiFilePath = doc.ApiFilePath()
 			default:
 				ctx.ModuleErrorf("depends on module %q of unknown tag %q", otherName, tag)
 			}
@@ -257,6 +315,18 @@ func (module *SdkLibrary) AndroidMk() android.AndroidMkData {
 					":"+path.Join("apistubs", owner, "test",
 					module.BaseModuleName()+".jar")+")")
 			}
+			if len(module.moduleLibApiStubsPath) == 1 {
+				fmt.Fprintln(w, "$(call dist-for-goals,sdk win_sdk,"+
+					module.moduleLibApiStubsImplPath.Strings()[0]+
+					":"+path.Join("apistubs", owner, "module-lib",
+					module.BaseModuleName()+".jar")+")")
+			}
+			if len(module.systemServerApiStubsPath) == 1 {
+				fmt.Fprintln(w, "$(call dist-for-goals,sdk win_sdk,"+
+					module.systemServerApiStubsImplPath.Strings()[0]+
+					":"+path.Join("apistubs", owner, "system-server",
+					module.BaseModuleName()+".jar")+")")
+			}
 			if module.publicApiFilePath != nil {
 				fmt.Fprintln(w, "$(call dist-for-goals,sdk win_sdk,"+
 					module.publicApiFilePath.String()+
@@ -275,6 +345,18 @@ func (module *SdkLibrary) AndroidMk() android.AndroidMkData {
 					":"+path.Join("apistubs", owner, "test", "api",
 					module.BaseModuleName()+".txt")+")")
 			}
+			if module.moduleLibApiFilePath != nil {
+				fmt.Fprintln(w, "$(call dist-for-goals,sdk win_sdk,"+
+					module.moduleLibApiFilePath.String()+
+					":"+path.Join("apistubs", owner, "module-lib", "api",
+					module.BaseModuleName()+".txt")+")")
+			}
+			if module.systemServerApiFilePath != nil {
+				fmt.Fprintln(w, "$(call dist-for-goals,sdk win_sdk,"+
+					module.systemServerApiFilePath.String()+
+					":"+path.Join("apistubs", owner, "system-server", "api",
+					module.BaseModuleName()+".txt")+")")
+			}
 		}
 	}
 	return data
@@ -288,6 +370,10 @@ func (module *SdkLibrary) stubsName(apiScope apiScope) string {
 		stubsName = stubsName + sdkSystemApiSuffix
 	case apiScopeTest:
 		stubsName = stubsName + sdkTestApiSuffix
+	case apiScopeModuleLib:
+		stubsName = stubsName + sdkModuleLibApiSuffix
+	case apiScopeSystemServer:
+		stubsName = stubsName + sdkSystemServerApiSuffix
 	}
 	return stubsName
 }
@@ -300,6 +386,10 @@ func (module *SdkLibrary) docsName(apiScope apiScope) string {
 		docsName = docsName + sdkSystemApiSuffix
 	case apiScopeTest:
 		docsName = docsName + sdkTestApiSuffix
+	case apiScopeModuleLib:
+		docsName = docsName + sdkModuleLibApiSuffix
+	case apiScopeSystemServer:
+		docsName = docsName + sdkSystemServerApiSuffix
 	}
 	return docsName
 }
@@ -338,11 +428,48 @@ func (module *SdkLibrary) sdkVersion(apiScope apiScope) string {
 		return "system_current"
 	case apiScopeTest:
 		return "test_current"
+	case apiScopeModuleLib:
+		return "module_current"
+	case apiScopeSystemServer:
+		return "system_server_current"
 	default:
 		return "current"
 	}
 }
 
+// Prefix of the api/<prefix>current.txt and api/<prefix>removed.txt files of the scope
+func apiFilePrefix(apiScope apiScope) string {
+	switch apiScope {
+	case apiScopeSystem:
+		return "system-"
+	case apiScopeTest:
+		return "test-"
+	case apiScopeModuleLib:
+		return "module-lib-"
+	case apiScopeSystemServer:
+		return "system-server-"
+	default:
+		return ""
+	}
+}
+
+// Returns true if the stubs and API files are created for the scope. The public, system and test
+// scopes are always created unless no_standard_libs is set, the other scopes are opt-in.
+func (module *SdkLibrary) hasApiScope(apiScope apiScope) bool {
+	switch apiScope {
+	case apiScopePublic:
+		return true
+	case apiScopeModuleLib:
+		return !Bool(module.properties.No_standard_libs) &&
+			Bool(module.sdkLibraryProperties.Module_lib.Enabled)
+	case apiScopeSystemServer:
+		return !Bool(module.properties.No_standard_libs) &&
+			Bool(module.sdkLibraryProperties.System_server.Enabled)
+	default:
+		return !Bool(module.properties.No_standard_libs)
+	}
+}
+
 // $(INTERNAL_PLATFORM_<apiTagName>_API_FILE) points to the generated
 // api file for the current source
 // TODO: remove this when apicheck is done in soong
@@ -353,6 +480,10 @@ func (module *SdkLibrary) apiTagName(apiScope apiScope) string {
 		apiTagName = apiTagName + "_SYSTEM"
 	case apiScopeTest:
 		apiTagName = apiTagName + "_TEST"
+	case apiScopeModuleLib:
+		apiTagName = apiTagName + "_MODULE_LIB"
+	case apiScopeSystemServer:
+		apiTagName = apiTagName + "_SYSTEM_SERVER"
 	}
 	return apiTagName
 }
@@ -366,6 +497,10 @@ func (module *SdkLibrary) latestApiFilegroupName(apiScope apiScope) string {
 		name = name + "system"
 	case apiScopeTest:
 		name = name + "test"
+	case apiScopeModuleLib:
+		name = name + "module-lib"
+	case apiScopeSystemServer:
+		name = name + "system-server"
 	}
 	name = name + ".latest"
 	return name
@@ -380,6 +515,10 @@ func (module *SdkLibrary) latestRemovedApiFilegroupName(apiScope apiScope) strin
 		name = name + "system"
 	case apiScopeTest:
 		name = name + "test"
+	case apiScopeModuleLib:
+		name = name + "module-lib"
+	case apiScopeSystemServer:
+		name = name + "system-server"
 	}
 	name = name + ".latest"
 	return name
@@ -502,6 +641,12 @@ func (module *SdkLibrary) createDocs(mctx android.TopDownMutatorContext, apiScop
 		droiddocArgs = droiddocArgs + " -showAnnotation android.annotation.SystemApi"
 	case apiScopeTest:
 		droiddocArgs = droiddocArgs + " -showAnnotation android.annotation.TestApi"
+	case apiScopeModuleLib:
+		droiddocArgs = droiddocArgs + " -showAnnotation android.annotation.SystemApi\\(" +
+			"client=android.annotation.SystemApi.Client.MODULE_LIBRARIES\\)"
+	case apiScopeSystemServer:
+		droiddocArgs = droiddocArgs + " -showAnnotation android.annotation.SystemApi\\(" +
+			"client=android.annotation.SystemApi.Client.SYSTEM_SERVER\\)"
 	}
 	props.Arg_files = module.sdkLibraryProperties.Droiddoc_option_files
 	props.Args = proptools.StringPtr(droiddocArgs)
@@ -509,18 +654,8 @@ func (module *SdkLibrary) createDocs(mctx android.TopDownMutatorContext, apiScop
 	// List of APIs identified from the provided source files are created. They are later
 	// compared against to the not-yet-released (a.k.a current) list of APIs and to the
 	// last-released (a.k.a numbered) list of API.
-	currentApiFileName := "current.txt"
-	removedApiFileName := "removed.txt"
-	switch apiScope {
-	case apiScopeSystem:
-		currentApiFileName = "system-" + currentApiFileName
-		removedApiFileName = "system-" + removedApiFileName
-	case apiScopeTest:
-		currentApiFileName = "test-" + currentApiFileName
-		removedApiFileName = "test-" + removedApiFileName
-	}
-	currentApiFileName = path.Join("api", currentApiFileName)
-	removedApiFileName = path.Join("api", removedApiFileName)
+	currentApiFileName := path.Join("api", apiFilePrefix(apiScope)+"current.txt")
+	removedApiFileName := path.Join("api", apiFilePrefix(apiScope)+"removed.txt")
 	// TODO(jiyong): remove these three props
 	props.Api_tag_name = proptools.StringPtr(module.apiTagName(apiScope))
 	props.Api_filename = proptools.StringPtr(currentApiFileName)
@@ -604,17 +739,9 @@ func (module *SdkLibrary) createXmlFile(mctx android.TopDownMutatorContext) {
 }
 
 func (module *SdkLibrary) PrebuiltJars(ctx android.BaseContext, sdkVersion string) android.Paths {
-	var api, v string
-	if sdkVersion == "" {
-		api = "system"
-		v = "current"
-	} else if strings.Contains(sdkVersion, "_") {
-		t := strings.Split(sdkVersion, "_")
-		api = t[0]
-		v = t[1]
-	} else {
-		api = "public"
-		v = sdkVersion
+	api, v := "system", "current"
+	if sdkVersion != "" {
+		api, v = splitSdkVersion(sdkVersion)
 	}
 	dir := filepath.Join("prebuilts", "sdk", v, api)
 	jar := filepath.Join(dir, module.BaseModuleName()+".jar")
@@ -626,17 +753,39 @@ func (module *SdkLibrary) PrebuiltJars(ctx android.BaseContext, sdkVersion strin
 	return android.Paths{jarPath.Path()}
 }
 
+// Returns the scope of the stubs that a module built against sdkVersion links against. Modules
+// built against a scope that this library doesn't create stubs for fall back to the widest scope
+// they may link against: module-lib code gets the public stubs, since it may not use the system
+// API, while system-server code gets the system stubs.
+func (module *SdkLibrary) apiScopeForSdkVersion(sdkVersion string) apiScope {
+	switch {
+	case strings.HasPrefix(sdkVersion, "module_") && module.hasApiScope(apiScopeModuleLib):
+		return apiScopeModuleLib
+	case strings.HasPrefix(sdkVersion, "system_server_") && module.hasApiScope(apiScopeSystemServer):
+		return apiScopeSystemServer
+	case strings.HasPrefix(sdkVersion, "system_"):
+		return apiScopeSystem
+	default:
+		return apiScopePublic
+	}
+}
+
 // to satisfy SdkLibraryDependency interface
 func (module *SdkLibrary) SdkHeaderJars(ctx android.BaseContext, sdkVersion string) android.Paths {
 	// This module is just a wrapper for the stubs.
 	if ctx.Config().UnbundledBuildUsePrebuiltSdks() {
 		return module.PrebuiltJars(ctx, sdkVersion)
+	} else if sdkVersion == "" {
+		return module.Library.HeaderJars()
 	} else {
-		if strings.HasPrefix(sdkVersion, "system_") {
+		switch module.apiScopeForSdkVersion(sdkVersion) {
+		case apiScopeModuleLib:
+			return module.moduleLibApiStubsPath
+		case apiScopeSystemServer:
+			return module.systemServerApiStubsPath
+		case apiScopeSystem:
 			return module.systemApiStubsPath
-		} else if sdkVersion == "" {
-			return module.Library.HeaderJars()
-		} else {
+		default:
 			return module.publicApiStubsPath
 		}
 	}
@@ -647,12 +796,17 @@ func (module *SdkLibrary) SdkImplementationJars(ctx android.BaseContext, sdkVers
 	// This module is just a wrapper for the stubs.
 	if ctx.Config().UnbundledBuildUsePrebuiltSdks() {
 		return module.PrebuiltJars(ctx, sdkVersion)
+	} else if sdkVersion == "" {
+		return module.Library.ImplementationJars()
 	} else {
-		if strings.HasPrefix(sdkVersion, "system_") {
+		switch module.apiScopeForSdkVersion(sdkVersion) {
+		case apiScopeModuleLib:
+			return module.moduleLibApiStubsImplPath
+		case apiScopeSystemServer:
+			return module.systemServerApiStubsImplPath
+		case apiScopeSystem:
 			return module.systemApiStubsImplPath
-		} else if sdkVersion == "" {
-			return module.Library.ImplementationJars()
-		} else {
+		default:
 			return module.publicApiStubsImplPath
 		}
 	}
@@ -692,7 +846,16 @@ func (module *SdkLibrary) createInternalModules(mctx android.TopDownMutatorConte
 
 	missing_current_api := false
 
-	for _, scope := range []string{"", "system-", "test-"} {
+	scopes := []string{"", "system-", "test-"}
+	var extraScopes []string
+	for _, apiScope := range []apiScope{apiScopeModuleLib, apiScopeSystemServer} {
+		if module.hasApiScope(apiScope) {
+			scopes = append(scopes, apiFilePrefix(apiScope))
+			extraScopes = append(extraScopes, strings.TrimSuffix(apiFilePrefix(apiScope), "-"))
+		}
+	}
+
+	for _, scope := range scopes {
 		for _, api := range []string{"current.txt", "removed.txt"} {
 			path := path.Join(mctx.ModuleDir(), "api", scope+api)
 			p := android.ExistentPathForSource(mctx, path)
@@ -713,7 +876,8 @@ func (module *SdkLibrary) createInternalModules(mctx android.TopDownMutatorConte
 
 		mctx.ModuleErrorf("One or more current api files are missing. "+
 			"You can update them by:\n"+
-			"%s %q && m update-api", script, mctx.ModuleDir())
+			"%s %q%s && m update-api", script, mctx.ModuleDir(),
+			android.JoinWithPrefix(extraScopes, " "))
 		return
 	}
 
@@ -730,6 +894,14 @@ func (module *SdkLibrary) createInternalModules(mctx android.TopDownMutatorConte
 		module.createStubsLibrary(mctx, apiScopeTest)
 		module.createDocs(mctx, apiScopeTest)
 
+		// for module-lib and system-server API stubs, if requested
+		for _, apiScope := range []apiScope{apiScopeModuleLib, apiScopeSystemServer} {
+			if module.hasApiScope(apiScope) {
+				module.createStubsLibrary(mctx, apiScope)
+				module.createDocs(mctx, apiScope)
+			}
+		}
+
 		// for runtime
 		module.createXmlFile(mctx)
 	}
@@ -96,6 +96,22 @@ func TestClasspath(t *testing.T) {
 			system:        "bootclasspath", // special value to tell 1.9 test to expect bootclasspath
 			aidl:          "-p" + buildDir + "/framework.aidl",
 		},
+		{
+
+			name:          "module_current",
+			properties:    `sdk_version: "module_current",`,
+			bootclasspath: []string{"android_module_lib_stubs_current", "core-lambda-stubs"},
+			system:        "bootclasspath", // special value to tell 1.9 test to expect bootclasspath
+			aidl:          "-p" + buildDir + "/framework.aidl",
+		},
+		{
+
+			name:          "system_server_current",
+			properties:    `sdk_version: "system_server_current",`,
+			bootclasspath: []string{"android_system_server_stubs_current", "core-lambda-stubs"},
+			system:        "bootclasspath", // special value to tell 1.9 test to expect bootclasspath
+			aidl:          "-p" + buildDir + "/framework.aidl",
+		},
 		{
 
 			name:          "core_current",
@@ -343,3 +359,23 @@ func TestClasspath(t *testing.T) {
 	}
 
 }
+
+func TestSdkVersionWithoutFrameworkStubs(t *testing.T) {
+	// Build a tree whose framework doesn't define the module-lib stubs.
+	bp := strings.Replace(GatherRequiredDepsForTest(), `"android_module_lib_stubs_current"`,
+		`"unused_module_lib_stubs_current"`, 1) + `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			sdk_version: "module_current",
+		}
+	`
+
+	config := testConfig(nil)
+	ctx := testContext(config, "", map[string][]byte{"Android.bp": []byte(bp)})
+	ctx.Register()
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp", "prebuilts/sdk/Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfNoMatchingErrors(t, `sdk_version: "module_current" is not supported by this tree`, errs)
+}
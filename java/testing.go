@@ -42,6 +42,8 @@ func GatherRequiredDepsForTest() string {
 		"android_stubs_current",
 		"android_system_stubs_current",
 		"android_test_stubs_current",
+		"android_module_lib_stubs_current",
+		"android_system_server_stubs_current",
 		"core.current.stubs",
 		"core.platform.api.stubs",
 		"kotlin-stdlib",
@@ -84,6 +86,8 @@ func GatherRequiredDepsForTest() string {
 		"android_stubs_current_system_modules",
 		"android_system_stubs_current_system_modules",
 		"android_test_stubs_current_system_modules",
+		"android_module_lib_stubs_current_system_modules",
+		"android_system_server_stubs_current_system_modules",
 	}
 
 	for _, extra := range systemModules {
@@ -52,6 +52,13 @@ var (
 		},
 		"interp", "main", "template", "stub", "srcsZips")
 
+	wheelImport = pctx.AndroidStaticRule("wheelImport",
+		blueprint.RuleParams{
+			Command:     `$zip2zipCmd -i $in -o $out -s -t $args`,
+			CommandDeps: []string{"$zip2zipCmd"},
+		},
+		"args")
+
 	embeddedPar = pctx.AndroidStaticRule("embeddedPar",
 		blueprint.RuleParams{
 			Command: `rm -f $out.main && ` +
@@ -77,6 +84,7 @@ func init() {
 
 	pctx.HostBinToolVariable("parCmd", "soong_zip")
 	pctx.HostBinToolVariable("mergeParCmd", "merge_zips")
+	pctx.HostBinToolVariable("zip2zipCmd", "zip2zip")
 }
 
 func registerBuildActionForParFile(ctx android.ModuleContext, embeddedLauncher bool,
@@ -52,8 +52,9 @@ type VersionProperties struct {
 	// list of the Python libraries under this Python version.
 	Libs []string `android:"arch_variant"`
 
-	// true, if the binary is required to be built with embedded launcher.
-	// TODO(nanzhang): Remove this flag when embedded Python3 is supported later.
+	// true, if the binary is required to be built with embedded launcher, which bundles the
+	// interpreter and the standard library of this Python version into the .par file so that it
+	// doesn't depend on the Python installed on the host.
 	Embedded_launcher *bool `android:"arch_variant"`
 }
 
@@ -124,6 +125,10 @@ type Module struct {
 	// the installer might be nil.
 	installer installer
 
+	// the properties of the imported wheel.
+	// wheelProperties is nil for modules other than python_wheel_import.
+	wheelProperties *WheelImportProperties
+
 	// the Python files of current module after expanding source dependencies.
 	// pathMapping: <dest: runfile_path, src: source_path>
 	srcsPathMappings []pathMapping
@@ -138,6 +143,10 @@ type Module struct {
 	// dependency modules' zip filepath for zipping current module source/data files.
 	depsSrcsZips android.Paths
 
+	// the data files of dependency modules.
+	// pathMapping: <dest: runfile_path, src: source_path>
+	depsDataPathMappings []pathMapping
+
 	// (.intermediate) module output path as installation source.
 	installSource android.OptionalPath
 
@@ -193,6 +202,9 @@ func (p *Module) Init() android.Module {
 	if p.bootstrapper != nil {
 		p.AddProperties(p.bootstrapper.bootstrapperProps()...)
 	}
+	if p.wheelProperties != nil {
+		p.AddProperties(p.wheelProperties)
+	}
 
 	android.InitAndroidArchModule(p, p.hod, p.multilib)
 	android.InitDefaultableModule(p)
@@ -300,29 +312,7 @@ func (p *Module) DepsMutator(ctx android.BottomUpMutatorContext) {
 				p.properties.Version.Py2.Libs)...)
 
 		if p.bootstrapper != nil && p.isEmbeddedLauncherEnabled(pyVersion2) {
-			ctx.AddVariationDependencies(nil, pythonLibTag, "py2-stdlib")
-
-			launcherModule := "py2-launcher"
-			if p.bootstrapper.autorun() {
-				launcherModule = "py2-launcher-autorun"
-			}
-			ctx.AddFarVariationDependencies([]blueprint.Variation{
-				{Mutator: "arch", Variation: ctx.Target().String()},
-			}, launcherTag, launcherModule)
-
-			// Add py2-launcher shared lib dependencies. Ideally, these should be
-			// derived from the `shared_libs` property of "py2-launcher". However, we
-			// cannot read the property at this stage and it will be too late to add
-			// dependencies later.
-			ctx.AddFarVariationDependencies([]blueprint.Variation{
-				{Mutator: "arch", Variation: ctx.Target().String()},
-			}, launcherSharedLibTag, "libsqlite")
-
-			if ctx.Target().Os.Bionic() {
-				ctx.AddFarVariationDependencies([]blueprint.Variation{
-					{Mutator: "arch", Variation: ctx.Target().String()},
-				}, launcherSharedLibTag, "libc", "libdl", "libm")
-			}
+			p.addEmbeddedLauncherDeps(ctx, "py2")
 		}
 
 	case pyVersion3:
@@ -331,9 +321,7 @@ func (p *Module) DepsMutator(ctx android.BottomUpMutatorContext) {
 				p.properties.Version.Py3.Libs)...)
 
 		if p.bootstrapper != nil && p.isEmbeddedLauncherEnabled(pyVersion3) {
-			//TODO(nanzhang): Add embedded launcher for Python3.
-			ctx.PropertyErrorf("version.py3.embedded_launcher",
-				"is not supported yet for Python3.")
+			p.addEmbeddedLauncherDeps(ctx, "py3")
 		}
 	default:
 		panic(fmt.Errorf("unknown Python Actual_version: %q for module: %q.",
@@ -341,6 +329,34 @@ func (p *Module) DepsMutator(ctx android.BottomUpMutatorContext) {
 	}
 }
 
+// add the dependencies on the standard library and the launcher of the given Python version
+// ("py2" or "py3") for binaries built with embedded launcher.
+func (p *Module) addEmbeddedLauncherDeps(ctx android.BottomUpMutatorContext, version string) {
+	ctx.AddVariationDependencies(nil, pythonLibTag, version+"-stdlib")
+
+	launcherModule := version + "-launcher"
+	if p.bootstrapper.autorun() {
+		launcherModule = version + "-launcher-autorun"
+	}
+	ctx.AddFarVariationDependencies([]blueprint.Variation{
+		{Mutator: "arch", Variation: ctx.Target().String()},
+	}, launcherTag, launcherModule)
+
+	// Add launcher shared lib dependencies. Ideally, these should be
+	// derived from the `shared_libs` property of the launcher. However, we
+	// cannot read the property at this stage and it will be too late to add
+	// dependencies later.
+	ctx.AddFarVariationDependencies([]blueprint.Variation{
+		{Mutator: "arch", Variation: ctx.Target().String()},
+	}, launcherSharedLibTag, "libsqlite")
+
+	if ctx.Target().Os.Bionic() {
+		ctx.AddFarVariationDependencies([]blueprint.Variation{
+			{Mutator: "arch", Variation: ctx.Target().String()},
+		}, launcherSharedLibTag, "libc", "libdl", "libm")
+	}
+}
+
 // check "libs" duplicates from current module dependencies.
 func uniqueLibs(ctx android.BottomUpMutatorContext,
 	commonLibs []string, versionProp string, versionLibs []string) []string {
@@ -375,12 +391,7 @@ func (p *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	// Only Python binaries and test has non-empty bootstrapper.
 	if p.bootstrapper != nil {
 		p.walkTransitiveDeps(ctx)
-		// TODO(nanzhang): Since embedded launcher is not supported for Python3 for now,
-		// so we initialize "embedded_launcher" to false.
-		embeddedLauncher := false
-		if p.properties.Actual_version == pyVersion2 {
-			embeddedLauncher = p.isEmbeddedLauncherEnabled(pyVersion2)
-		}
+		embeddedLauncher := p.isEmbeddedLauncherEnabled(p.properties.Actual_version)
 		p.installSource = p.bootstrapper.bootstrap(ctx, p.properties.Actual_version,
 			embeddedLauncher, p.srcsPathMappings, p.srcsZip, p.depsSrcsZips)
 	}
@@ -394,6 +405,11 @@ func (p *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		})
 		p.installer.setAndroidMkSharedLibs(sharedLibs)
 
+		if test, ok := p.installer.(*testDecorator); ok {
+			test.data = append(append([]pathMapping(nil), p.dataPathMappings...),
+				p.depsDataPathMappings...)
+		}
+
 		if p.installSource.Valid() {
 			p.installer.install(ctx, p.installSource.Path())
 		}
@@ -421,6 +437,10 @@ func (p *Module) GeneratePythonBuildActions(ctx android.ModuleContext) {
 	if p.bootstrapper != nil && !p.bootstrapper.autorun() {
 		requiresSrcs = false
 	}
+	if p.wheelProperties != nil {
+		// the sources of python_wheel_import modules are in the wheel.
+		requiresSrcs = false
+	}
 	if len(expandedSrcs) == 0 && requiresSrcs {
 		ctx.ModuleErrorf("doesn't have any source files!")
 	}
@@ -517,6 +537,11 @@ func (p *Module) createSrcsZip(ctx android.ModuleContext, pkgPath string) androi
 		}
 	}
 	var zips android.Paths
+	if p.wheelProperties != nil {
+		if zip := p.importWheel(ctx, pkgPath); zip != nil {
+			zips = append(zips, zip)
+		}
+	}
 	if len(protoSrcs) > 0 {
 		protoFlags := android.GetProtoFlags(ctx, &p.protoProperties)
 		protoFlags.OutTypeFlag = "--python_out"
@@ -628,8 +653,10 @@ func (p *Module) walkTransitiveDeps(ctx android.ModuleContext) {
 			}
 			data := dep.GetDataPathMappings()
 			for _, path := range data {
-				fillInMap(ctx, destToPyData,
-					path.dest, path.src.String(), ctx.ModuleName(), ctx.OtherModuleName(child))
+				if fillInMap(ctx, destToPyData,
+					path.dest, path.src.String(), ctx.ModuleName(), ctx.OtherModuleName(child)) {
+					p.depsDataPathMappings = append(p.depsDataPathMappings, path)
+				}
 			}
 			p.depsSrcsZips = append(p.depsSrcsZips, dep.GetSrcsZip())
 		}
@@ -50,6 +50,7 @@ var (
 	noSrcFileErr      = moduleVariantErrTemplate + "doesn't have any source files!"
 	badSrcFileExtErr  = moduleVariantErrTemplate + "srcs: found non (.py|.proto) file: %q!"
 	badDataFileExtErr = moduleVariantErrTemplate + "data: found (.py|.proto) file: %q!"
+	badWheelErr       = moduleVariantErrTemplate + "wheel: %q is not compatible with %s, its Python tags are %q."
 	bpFile            = "Blueprints"
 
 	data = []struct {
@@ -320,6 +321,63 @@ var (
 				},
 			},
 		},
+		{
+			desc: "module with wheel import",
+			mockFiles: map[string][]byte{
+				bpFile: []byte(`subdirs = ["dir"]`),
+				filepath.Join("dir", bpFile): []byte(
+					`python_wheel_import {
+						name: "six",
+						pkg_path: "third_party",
+						wheel: "six-1.12.0-py2.py3-none-any.whl",
+					}
+
+					python_binary_host {
+						name: "bin",
+						srcs: [
+							"bin.py",
+						],
+						libs: [
+							"six",
+						],
+					}`,
+				),
+				filepath.Join("dir", "six-1.12.0-py2.py3-none-any.whl"): nil,
+				filepath.Join("dir", "bin.py"):                          nil,
+				stubTemplateHost: []byte(`PYTHON_BINARY = '%interpreter%'
+				MAIN_FILE = '%main%'`),
+			},
+			expectedBinaries: []pyModule{
+				{
+					name:          "bin",
+					actualVersion: "PY3",
+					pyRunfiles: []string{
+						"bin.py",
+					},
+					srcsZip: "@prefix@/.intermediates/dir/bin/PY3/bin.py.srcszip",
+					depsSrcsZips: []string{
+						"@prefix@/.intermediates/dir/six/PY3/six.whl.srcszip",
+					},
+				},
+			},
+		},
+		{
+			desc: "module with incompatible wheel",
+			mockFiles: map[string][]byte{
+				bpFile: []byte(`subdirs = ["dir"]`),
+				filepath.Join("dir", bpFile): []byte(
+					`python_wheel_import {
+						name: "futures",
+						wheel: "futures-3.2.0-py2-none-any.whl",
+					}`,
+				),
+				filepath.Join("dir", "futures-3.2.0-py2-none-any.whl"): nil,
+			},
+			errors: []string{
+				fmt.Sprintf(badWheelErr,
+					"dir/Blueprints:3:12", "futures", "PY3", "futures-3.2.0-py2-none-any.whl", "PY3", "py2"),
+			},
+		},
 	}
 )
 
@@ -336,6 +394,8 @@ func TestPythonModule(t *testing.T) {
 				android.ModuleFactoryAdaptor(PythonLibraryHostFactory))
 			ctx.RegisterModuleType("python_binary_host",
 				android.ModuleFactoryAdaptor(PythonBinaryHostFactory))
+			ctx.RegisterModuleType("python_wheel_import",
+				android.ModuleFactoryAdaptor(PythonWheelImportFactory))
 			ctx.RegisterModuleType("python_defaults",
 				android.ModuleFactoryAdaptor(defaultsFactory))
 			ctx.PreArchMutators(android.RegisterDefaultsPreArchMutators)
@@ -361,6 +421,100 @@ func TestPythonModule(t *testing.T) {
 	}
 }
 
+func TestPythonTestDataAndSupportScripts(t *testing.T) {
+	config, buildDir := setupBuildEnv(t)
+	defer tearDownBuildEnv(buildDir)
+
+	ctx := android.NewTestContext()
+	ctx.PreDepsMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.BottomUp("version_split", versionSplitMutator()).Parallel()
+	})
+	ctx.RegisterModuleType("python_library_host",
+		android.ModuleFactoryAdaptor(PythonLibraryHostFactory))
+	ctx.RegisterModuleType("python_test_host",
+		android.ModuleFactoryAdaptor(PythonTestHostFactory))
+	ctx.Register()
+
+	mockFiles := map[string][]byte{
+		bpFile: []byte(`subdirs = ["dir"]`),
+		filepath.Join("dir", bpFile): []byte(
+			`python_library_host {
+				name: "lib1",
+				pkg_path: "a",
+				srcs: [
+					"lib1.py",
+				],
+				data: [
+					"libdata/lib1.txt",
+				],
+			}
+
+			python_test_host {
+				name: "test1",
+				pkg_path: "b",
+				srcs: [
+					"test1.py",
+				],
+				data: [
+					"testdata/test1.txt",
+				],
+				libs: [
+					"lib1",
+				],
+			}`,
+		),
+		filepath.Join("dir", "lib1.py"):            nil,
+		filepath.Join("dir", "libdata/lib1.txt"):   nil,
+		filepath.Join("dir", "test1.py"):           nil,
+		filepath.Join("dir", "testdata/test1.txt"): nil,
+		stubTemplateHost: []byte(`PYTHON_BINARY = '%interpreter%'
+				MAIN_FILE = '%main%'`),
+	}
+	for _, script := range testSupportScripts {
+		mockFiles[script] = nil
+	}
+	ctx.MockFileSystem(mockFiles)
+
+	_, errs := ctx.ParseBlueprintsFiles(bpFile)
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	module := ctx.ModuleForTests("test1", "PY3")
+	test := module.Module().(*Module).installer.(*testDecorator)
+
+	// Ensure that the data files of the test and its dependencies are installed at their
+	// runfiles path.
+	var data []string
+	for _, d := range test.data {
+		data = append(data, d.dest+":"+d.src.String())
+	}
+	expectedData := []string{
+		"b/testdata/test1.txt:dir/testdata/test1.txt",
+		"a/libdata/lib1.txt:dir/libdata/lib1.txt",
+	}
+	if !reflect.DeepEqual(data, expectedData) {
+		t.Errorf("expected data %q, got %q", expectedData, data)
+	}
+	for _, d := range []string{"b/testdata/test1.txt", "a/libdata/lib1.txt"} {
+		if !strings.Contains(strings.Join(module.AllOutputs(), " "), "/nativetest/test1/"+d) {
+			t.Errorf("expected %q to be installed, got %q", d, module.AllOutputs())
+		}
+	}
+
+	// Ensure that the test support scripts are in the par file.
+	supportZip := module.Output("test_support.srcszip")
+	if g, w := supportZip.Args["args"], "-C build/soong/python/scripts "+
+		"-f build/soong/python/scripts/soong_runfiles.py "+
+		"-f build/soong/python/scripts/soong_test_runner.py"; g != w {
+		t.Errorf("expected test support archive args %q, got %q", w, g)
+	}
+	par := module.Rule("hostPar")
+	if !strings.Contains(par.Args["srcsZips"], supportZip.Output.String()) {
+		t.Errorf("expected %q in the par file, got %q", supportZip.Output.String(), par.Args["srcsZips"])
+	}
+}
+
 func expectErrors(t *testing.T, actErrs []error, expErrs []string) (testErrs []error) {
 	actErrStrs := []string{}
 	for _, v := range actErrs {
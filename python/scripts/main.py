@@ -9,4 +9,15 @@ sys.argv[0] = __loader__.archive
 # when people try to use it.
 sys.executable = None
 
+# python_test modules run their tests with soong_test_runner instead of running
+# the main module when the first argument is --test-runner.
+if sys.argv[1:2] == ["--test-runner"]:
+    try:
+        import soong_test_runner
+    except ImportError:
+        soong_test_runner = None
+    if soong_test_runner is not None:
+        del sys.argv[1]
+        sys.exit(soong_test_runner.main("ENTRY_POINT", sys.argv[1:]))
+
 runpy._run_module_as_main("ENTRY_POINT", alter_argv=False)
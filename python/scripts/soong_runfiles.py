@@ -0,0 +1,70 @@
+# Copyright 2019 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""Finds the data files of a python_test at runtime.
+
+The data files of a module are at the same path in the runfiles as in the par
+file: the pkg_path of the module followed by their path relative to the
+directory of the module.  They are also installed next to the test, so that
+tests built with the embedded launcher, which don't extract the par file, can
+open them:
+
+  import soong_runfiles
+  with open(soong_runfiles.rlocation('mypkg/testdata/input.txt')) as f:
+    ...
+"""
+
+import atexit
+import os
+import shutil
+import sys
+import tempfile
+import zipfile
+
+# The environment variable that the launcher sets to the directory the par file
+# is extracted to.
+RUNFILES_ENV = 'SOONG_PYTHON_RUNFILES'
+
+_extracted_dir = None
+
+
+def _extract(archive, path):
+  global _extracted_dir
+  if _extracted_dir is None:
+    _extracted_dir = tempfile.mkdtemp('', 'Soong.python_runfiles_')
+    atexit.register(shutil.rmtree, _extracted_dir, True)
+  zipfile.ZipFile(archive).extract(path, _extracted_dir)
+  return os.path.join(_extracted_dir, path)
+
+
+def rlocation(path):
+  """Returns the absolute path of the data file at the given runfiles path."""
+  runfiles_dir = os.environ.get(RUNFILES_ENV)
+  if runfiles_dir:
+    candidate = os.path.join(runfiles_dir, path)
+    if os.path.exists(candidate):
+      return candidate
+
+  # The data files installed next to the test.
+  test_path = os.path.realpath(sys.argv[0])
+  candidate = os.path.join(os.path.dirname(test_path), path)
+  if os.path.exists(candidate):
+    return candidate
+
+  # The data files in the par file of a test built with the embedded launcher.
+  if zipfile.is_zipfile(test_path):
+    if path in zipfile.ZipFile(test_path).namelist():
+      return _extract(test_path, path)
+
+  raise IOError('runfile %r not found' % path)
@@ -0,0 +1,148 @@
+# Copyright 2019 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""Runs the unittest test cases of the main module of a python_test and
+writes the results as JUnit XML.
+
+The launcher of python_test modules runs this instead of the main module when
+the first argument is --test-runner, e.g.:
+
+  $ANDROID_HOST_OUT/nativetest64/foo_test/foo_test --test-runner \\
+      --junit-xml foo_test.xml
+"""
+
+import argparse
+import importlib
+import os
+import sys
+import time
+import unittest
+from xml.etree import ElementTree
+
+# The environment variable that tradefed and other harnesses use to ask for
+# the results in a specific file.
+XML_OUTPUT_FILE = 'XML_OUTPUT_FILE'
+
+
+class JUnitResult(unittest.TextTestResult):
+  """Records the outcome and the duration of each test case."""
+
+  def __init__(self, stream, descriptions, verbosity):
+    super(JUnitResult, self).__init__(stream, descriptions, verbosity)
+    self.cases = []
+    self._start = 0
+
+  def startTest(self, test):
+    self._start = time.time()
+    super(JUnitResult, self).startTest(test)
+
+  def _record(self, test, status, message=None, details=None):
+    self.cases.append((test, status, message, details, time.time() - self._start))
+
+  def addSuccess(self, test):
+    super(JUnitResult, self).addSuccess(test)
+    self._record(test, 'passed')
+
+  def addFailure(self, test, err):
+    super(JUnitResult, self).addFailure(test, err)
+    self._record(test, 'failure', str(err[1]), self.failures[-1][1])
+
+  def addError(self, test, err):
+    super(JUnitResult, self).addError(test, err)
+    self._record(test, 'error', str(err[1]), self.errors[-1][1])
+
+  def addSkip(self, test, reason):
+    super(JUnitResult, self).addSkip(test, reason)
+    self._record(test, 'skipped', reason)
+
+  def addExpectedFailure(self, test, err):
+    super(JUnitResult, self).addExpectedFailure(test, err)
+    self._record(test, 'passed')
+
+  def addUnexpectedSuccess(self, test):
+    super(JUnitResult, self).addUnexpectedSuccess(test)
+    self._record(test, 'failure', 'unexpected success')
+
+
+def junit_xml(suite_name, result, duration):
+  """Returns the JUnit XML tree of the results of a test run."""
+  counts = {'failure': 0, 'error': 0, 'skipped': 0}
+  testsuite = ElementTree.Element('testsuite')
+  for test, status, message, details, case_duration in result.cases:
+    test_id = test.id()
+    classname, _, name = test_id.rpartition('.')
+    testcase = ElementTree.SubElement(testsuite, 'testcase', {
+        'classname': classname,
+        'name': name,
+        'time': '%.3f' % case_duration,
+    })
+    if status in counts:
+      counts[status] += 1
+      element = ElementTree.SubElement(testcase, status)
+      if message is not None:
+        element.set('message', message)
+      if details is not None:
+        element.text = details
+
+  testsuite.set('name', suite_name)
+  testsuite.set('tests', str(len(result.cases)))
+  testsuite.set('failures', str(counts['failure']))
+  testsuite.set('errors', str(counts['error']))
+  testsuite.set('skipped', str(counts['skipped']))
+  testsuite.set('time', '%.3f' % duration)
+
+  testsuites = ElementTree.Element('testsuites')
+  testsuites.append(testsuite)
+  return ElementTree.ElementTree(testsuites)
+
+
+def main(module_name, argv):
+  """Runs the tests of the given module, returns the exit code."""
+  parser = argparse.ArgumentParser(
+      prog=os.path.basename(sys.argv[0]) + ' --test-runner',
+      description='Runs the unittest test cases of ' + module_name + '.')
+  parser.add_argument('--junit-xml', default=os.environ.get(XML_OUTPUT_FILE),
+                      help='file to write the results to as JUnit XML, '
+                      'defaults to $' + XML_OUTPUT_FILE)
+  parser.add_argument('-v', '--verbose', action='store_const', const=2,
+                      default=1, dest='verbosity',
+                      help='print the name of each test case')
+  parser.add_argument('tests', nargs='*',
+                      help='names of the test classes or methods to run, '
+                      'e.g. MyTest or MyTest.test_foo')
+  args = parser.parse_args(argv)
+
+  module = importlib.import_module(module_name)
+  loader = unittest.TestLoader()
+  if args.tests:
+    suite = loader.loadTestsFromNames(args.tests, module)
+  else:
+    suite = loader.loadTestsFromModule(module)
+
+  runner = unittest.TextTestRunner(verbosity=args.verbosity,
+                                   resultclass=JUnitResult)
+  start = time.time()
+  result = runner.run(suite)
+
+  if args.junit_xml:
+    junit_xml(module_name, result, time.time() - start).write(
+        args.junit_xml, encoding='utf-8')
+
+  return 0 if result.wasSuccessful() else 1
+
+
+if __name__ == '__main__':
+  if len(sys.argv) < 2:
+    sys.exit('usage: %s <module> [args...]' % sys.argv[0])
+  sys.exit(main(sys.argv[1], sys.argv[2:]))
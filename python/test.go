@@ -15,6 +15,9 @@
 package python
 
 import (
+	"path/filepath"
+	"strings"
+
 	"android/soong/android"
 	"android/soong/tradefed"
 )
@@ -36,18 +39,63 @@ type TestProperties struct {
 	Test_config_template *string `android:"arch_variant"`
 }
 
+var (
+	// the scripts that are added to the par files of tests, at the root of the runfiles.
+	// soong_test_runner.py runs the tests when the test is run with --test-runner, and
+	// soong_runfiles.py finds the data files of the test.
+	testSupportScripts = []string{
+		"build/soong/python/scripts/soong_runfiles.py",
+		"build/soong/python/scripts/soong_test_runner.py",
+	}
+)
+
 type testDecorator struct {
 	*binaryDecorator
 
 	testProperties TestProperties
 
 	testConfig android.Path
+
+	// the data files of the test and its dependencies, installed next to the test at their
+	// runfiles path.
+	data []pathMapping
 }
 
 func (test *testDecorator) bootstrapperProps() []interface{} {
 	return append(test.binaryDecorator.bootstrapperProps(), &test.testProperties)
 }
 
+func (test *testDecorator) bootstrap(ctx android.ModuleContext, actualVersion string,
+	embeddedLauncher bool, srcsPathMappings []pathMapping, srcsZip android.Path,
+	depsSrcsZips android.Paths) android.OptionalPath {
+
+	return test.binaryDecorator.bootstrap(ctx, actualVersion, embeddedLauncher,
+		srcsPathMappings, srcsZip, append(depsSrcsZips, testSupportZip(ctx)))
+}
+
+// register build actions to zip the test support scripts.
+func testSupportZip(ctx android.ModuleContext) android.Path {
+	scripts := android.PathsForSource(ctx, testSupportScripts)
+
+	parArgs := []string{`-C ` + filepath.Dir(testSupportScripts[0])}
+	for _, script := range scripts {
+		parArgs = append(parArgs, `-f `+script.String())
+	}
+
+	supportZip := android.PathForModuleOut(ctx, "test_support.srcszip")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        zip,
+		Description: "python test support archive",
+		Output:      supportZip,
+		Implicits:   scripts,
+		Args: map[string]string{
+			"args": strings.Join(parArgs, " "),
+		},
+	})
+
+	return supportZip
+}
+
 func (test *testDecorator) install(ctx android.ModuleContext, file android.Path) {
 	test.testConfig = tradefed.AutoGenPythonBinaryHostTestConfig(ctx, test.testProperties.Test_config,
 		test.testProperties.Test_config_template, test.binaryDecorator.binaryProperties.Test_suites)
@@ -58,6 +106,23 @@ func (test *testDecorator) install(ctx android.ModuleContext, file android.Path)
 	test.binaryDecorator.pythonInstaller.relative = ctx.ModuleName()
 
 	test.binaryDecorator.pythonInstaller.install(ctx, file)
+
+	installDir := test.binaryDecorator.pythonInstaller.installDir(ctx)
+	for _, d := range test.data {
+		dir, file := filepath.Split(d.dest)
+		ctx.InstallFile(installDir.Join(ctx, dir), file, d.src)
+	}
+}
+
+func (p *Module) TestModuleInfo() *android.TestModuleInfo {
+	test, ok := p.installer.(*testDecorator)
+	if !ok {
+		return nil
+	}
+	return &android.TestModuleInfo{
+		TestSuites: test.binaryDecorator.binaryProperties.Test_suites,
+		TestConfig: test.testConfig,
+	}
 }
 
 func NewTest(hod android.HostOrDeviceSupported) *Module {
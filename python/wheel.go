@@ -0,0 +1,116 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+// This file contains the module type for importing prebuilt Python wheels as libraries.
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterModuleType("python_wheel_import", PythonWheelImportFactory)
+}
+
+type WheelImportProperties struct {
+	// the .whl file to import. The packages and the .dist-info directory in the wheel are
+	// placed under pkg_path, like the srcs of a python_library.
+	// The Python tag in the name of the wheel must match the version of each variant, e.g.
+	// py2.py3 wheels can be used for both Python2 and Python3.
+	Wheel *string `android:"path,arch_variant"`
+}
+
+func PythonWheelImportFactory() android.Module {
+	module := newModule(android.HostAndDeviceSupported, android.MultilibBoth)
+	module.wheelProperties = &WheelImportProperties{}
+
+	return module.Init()
+}
+
+// wheelPythonTags returns the Python tags from the name of a wheel, which is
+// {distribution}-{version}(-{build tag})?-{python tag}-{abi tag}-{platform tag}.whl, and false if
+// the name isn't a valid wheel name.
+func wheelPythonTags(name string) ([]string, bool) {
+	if !strings.HasSuffix(name, ".whl") {
+		return nil, false
+	}
+	fields := strings.Split(strings.TrimSuffix(name, ".whl"), "-")
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, false
+	}
+	return strings.Split(fields[len(fields)-3], "."), true
+}
+
+// wheelSupportsVersion returns true if one of the Python tags of a wheel, like py3 or cp37, is
+// for the given Python version.
+func wheelSupportsVersion(tags []string, actualVersion string) bool {
+	major := ""
+	switch actualVersion {
+	case pyVersion2:
+		major = "2"
+	case pyVersion3:
+		major = "3"
+	}
+	for _, tag := range tags {
+		for _, impl := range []string{"py", "cp"} {
+			if strings.HasPrefix(tag, impl+major) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// register build actions to repackage the wheel of a python_wheel_import module under pkgPath.
+func (p *Module) importWheel(ctx android.ModuleContext, pkgPath string) android.Path {
+	if p.wheelProperties.Wheel == nil {
+		ctx.PropertyErrorf("wheel", "missing wheel file")
+		return nil
+	}
+	wheel := android.PathForModuleSrc(ctx, *p.wheelProperties.Wheel)
+
+	tags, ok := wheelPythonTags(wheel.Base())
+	if !ok {
+		ctx.PropertyErrorf("wheel", "%q is not named like a wheel, "+
+			"{distribution}-{version}(-{build tag})?-{python tag}-{abi tag}-{platform tag}.whl",
+			wheel.Base())
+		return nil
+	}
+	if !wheelSupportsVersion(tags, p.properties.Actual_version) {
+		ctx.PropertyErrorf("wheel", "%q is not compatible with %s, its Python tags are %q.",
+			wheel.Base(), p.properties.Actual_version, strings.Join(tags, "."))
+		return nil
+	}
+
+	args := ""
+	if pkgPath != "" {
+		args = `'**/*:` + pkgPath + `'`
+	}
+
+	srcsZip := android.PathForModuleOut(ctx, ctx.ModuleName()+".whl.srcszip")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        wheelImport,
+		Description: "python wheel import",
+		Input:       wheel,
+		Output:      srcsZip,
+		Args: map[string]string{
+			"args": args,
+		},
+	})
+
+	return srcsZip
+}
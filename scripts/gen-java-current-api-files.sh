@@ -15,15 +15,20 @@
 # limitations under the License.
 
 if [[ -z "$1" ]]; then
-  echo "usage: $0 <modulePath>" >&2
+  echo "usage: $0 <modulePath> [<extra scope>...]" >&2
+  echo "  extra scopes: module-lib system-server" >&2
   exit 1
 fi
 
 api_dir=$1/api
+shift
 
 mkdir -p "$api_dir"
 
 scopes=("" system- test-)
+for extra in "$@"; do
+  scopes+=("${extra}-")
+done
 apis=(current removed)
 
 for scope in "${scopes[@]}"; do
@@ -16,6 +16,8 @@ package symbol_inject
 
 import (
 	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"io"
 )
@@ -128,6 +130,74 @@ func extractElfSymbols(elfFile mockableElfFile) (*File, error) {
 	return file, nil
 }
 
+// ElfBuildId returns the contents of the NT_GNU_BUILD_ID note of an ELF file as a hex string, or an
+// empty string if the file has no build ID.
+func ElfBuildId(r io.ReaderAt) (string, error) {
+	elfFile, err := elf.NewFile(r)
+	if err != nil {
+		return "", cantParseError{err}
+	}
+
+	for _, section := range elfFile.Sections {
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+		data, err := section.Data()
+		if err != nil {
+			return "", err
+		}
+		id, err := buildIdFromNotes(data, elfFile.ByteOrder)
+		if err != nil {
+			return "", fmt.Errorf("section %s: %s", section.Name, err)
+		}
+		if id != nil {
+			return hex.EncodeToString(id), nil
+		}
+	}
+
+	return "", nil
+}
+
+const ntGnuBuildId = 3
+
+// buildIdFromNotes returns the descriptor of the NT_GNU_BUILD_ID note in the contents of a note
+// section, or nil if there is none.  Each note is a header of three words holding the sizes of the
+// name and the descriptor and the type, followed by the name and the descriptor, each padded to a
+// multiple of 4 bytes.
+func buildIdFromNotes(data []byte, byteOrder binary.ByteOrder) ([]byte, error) {
+	align := func(n uint64) uint64 {
+		return (n + 3) &^ 3
+	}
+
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, fmt.Errorf("truncated note header")
+		}
+		nameSize := uint64(byteOrder.Uint32(data[0:]))
+		descSize := uint64(byteOrder.Uint32(data[4:]))
+		noteType := byteOrder.Uint32(data[8:])
+		data = data[12:]
+
+		if align(nameSize)+descSize > uint64(len(data)) {
+			return nil, fmt.Errorf("truncated note")
+		}
+		name := data[:nameSize]
+		desc := data[align(nameSize) : align(nameSize)+descSize]
+
+		if noteType == ntGnuBuildId && string(name) == "GNU\x00" {
+			return desc, nil
+		}
+
+		next := align(nameSize) + align(descSize)
+		if next > uint64(len(data)) {
+			next = uint64(len(data))
+		}
+		data = data[next:]
+	}
+
+	return nil, nil
+}
+
 func dumpElfSymbols(r io.ReaderAt) error {
 	elfFile, err := elf.NewFile(r)
 	if err != nil {
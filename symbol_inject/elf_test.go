@@ -15,6 +15,8 @@
 package symbol_inject
 
 import (
+	"bytes"
+	"encoding/binary"
 	"strconv"
 	"testing"
 )
@@ -61,3 +63,83 @@ func TestElfSymbolTable(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildIdFromNotes(t *testing.T) {
+	note := func(name string, noteType uint32, desc []byte) []byte {
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, uint32(len(name)))
+		binary.Write(&buf, binary.LittleEndian, uint32(len(desc)))
+		binary.Write(&buf, binary.LittleEndian, noteType)
+		buf.WriteString(name)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		buf.Write(desc)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		return buf.Bytes()
+	}
+
+	id := []byte{0xde, 0xad, 0xbe, 0xef, 0x01}
+	abiTag := note("GNU\x00", 1, []byte{0, 0, 0, 0, 3, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0})
+	android := note("Android\x00", 1, []byte{28, 0, 0, 0})
+
+	testCases := []struct {
+		name string
+		data []byte
+		out  []byte
+		err  bool
+	}{
+		{
+			name: "build id",
+			data: note("GNU\x00", ntGnuBuildId, id),
+			out:  id,
+		},
+		{
+			name: "after other notes",
+			data: append(append(abiTag, android...), note("GNU\x00", ntGnuBuildId, id)...),
+			out:  id,
+		},
+		{
+			name: "wrong owner",
+			data: note("Android\x00", ntGnuBuildId, id),
+			out:  nil,
+		},
+		{
+			name: "no notes",
+			data: nil,
+			out:  nil,
+		},
+		{
+			name: "truncated",
+			data: note("GNU\x00", ntGnuBuildId, id)[:16],
+			err:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			out, err := buildIdFromNotes(testCase.data, binary.LittleEndian)
+			if testCase.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if !bytes.Equal(out, testCase.out) {
+				t.Errorf("expected build id %x, got %x", testCase.out, out)
+			}
+		})
+	}
+}
+
+func TestElfBuildIdNotElf(t *testing.T) {
+	_, err := ElfBuildId(bytes.NewReader([]byte("not an elf file")))
+	if _, ok := err.(cantParseError); !ok {
+		t.Errorf("expected cantParseError, got %v", err)
+	}
+}
@@ -15,6 +15,10 @@
 package sysprop
 
 import (
+	"fmt"
+	"io"
+	"path"
+
 	"android/soong/android"
 	"android/soong/cc"
 	"android/soong/java"
@@ -32,6 +36,9 @@ type syspropLibrary struct {
 
 	commonProperties         commonProperties
 	syspropLibraryProperties syspropLibraryProperties
+
+	checkApiTimestamp  android.WritablePath
+	updateApiTimestamp android.WritablePath
 }
 
 type syspropLibraryProperties struct {
@@ -53,8 +60,44 @@ type commonProperties struct {
 var (
 	Bool         = proptools.Bool
 	syspropCcTag = dependencyTag{name: "syspropCc"}
+
+	pctx = android.NewPackageContext("android/soong/sysprop")
+
+	syspropApiDump = pctx.AndroidStaticRule("syspropApiDump",
+		blueprint.RuleParams{
+			Command:     `${syspropApiCmd} -o $out $in`,
+			CommandDeps: []string{"${syspropApiCmd}"},
+		})
+
+	syspropApiCheck = pctx.AndroidStaticRule("syspropApiCheck",
+		blueprint.RuleParams{
+			Command: `( cmp -s $dumpedApiFile $currentApiFile && ` +
+				`${syspropApiCmd} -latest $latestApiFile $currentApiFile && touch $out ) || ` +
+				`( echo -e "$msg" ; exit 38 )`,
+			CommandDeps: []string{"${syspropApiCmd}"},
+		},
+		"dumpedApiFile", "currentApiFile", "latestApiFile", "msg")
+
+	// syspropApiCheckSkipped prints a warning instead of checking the API of a sysprop_library
+	// without API files.
+	syspropApiCheckSkipped = pctx.AndroidStaticRule("syspropApiCheckSkipped",
+		blueprint.RuleParams{
+			Command: `echo -e "$msg" && touch $out`,
+		},
+		"msg")
+
+	syspropApiUpdate = pctx.AndroidStaticRule("syspropApiUpdate",
+		blueprint.RuleParams{
+			Command: `( mkdir -p $$(dirname $currentApiFile) && cp -f $in $currentApiFile && touch $out ) || ` +
+				`( echo failed to update sysprop API ; exit 38 )`,
+		},
+		"currentApiFile")
 )
 
+func init() {
+	pctx.HostBinToolVariable("syspropApiCmd", "sysprop_api")
+}
+
 func init() {
 	android.RegisterModuleType("sysprop_library", syspropLibraryFactory)
 }
@@ -67,6 +110,124 @@ func (m *syspropLibrary) SyspropJavaModule() *java.SdkLibrary {
 	return &m.SdkLibrary
 }
 
+// The API of a sysprop_library is dumped to api/<name>-current.txt, which must be updated along with the
+// .sysprop files, and must stay backward compatible with api/<name>-latest.txt, the API of the latest
+// release.
+func (m *syspropLibrary) currentApiFile() string {
+	return path.Join("api", m.Name()+"-current.txt")
+}
+
+func (m *syspropLibrary) latestApiFile() string {
+	return path.Join("api", m.Name()+"-latest.txt")
+}
+
+func (m *syspropLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	m.SdkLibrary.GenerateAndroidBuildActions(ctx)
+
+	currentApiFile := android.ExistentPathForSource(ctx, ctx.ModuleDir(), m.currentApiFile())
+	latestApiFile := android.ExistentPathForSource(ctx, ctx.ModuleDir(), m.latestApiFile())
+
+	dumpedApiFile := android.PathForModuleOut(ctx, "api-dump.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        syspropApiDump,
+		Description: "sysprop API dump",
+		Output:      dumpedApiFile,
+		Inputs:      android.PathsForModuleSrc(ctx, m.commonProperties.Srcs),
+	})
+
+	m.checkApiTimestamp = android.PathForModuleOut(ctx, "check_api.timestamp")
+	m.updateApiTimestamp = android.PathForModuleOut(ctx, "update_api.timestamp")
+
+	if !currentApiFile.Valid() || !latestApiFile.Valid() {
+		// The API can't be checked until the API files exist, but update-api can still create
+		// the current one.
+		currentApiPath := path.Join(ctx.ModuleDir(), m.currentApiFile())
+		latestApiPath := path.Join(ctx.ModuleDir(), m.latestApiFile())
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        syspropApiCheckSkipped,
+			Description: "sysprop API check",
+			Output:      m.checkApiTimestamp,
+			Args: map[string]string{
+				"msg": fmt.Sprintf(`warning: skipping the API check of sysprop_library %s because %s or %s is missing.\n`+
+					`You can create them by:\n`+
+					`   make %s-update-api && cp %s %s`,
+					m.Name(), currentApiPath, latestApiPath, m.Name(), currentApiPath, latestApiPath),
+			},
+		})
+
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        syspropApiUpdate,
+			Description: "sysprop API update",
+			Output:      m.updateApiTimestamp,
+			Input:       dumpedApiFile,
+			Args: map[string]string{
+				"currentApiFile": currentApiPath,
+			},
+		})
+		return
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        syspropApiCheck,
+		Description: "sysprop API check",
+		Output:      m.checkApiTimestamp,
+		Implicits:   android.Paths{dumpedApiFile, currentApiFile.Path(), latestApiFile.Path()},
+		Args: map[string]string{
+			"dumpedApiFile":  dumpedApiFile.String(),
+			"currentApiFile": currentApiFile.Path().String(),
+			"latestApiFile":  latestApiFile.Path().String(),
+			"msg": fmt.Sprintf(`\n******************************\n`+
+				`The API of sysprop_library %s doesn't match %s, or breaks the\n`+
+				`backward compatibility with %s.\n\n`+
+				`Removing properties, or changing their type or access, is not allowed.\n`+
+				`Otherwise you can update %s by executing the following command:\n`+
+				`   make %s-update-api\n`+
+				`******************************\n`,
+				m.Name(), currentApiFile.Path(), latestApiFile.Path(), currentApiFile.Path(), m.Name()),
+		},
+	})
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        syspropApiUpdate,
+		Description: "sysprop API update",
+		Output:      m.updateApiTimestamp,
+		Input:       dumpedApiFile,
+		Implicit:    currentApiFile.Path(),
+		Args: map[string]string{
+			"currentApiFile": currentApiFile.Path().String(),
+		},
+	})
+}
+
+func (m *syspropLibrary) AndroidMk() android.AndroidMkData {
+	data := m.SdkLibrary.AndroidMk()
+
+	custom := data.Custom
+	data.Custom = func(w io.Writer, name, prefix, moduleDir string, data android.AndroidMkData) {
+		custom(w, name, prefix, moduleDir, data)
+
+		if m.checkApiTimestamp != nil {
+			fmt.Fprintln(w, ".PHONY:", m.Name()+"-check-api")
+			fmt.Fprintln(w, m.Name()+"-check-api:", m.checkApiTimestamp.String())
+
+			fmt.Fprintln(w, ".PHONY: checkapi")
+			fmt.Fprintln(w, "checkapi:", m.checkApiTimestamp.String())
+
+			fmt.Fprintln(w, ".PHONY: droidcore")
+			fmt.Fprintln(w, "droidcore: checkapi")
+		}
+		if m.updateApiTimestamp != nil {
+			fmt.Fprintln(w, ".PHONY:", m.Name()+"-update-api")
+			fmt.Fprintln(w, m.Name()+"-update-api:", m.updateApiTimestamp.String())
+
+			fmt.Fprintln(w, ".PHONY: update-api")
+			fmt.Fprintln(w, "update-api:", m.updateApiTimestamp.String())
+		}
+	}
+
+	return data
+}
+
 func syspropLibraryFactory() android.Module {
 	m := &syspropLibrary{}
 
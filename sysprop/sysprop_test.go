@@ -21,6 +21,7 @@ import (
 
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -159,6 +160,13 @@ func testContext(config android.Config, bp string,
 
 		"android/sysprop/PlatformProperties.sysprop": nil,
 		"com/android/VendorProperties.sysprop":       nil,
+
+		"api/sysprop-platform-current.txt":            nil,
+		"api/sysprop-platform-latest.txt":             nil,
+		"api/sysprop-platform-on-product-current.txt": nil,
+		"api/sysprop-platform-on-product-latest.txt":  nil,
+		"api/sysprop-vendor-current.txt":              nil,
+		"api/sysprop-vendor-latest.txt":               nil,
 	}
 
 	for k, v := range fs {
@@ -312,6 +320,32 @@ func TestSyspropLibrary(t *testing.T) {
 	ctx.ModuleForTests("sysprop-platform", "android_common")
 	ctx.ModuleForTests("sysprop-vendor", "android_common")
 
+	// Check for the API dump and the API compatibility check
+	platform := ctx.ModuleForTests("sysprop-platform", "android_common")
+	apiDump := platform.Output("api-dump.txt")
+	if g, w := apiDump.Inputs.Strings(), []string{"android/sysprop/PlatformProperties.sysprop"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected API dump inputs %q, got %q", w, g)
+	}
+
+	checkApi := platform.Output("check_api.timestamp")
+	if g, w := checkApi.Args["dumpedApiFile"], apiDump.Output.String(); g != w {
+		t.Errorf("expected dumped API file %q, got %q", w, g)
+	}
+	if g, w := checkApi.Args["currentApiFile"], "api/sysprop-platform-current.txt"; g != w {
+		t.Errorf("expected current API file %q, got %q", w, g)
+	}
+	if g, w := checkApi.Args["latestApiFile"], "api/sysprop-platform-latest.txt"; g != w {
+		t.Errorf("expected latest API file %q, got %q", w, g)
+	}
+
+	updateApi := platform.Output("update_api.timestamp")
+	if g, w := updateApi.Input.String(), apiDump.Output.String(); g != w {
+		t.Errorf("expected API update input %q, got %q", w, g)
+	}
+	if g, w := updateApi.Args["currentApiFile"], "api/sysprop-platform-current.txt"; g != w {
+		t.Errorf("expected API update of %q, got %q", w, g)
+	}
+
 	// Check for exported includes
 	coreVariant := "android_arm64_armv8-a_core_static"
 	vendorVariant := "android_arm64_armv8-a_vendor_static"
@@ -363,3 +397,43 @@ func TestSyspropLibrary(t *testing.T) {
 			platformSystemVendorPath, vendorInternalPath, vendorFlags)
 	}
 }
+
+func TestSyspropLibraryMissingApiFiles(t *testing.T) {
+	config := testConfig(nil)
+	ctx := testContext(config, "", map[string][]byte{
+		"missing/Android.bp": []byte(`
+			sysprop_library {
+				name: "sysprop-platform",
+				srcs: ["PlatformProperties.sysprop"],
+				api_packages: ["android.sysprop"],
+				property_owner: "Platform",
+			}
+		`),
+		"missing/PlatformProperties.sysprop": nil,
+		"missing/api/current.txt":            nil,
+		"missing/api/removed.txt":            nil,
+		"missing/api/system-current.txt":     nil,
+		"missing/api/system-removed.txt":     nil,
+		"missing/api/test-current.txt":       nil,
+		"missing/api/test-removed.txt":       nil,
+	})
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp", "prebuilts/sdk/Android.bp", "missing/Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	// Without API files the check is skipped with a warning, and update-api creates the current one.
+	m := ctx.ModuleForTests("sysprop-platform", "android_common")
+	check := m.Output("check_api.timestamp")
+	if check.Rule != syspropApiCheckSkipped {
+		t.Errorf("expected the API check to be skipped, got %v", check.Rule)
+	}
+	if w := "warning: skipping the API check of sysprop_library sysprop-platform"; !strings.Contains(check.Args["msg"], w) {
+		t.Errorf("expected msg to contain %q, got %q", w, check.Args["msg"])
+	}
+
+	update := m.Output("update_api.timestamp")
+	if g, w := update.Args["currentApiFile"], "missing/api/sysprop-platform-current.txt"; g != w {
+		t.Errorf("expected update-api to write %q, got %q", w, g)
+	}
+}
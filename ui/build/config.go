@@ -609,6 +609,8 @@ func (c *configImpl) Tapas(ctx Context, apps []string, arch, variant string) {
 		product = "aosp_mips"
 	case "mips64":
 		product = "aosp_mips64"
+	case "riscv64":
+		product = "aosp_riscv64"
 	case "x86":
 		product = "aosp_x86"
 	case "x86_64":
@@ -832,8 +834,20 @@ func (c *configImpl) hostCrossOut() string {
 	}
 }
 
+// HostArch returns the architecture of the machine soong_ui is running on, using the same names as
+// HOST_ARCH.
+func (c *configImpl) HostArch() string {
+	if runtime.GOOS == "linux" && runtime.GOARCH == "arm64" {
+		return "arm64"
+	}
+	return "x86_64"
+}
+
 func (c *configImpl) HostPrebuiltTag() string {
 	if runtime.GOOS == "linux" {
+		if c.HostArch() == "arm64" {
+			return "linux-arm64"
+		}
 		return "linux-x86"
 	} else if runtime.GOOS == "darwin" {
 		return "darwin-x86"
@@ -118,6 +118,8 @@ func (m *Metrics) getArch(arch string) *soong_metrics_proto.MetricsBase_Arch {
 		return soong_metrics_proto.MetricsBase_ARM.Enum()
 	case "arm64":
 		return soong_metrics_proto.MetricsBase_ARM64.Enum()
+	case "riscv64":
+		return soong_metrics_proto.MetricsBase_RISCV64.Enum()
 	case "x86":
 		return soong_metrics_proto.MetricsBase_X86.Enum()
 	case "x86_64":
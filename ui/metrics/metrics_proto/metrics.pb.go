@@ -71,6 +71,7 @@ const (
 	MetricsBase_ARM64   MetricsBase_Arch = 2
 	MetricsBase_X86     MetricsBase_Arch = 3
 	MetricsBase_X86_64  MetricsBase_Arch = 4
+	MetricsBase_RISCV64 MetricsBase_Arch = 5
 )
 
 var MetricsBase_Arch_name = map[int32]string{
@@ -79,6 +80,7 @@ var MetricsBase_Arch_name = map[int32]string{
 	2: "ARM64",
 	3: "X86",
 	4: "X86_64",
+	5: "RISCV64",
 }
 
 var MetricsBase_Arch_value = map[string]int32{
@@ -87,6 +89,7 @@ var MetricsBase_Arch_value = map[string]int32{
 	"ARM64":   2,
 	"X86":     3,
 	"X86_64":  4,
+	"RISCV64": 5,
 }
 
 func (x MetricsBase_Arch) Enum() *MetricsBase_Arch {
@@ -521,54 +524,54 @@ func init() {
 func init() { proto.RegisterFile("metrics.proto", fileDescriptor_6039342a2ba47b72) }
 
 var fileDescriptor_6039342a2ba47b72 = []byte{
-	// 769 bytes of a gzipped FileDescriptorProto
+	// 776 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0x6f, 0x6b, 0xdb, 0x46,
-	0x18, 0xaf, 0x62, 0x25, 0x96, 0x1e, 0xc5, 0xae, 0x7a, 0xc9, 0xa8, 0xca, 0x08, 0x33, 0x66, 0x1d,
-	0x7e, 0xb1, 0xba, 0xc5, 0x14, 0x53, 0x4c, 0x19, 0xd8, 0x89, 0x29, 0x25, 0xd8, 0x2e, 0x4a, 0xdc,
+	0x18, 0xaf, 0x62, 0x25, 0x96, 0x1e, 0xc5, 0xae, 0x7a, 0xc9, 0xa8, 0xca, 0x08, 0x0b, 0x66, 0x1d,
+	0x79, 0xb1, 0xba, 0xc5, 0x04, 0x53, 0xc2, 0x18, 0x24, 0x8e, 0x29, 0x26, 0xd8, 0x2e, 0x72, 0x9c,
 	0x95, 0xed, 0xc5, 0xa1, 0x4a, 0xe7, 0x46, 0x9b, 0xa5, 0x13, 0x77, 0xa7, 0x32, 0x7f, 0x88, 0x7d,
-	0x93, 0x7d, 0xad, 0x7d, 0x8f, 0x71, 0xcf, 0x49, 0x8e, 0x02, 0x81, 0x85, 0xbe, 0x3b, 0x3d, 0xbf,
-	0x3f, 0xf7, 0x7b, 0x4e, 0xba, 0x47, 0xd0, 0xc9, 0x98, 0x12, 0x69, 0x2c, 0x87, 0x85, 0xe0, 0x8a,
-	0x93, 0x13, 0xc9, 0x79, 0xfe, 0x85, 0x7e, 0x2e, 0xd3, 0x6d, 0x42, 0x2b, 0xa8, 0xff, 0x8f, 0x0b,
-	0xde, 0xc2, 0xac, 0x67, 0x91, 0x64, 0xe4, 0x15, 0x9c, 0x1a, 0x42, 0x12, 0x29, 0x46, 0x55, 0x9a,
-	0x31, 0xa9, 0xa2, 0xac, 0x08, 0xac, 0x9e, 0x35, 0x68, 0x85, 0x04, 0xb1, 0x8b, 0x48, 0xb1, 0xeb,
-	0x1a, 0x21, 0xcf, 0xc0, 0x31, 0x8a, 0x34, 0x09, 0x0e, 0x7a, 0xd6, 0xc0, 0x0d, 0xdb, 0xf8, 0xfc,
-	0x3e, 0x21, 0x13, 0x78, 0x56, 0x6c, 0x23, 0xb5, 0xe1, 0x22, 0xa3, 0x5f, 0x99, 0x90, 0x29, 0xcf,
-	0x69, 0xcc, 0x13, 0x96, 0x47, 0x19, 0x0b, 0x5a, 0xc8, 0x7d, 0x5a, 0x13, 0x3e, 0x1a, 0xfc, 0xbc,
-	0x82, 0xc9, 0x73, 0xe8, 0xaa, 0x48, 0x7c, 0x61, 0x8a, 0x16, 0x82, 0x27, 0x65, 0xac, 0x02, 0x1b,
-	0x05, 0x1d, 0x53, 0xfd, 0x60, 0x8a, 0x24, 0x81, 0xd3, 0x8a, 0x66, 0x42, 0x7c, 0x8d, 0x44, 0x1a,
-	0xe5, 0x2a, 0x38, 0xec, 0x59, 0x83, 0xee, 0xe8, 0xc5, 0xf0, 0x9e, 0x9e, 0x87, 0x8d, 0x7e, 0x87,
-	0x33, 0x8d, 0x7c, 0x34, 0xa2, 0x49, 0x6b, 0xbe, 0x7c, 0x17, 0x12, 0xe3, 0xd7, 0x04, 0xc8, 0x0a,
-	0xbc, 0x6a, 0x97, 0x48, 0xc4, 0x37, 0xc1, 0x11, 0x9a, 0x3f, 0xff, 0x5f, 0xf3, 0xa9, 0x88, 0x6f,
-	0x26, 0xed, 0xf5, 0xf2, 0x72, 0xb9, 0xfa, 0x75, 0x19, 0x82, 0xb1, 0xd0, 0x45, 0x32, 0x84, 0x93,
-	0x86, 0xe1, 0x3e, 0x75, 0x1b, 0x5b, 0x7c, 0x72, 0x4b, 0xac, 0x03, 0xfc, 0x0c, 0x55, 0x2c, 0x1a,
-	0x17, 0xe5, 0x9e, 0xee, 0x20, 0xdd, 0x37, 0xc8, 0x79, 0x51, 0xd6, 0xec, 0x4b, 0x70, 0x6f, 0xb8,
-	0xac, 0xc2, 0xba, 0xdf, 0x14, 0xd6, 0xd1, 0x06, 0x18, 0x35, 0x84, 0x0e, 0x9a, 0x8d, 0xf2, 0xc4,
-	0x18, 0xc2, 0x37, 0x19, 0x7a, 0xda, 0x64, 0x94, 0x27, 0xe8, 0xf9, 0x14, 0xda, 0xe8, 0xc9, 0x65,
-	0xe0, 0x61, 0x0f, 0x47, 0xfa, 0x71, 0x25, 0x49, 0xbf, 0xda, 0x8c, 0x4b, 0xca, 0xfe, 0x52, 0x22,
-	0x0a, 0x8e, 0x11, 0xf6, 0x0c, 0x3c, 0xd7, 0xa5, 0x3d, 0x27, 0x16, 0x5c, 0x4a, 0x6d, 0xd1, 0xb9,
-	0xe5, 0x9c, 0xeb, 0xda, 0x4a, 0x92, 0x9f, 0xe0, 0x71, 0x83, 0x83, 0xb1, 0xbb, 0xe6, 0xf3, 0xd9,
-	0xb3, 0x30, 0xc8, 0x0b, 0x38, 0x69, 0xf0, 0xf6, 0x2d, 0x3e, 0x36, 0x07, 0xbb, 0xe7, 0x36, 0x72,
-	0xf3, 0x52, 0xd1, 0x24, 0x15, 0x81, 0x6f, 0x72, 0xf3, 0x52, 0x5d, 0xa4, 0x82, 0xfc, 0x02, 0x9e,
-	0x64, 0xaa, 0x2c, 0xa8, 0xe2, 0x7c, 0x2b, 0x83, 0x27, 0xbd, 0xd6, 0xc0, 0x1b, 0x9d, 0xdd, 0x7b,
-	0x44, 0x1f, 0x98, 0xd8, 0xbc, 0xcf, 0x37, 0x3c, 0x04, 0x54, 0x5c, 0x6b, 0x01, 0x99, 0x80, 0xfb,
-	0x67, 0xa4, 0x52, 0x2a, 0xca, 0x5c, 0x06, 0xe4, 0x21, 0x6a, 0x47, 0xf3, 0xc3, 0x32, 0x97, 0xe4,
-	0x2d, 0x80, 0x61, 0xa2, 0xf8, 0xe4, 0x21, 0x62, 0x17, 0xd1, 0x5a, 0x9d, 0xa7, 0xf9, 0x1f, 0x91,
-	0x51, 0x9f, 0x3e, 0x48, 0x8d, 0x02, 0xad, 0xee, 0xbf, 0x82, 0xe3, 0x3b, 0x17, 0xc5, 0x01, 0x7b,
-	0x7d, 0x35, 0x0f, 0xfd, 0x47, 0xa4, 0x03, 0xae, 0x5e, 0x5d, 0xcc, 0x67, 0xeb, 0x77, 0xbe, 0x45,
-	0xda, 0xa0, 0x2f, 0x97, 0x7f, 0xd0, 0x7f, 0x0b, 0x36, 0x1e, 0xa5, 0x07, 0xf5, 0xa7, 0xe1, 0x3f,
-	0xd2, 0xe8, 0x34, 0x5c, 0xf8, 0x16, 0x71, 0xe1, 0x70, 0x1a, 0x2e, 0xc6, 0xaf, 0xfd, 0x03, 0x5d,
-	0xfb, 0xf4, 0x66, 0xec, 0xb7, 0x08, 0xc0, 0xd1, 0xa7, 0x37, 0x63, 0x3a, 0x7e, 0xed, 0xdb, 0xfd,
-	0xbf, 0x2d, 0x70, 0xea, 0x1c, 0x84, 0x80, 0x9d, 0x30, 0x19, 0xe3, 0x6c, 0x72, 0x43, 0x5c, 0xeb,
-	0x1a, 0x4e, 0x17, 0x33, 0x89, 0x70, 0x4d, 0xce, 0x00, 0xa4, 0x8a, 0x84, 0xc2, 0x71, 0x86, 0x73,
-	0xc7, 0x0e, 0x5d, 0xac, 0xe8, 0x29, 0x46, 0xbe, 0x07, 0x57, 0xb0, 0x68, 0x6b, 0x50, 0x1b, 0x51,
-	0x47, 0x17, 0x10, 0x3c, 0x03, 0xc8, 0x58, 0xc6, 0xc5, 0x8e, 0x96, 0x92, 0xe1, 0x54, 0xb1, 0x43,
-	0xd7, 0x54, 0xd6, 0x92, 0xf5, 0xff, 0xb5, 0xa0, 0xbb, 0xe0, 0x49, 0xb9, 0x65, 0xd7, 0xbb, 0x82,
-	0x61, 0xaa, 0xdf, 0xe1, 0xd8, 0x9c, 0x9b, 0xdc, 0x49, 0xc5, 0x32, 0x4c, 0xd7, 0x1d, 0xbd, 0xbc,
-	0xff, 0xba, 0xdc, 0x91, 0x9a, 0x61, 0x74, 0x85, 0xb2, 0xc6, 0xc5, 0xf9, 0x7c, 0x5b, 0x25, 0x3f,
-	0x80, 0x97, 0xa1, 0x86, 0xaa, 0x5d, 0x51, 0x77, 0x09, 0xd9, 0xde, 0x86, 0xfc, 0x08, 0xdd, 0xbc,
-	0xcc, 0x28, 0xdf, 0x50, 0x53, 0x94, 0xd8, 0x6f, 0x27, 0x3c, 0xce, 0xcb, 0x6c, 0xb5, 0x31, 0xfb,
-	0xc9, 0xfe, 0x4b, 0xf0, 0x1a, 0x7b, 0xdd, 0x7d, 0x17, 0x2e, 0x1c, 0x5e, 0xad, 0x56, 0x4b, 0xfd,
-	0xd2, 0x1c, 0xb0, 0x17, 0xd3, 0xcb, 0xb9, 0x7f, 0x30, 0xfb, 0xee, 0xb7, 0xea, 0xef, 0x51, 0x25,
-	0xa7, 0xf8, 0x4b, 0xf9, 0x2f, 0x00, 0x00, 0xff, 0xff, 0x81, 0xd0, 0x84, 0x23, 0x62, 0x06, 0x00,
-	0x00,
+	0xa0, 0x7d, 0xa0, 0x7d, 0x8f, 0x71, 0xcf, 0x49, 0x8e, 0x02, 0x81, 0x86, 0xbc, 0x3b, 0x3d, 0xbf,
+	0x3f, 0xf7, 0x7b, 0x4e, 0xba, 0x47, 0xd0, 0xc9, 0x98, 0x12, 0x69, 0x2c, 0xfb, 0x85, 0xe0, 0x8a,
+	0x93, 0x03, 0xc9, 0x79, 0xfe, 0x85, 0x7e, 0x2e, 0xd3, 0x75, 0x42, 0x2b, 0xa8, 0xf7, 0xaf, 0x0b,
+	0xde, 0xd4, 0xac, 0x2f, 0x22, 0xc9, 0xc8, 0x3b, 0x38, 0x34, 0x84, 0x24, 0x52, 0x8c, 0xaa, 0x34,
+	0x63, 0x52, 0x45, 0x59, 0x11, 0x58, 0xc7, 0xd6, 0x49, 0x2b, 0x24, 0x88, 0x5d, 0x46, 0x8a, 0x5d,
+	0xd7, 0x08, 0x79, 0x05, 0x8e, 0x51, 0xa4, 0x49, 0xb0, 0x73, 0x6c, 0x9d, 0xb8, 0x61, 0x1b, 0x9f,
+	0x27, 0x09, 0x39, 0x83, 0x57, 0xc5, 0x3a, 0x52, 0x2b, 0x2e, 0x32, 0xfa, 0x95, 0x09, 0x99, 0xf2,
+	0x9c, 0xc6, 0x3c, 0x61, 0x79, 0x94, 0xb1, 0xa0, 0x85, 0xdc, 0x97, 0x35, 0xe1, 0xc6, 0xe0, 0xa3,
+	0x0a, 0x26, 0xaf, 0xa1, 0xab, 0x22, 0xf1, 0x85, 0x29, 0x5a, 0x08, 0x9e, 0x94, 0xb1, 0x0a, 0x6c,
+	0x14, 0x74, 0x4c, 0xf5, 0xa3, 0x29, 0x92, 0x04, 0x0e, 0x2b, 0x9a, 0x09, 0xf1, 0x35, 0x12, 0x69,
+	0x94, 0xab, 0x60, 0xf7, 0xd8, 0x3a, 0xe9, 0x0e, 0xde, 0xf4, 0x1f, 0xe8, 0xb9, 0xdf, 0xe8, 0xb7,
+	0x7f, 0xa1, 0x91, 0x1b, 0x23, 0x3a, 0x6b, 0x8d, 0x67, 0x1f, 0x42, 0x62, 0xfc, 0x9a, 0x00, 0x99,
+	0x83, 0x57, 0xed, 0x12, 0x89, 0xf8, 0x36, 0xd8, 0x43, 0xf3, 0xd7, 0xdf, 0x34, 0x3f, 0x17, 0xf1,
+	0xed, 0x59, 0x7b, 0x39, 0xbb, 0x9a, 0xcd, 0x7f, 0x9b, 0x85, 0x60, 0x2c, 0x74, 0x91, 0xf4, 0xe1,
+	0xa0, 0x61, 0xb8, 0x4d, 0xdd, 0xc6, 0x16, 0x5f, 0xdc, 0x11, 0xeb, 0x00, 0x3f, 0x43, 0x15, 0x8b,
+	0xc6, 0x45, 0xb9, 0xa5, 0x3b, 0x48, 0xf7, 0x0d, 0x32, 0x2a, 0xca, 0x9a, 0x7d, 0x05, 0xee, 0x2d,
+	0x97, 0x55, 0x58, 0xf7, 0x49, 0x61, 0x1d, 0x6d, 0x80, 0x51, 0x43, 0xe8, 0xa0, 0xd9, 0x20, 0x4f,
+	0x8c, 0x21, 0x3c, 0xc9, 0xd0, 0xd3, 0x26, 0x83, 0x3c, 0x41, 0xcf, 0x97, 0xd0, 0x46, 0x4f, 0x2e,
+	0x03, 0x0f, 0x7b, 0xd8, 0xd3, 0x8f, 0x73, 0x49, 0x7a, 0xd5, 0x66, 0x5c, 0x52, 0xf6, 0xb7, 0x12,
+	0x51, 0xb0, 0x8f, 0xb0, 0x67, 0xe0, 0xb1, 0x2e, 0x6d, 0x39, 0xb1, 0xe0, 0x52, 0x6a, 0x8b, 0xce,
+	0x1d, 0x67, 0xa4, 0x6b, 0x73, 0x49, 0x7e, 0x82, 0xe7, 0x0d, 0x0e, 0xc6, 0xee, 0x9a, 0xcf, 0x67,
+	0xcb, 0xc2, 0x20, 0x6f, 0xe0, 0xa0, 0xc1, 0xdb, 0xb6, 0xf8, 0xdc, 0x1c, 0xec, 0x96, 0xdb, 0xc8,
+	0xcd, 0x4b, 0x45, 0x93, 0x54, 0x04, 0xbe, 0xc9, 0xcd, 0x4b, 0x75, 0x99, 0x0a, 0xf2, 0x2b, 0x78,
+	0x92, 0xa9, 0xb2, 0xa0, 0x8a, 0xf3, 0xb5, 0x0c, 0x5e, 0x1c, 0xb7, 0x4e, 0xbc, 0xc1, 0xd1, 0x83,
+	0x47, 0xf4, 0x91, 0x89, 0xd5, 0x24, 0x5f, 0xf1, 0x10, 0x50, 0x71, 0xad, 0x05, 0xe4, 0x0c, 0xdc,
+	0xbf, 0x22, 0x95, 0x52, 0x51, 0xe6, 0x32, 0x20, 0x8f, 0x51, 0x3b, 0x9a, 0x1f, 0x96, 0xb9, 0x24,
+	0xbf, 0x00, 0x18, 0x26, 0x8a, 0x0f, 0x1e, 0x23, 0x76, 0x11, 0xad, 0xd5, 0x79, 0x9a, 0xff, 0x19,
+	0x19, 0xf5, 0xe1, 0xa3, 0xd4, 0x28, 0xd0, 0xea, 0xde, 0x3b, 0xd8, 0xbf, 0x77, 0x51, 0x1c, 0xb0,
+	0x97, 0x8b, 0x71, 0xe8, 0x3f, 0x23, 0x1d, 0x70, 0xf5, 0xea, 0x72, 0x7c, 0xb1, 0xfc, 0xe0, 0x5b,
+	0xa4, 0x0d, 0xfa, 0x72, 0xf9, 0x3b, 0xbd, 0x09, 0xd8, 0x78, 0x94, 0x1e, 0xd4, 0x9f, 0x86, 0xff,
+	0x4c, 0xa3, 0xe7, 0xe1, 0xd4, 0xb7, 0x88, 0x0b, 0xbb, 0xe7, 0xe1, 0x74, 0x78, 0xea, 0xef, 0xe8,
+	0xda, 0xa7, 0xf7, 0x43, 0xbf, 0x45, 0x00, 0xf6, 0x3e, 0xbd, 0x1f, 0xd2, 0xe1, 0xa9, 0x6f, 0x6b,
+	0x55, 0x38, 0x59, 0x8c, 0x6e, 0x86, 0xa7, 0xfe, 0x6e, 0xef, 0x1f, 0x0b, 0x9c, 0x3a, 0x14, 0x21,
+	0x60, 0x27, 0x4c, 0xc6, 0x38, 0xa8, 0xdc, 0x10, 0xd7, 0xba, 0x86, 0xa3, 0xc6, 0x8c, 0x25, 0x5c,
+	0x93, 0x23, 0x00, 0xa9, 0x22, 0xa1, 0x70, 0xb6, 0xe1, 0x10, 0xb2, 0x43, 0x17, 0x2b, 0x7a, 0xa4,
+	0x91, 0xef, 0xc1, 0x15, 0x2c, 0x5a, 0x1b, 0xd4, 0x46, 0xd4, 0xd1, 0x05, 0x04, 0x8f, 0x00, 0x32,
+	0x96, 0x71, 0xb1, 0xa1, 0xa5, 0x64, 0x38, 0x62, 0xec, 0xd0, 0x35, 0x95, 0xa5, 0x64, 0xbd, 0xff,
+	0x2c, 0xe8, 0x4e, 0x79, 0x52, 0xae, 0xd9, 0xf5, 0xa6, 0x60, 0x98, 0xea, 0x0f, 0xd8, 0x37, 0x87,
+	0x28, 0x37, 0x52, 0xb1, 0x0c, 0xd3, 0x75, 0x07, 0x6f, 0x1f, 0xbe, 0x3b, 0xf7, 0xa4, 0x66, 0x32,
+	0x2d, 0x50, 0xd6, 0xb8, 0x45, 0x9f, 0xef, 0xaa, 0xe4, 0x07, 0xf0, 0x32, 0xd4, 0x50, 0xb5, 0x29,
+	0xea, 0x2e, 0x21, 0xdb, 0xda, 0x90, 0x1f, 0xa1, 0x9b, 0x97, 0x19, 0xe5, 0x2b, 0x6a, 0x8a, 0x12,
+	0xfb, 0xed, 0x84, 0xfb, 0x79, 0x99, 0xcd, 0x57, 0x66, 0x3f, 0xd9, 0x7b, 0x0b, 0x5e, 0x63, 0xaf,
+	0xfb, 0x2f, 0xc6, 0x85, 0xdd, 0xc5, 0x7c, 0x3e, 0xd3, 0x6f, 0xd0, 0x01, 0x7b, 0x7a, 0x7e, 0x35,
+	0xf6, 0x77, 0x2e, 0xbe, 0xfb, 0xbd, 0xfa, 0x95, 0x54, 0xc9, 0x29, 0xfe, 0x5f, 0xfe, 0x1f, 0x00,
+	0x11, 0xdc, 0xe5, 0x46, 0x6f, 0x06, 0x00, 0x00,
 }
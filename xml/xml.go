@@ -56,8 +56,15 @@ func init() {
 	pctx.HostBinToolVariable("XmlLintCmd", "xmllint")
 }
 
+// xsdConfigProducer is implemented by the xsd_config modules of the xsdc plugin, and returns the xsd
+// file that their parsers are generated from.
+type xsdConfigProducer interface {
+	XsdConfigPath() android.OptionalPath
+}
+
 type prebuiltEtcXmlProperties struct {
-	// Optional DTD that will be used to validate the xml file.
+	// Optional DTD or XSD that will be used to validate the xml file.  Use ":<name>" to validate
+	// against the xsd of an xsd_config module.
 	Schema *string `android:"path"`
 }
 
@@ -75,11 +82,33 @@ func (p *prebuiltEtcXml) DepsMutator(ctx android.BottomUpMutatorContext) {
 	p.PrebuiltEtc.DepsMutator(ctx)
 }
 
+// schemaPath returns the path of the schema, which is the xsd of the module if the schema refers to
+// an xsd_config module, as its outputs are the generated parsers.
+func (p *prebuiltEtcXml) schemaPath(ctx android.ModuleContext) android.Path {
+	schema := proptools.String(p.properties.Schema)
+	if name := android.SrcIsModule(schema); name != "" {
+		if dep, _ := ctx.GetDirectDep(name); dep != nil {
+			if xsdConfig, ok := dep.(xsdConfigProducer); ok {
+				xsd := xsdConfig.XsdConfigPath()
+				if !xsd.Valid() {
+					ctx.PropertyErrorf("schema", "%q has no xsd file", name)
+					return nil
+				}
+				return xsd.Path()
+			}
+		}
+	}
+	return android.PathForModuleSrc(ctx, schema)
+}
+
 func (p *prebuiltEtcXml) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	p.PrebuiltEtc.GenerateAndroidBuildActions(ctx)
 
 	if p.properties.Schema != nil {
-		schema := android.PathForModuleSrc(ctx, proptools.String(p.properties.Schema))
+		schema := p.schemaPath(ctx)
+		if schema == nil {
+			return
+		}
 
 		switch schema.Ext() {
 		case ".dtd":
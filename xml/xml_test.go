@@ -27,6 +27,7 @@ func testXml(t *testing.T, bp string) *android.TestContext {
 	ctx := android.NewTestArchContext()
 	ctx.RegisterModuleType("prebuilt_etc", android.ModuleFactoryAdaptor(android.PrebuiltEtcFactory))
 	ctx.RegisterModuleType("prebuilt_etc_xml", android.ModuleFactoryAdaptor(PrebuiltEtcXmlFactory))
+	ctx.RegisterModuleType("xsd_config", android.ModuleFactoryAdaptor(testXsdConfigFactory))
 	ctx.Register()
 	mockFiles := map[string][]byte{
 		"Android.bp": []byte(bp),
@@ -34,6 +35,8 @@ func testXml(t *testing.T, bp string) *android.TestContext {
 		"foo.dtd":    nil,
 		"bar.xml":    nil,
 		"bar.xsd":    nil,
+		"baz.xml":    nil,
+		"config.xsd": nil,
 	}
 	ctx.MockFileSystem(mockFiles)
 	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
@@ -84,3 +87,53 @@ func TestPrebuiltEtcXml(t *testing.T) {
 		t.Errorf("dtd expected %q != got %q", "foo.dtdl", schema)
 	}
 }
+
+// testXsdConfig stands in for the xsd_config modules of the xsdc plugin, whose outputs are the
+// generated parsers.
+type testXsdConfig struct {
+	android.ModuleBase
+
+	properties struct {
+		Srcs []string `android:"path"`
+	}
+
+	xsd android.OptionalPath
+}
+
+func (x *testXsdConfig) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	x.xsd = android.OptionalPathForPath(ctx.ExpandSources(x.properties.Srcs, nil)[0])
+}
+
+func (x *testXsdConfig) Srcs() android.Paths {
+	return android.Paths{android.PathForTesting("config.srcjar")}
+}
+
+func (x *testXsdConfig) XsdConfigPath() android.OptionalPath {
+	return x.xsd
+}
+
+func testXsdConfigFactory() android.Module {
+	module := &testXsdConfig{}
+	module.AddProperties(&module.properties)
+	android.InitAndroidModule(module)
+	return module
+}
+
+func TestPrebuiltEtcXmlXsdConfig(t *testing.T) {
+	ctx := testXml(t, `
+		xsd_config {
+			name: "foo_config",
+			srcs: ["config.xsd"],
+		}
+		prebuilt_etc_xml {
+			name: "baz.xml",
+			src: "baz.xml",
+			schema: ":foo_config",
+		}
+	`)
+
+	xmllint := ctx.ModuleForTests("baz.xml", "android_common").Rule("xmllint-xsd")
+	if g, w := xmllint.Args["xsd"], "config.xsd"; g != w {
+		t.Errorf("xsd expected %q != got %q", w, g)
+	}
+}